@@ -11,15 +11,25 @@ import (
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/soyunomas/loopwarden/internal/config"
-	"github.com/soyunomas/loopwarden/internal/detector"
 	"github.com/soyunomas/loopwarden/internal/notifier"
-	"github.com/soyunomas/loopwarden/internal/sniffer"
+	"github.com/soyunomas/loopwarden/internal/linkmon"
 )
 
 func main() {
+	// Subcomandos
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		runReplay(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "silence" {
+		runSilence(os.Args[2:])
+		return
+	}
+
 	// Flags
 	configPath := flag.String("config", "configs/config.toml", "Path to configuration file")
 	flag.Parse()
@@ -30,6 +40,11 @@ func main() {
 		fmt.Fprintf(os.Stderr, "❌ Error loading config: %v\n", err)
 		os.Exit(1)
 	}
+	// Mismas reglas que en una recarga (SIGHUP): mejor fallar ahora que con un default silencioso
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid config: %v\n", err)
+		os.Exit(1)
+	}
 
 	// 1.5 Logging
 	if cfg.System.LogFile != "" {
@@ -51,10 +66,6 @@ func main() {
 	if sensorName == "" { sensorName = "LoopWarden" }
 	notify := notifier.NewNotifier(&cfg.Alerts, sensorName)
 
-	if len(cfg.Network.Interfaces) == 0 {
-		log.Fatal("❌ No interfaces defined in config (network.interfaces = [])")
-	}
-
 	// --- CAMBIO CRÍTICO: GESTIÓN DE SEÑALES CON CONTEXTO ---
 	// Creamos un contexto cancelable para coordinar el apagado
 	ctx, cancel := context.WithCancel(context.Background())
@@ -63,31 +74,33 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// SIGHUP: recarga de configuración en caliente
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
 	// 3. Orquestación Paralela
 	var wg sync.WaitGroup
 	
 	fmt.Printf("🛡️  LoopWarden starting on %d interfaces...\n", len(cfg.Network.Interfaces))
-	notify.Alert(fmt.Sprintf("🟢 LoopWarden Started (Monitors: %v)", cfg.Network.Interfaces))
+	notify.Notify(notifier.SystemEvent("Started", "🟢 LoopWarden Started").
+		With("MONITORS", fmt.Sprint(cfg.Network.Interfaces)))
 
-	for _, ifaceName := range cfg.Network.Interfaces {
-		wg.Add(1)
-		currentIface := ifaceName 
-
-		go func(iface string) {
-			defer wg.Done()
-			
-			engine := detector.NewEngine(&cfg.Algorithms, notify, iface)
-
-			log.Printf("🚀 Launching stack for %s", iface)
-			
-			// AHORA PASAMOS 'ctx' EN LUGAR DE 'sigChan'
-			if err := sniffer.Run(ctx, iface, cfg, engine); err != nil {
-				log.Printf("❌ Critical error on interface %s: %v", iface, err)
-				notify.Alert(fmt.Sprintf("❌ Stack failure on %s: %v", iface, err))
-			} else {
-				log.Printf("⏹️ Stack stopped for %s", iface)
+	// Monitor de enlace: sin netlink (contenedores restringidos) los supervisores sondean
+	links, err := linkmon.Open()
+	if err != nil {
+		log.Printf("⚠️ Link monitor unavailable, falling back to polling: %v", err)
+		links = nil
+	} else {
+		go func() {
+			if err := links.Run(ctx); err != nil {
+				log.Printf("⚠️ Link monitor stopped: %v", err)
 			}
-		}(currentIface)
+		}()
+	}
+
+	stacks := newStackSet(ctx, &wg, notify, links)
+	for _, ifaceName := range cfg.Network.Interfaces {
+		stacks.start(ifaceName, cfg)
 	}
 
 	// 4. Telemetría
@@ -105,9 +118,29 @@ func main() {
 		}()
 	}
 
+	// 5. API local de silencios ("loopwarden silence ...")
+	if addr := cfg.Alerts.Silences.Listen; addr != "" {
+		go func() {
+			log.Printf("🔕 Silence API listening on %s", addr)
+			if cfg.Alerts.Silences.Token == "" {
+				log.Printf("⚠️ Silence API has no token: any local process can silence alerts")
+			}
+			if err := http.ListenAndServe(addr, notify.SilenceAPI()); err != nil {
+				log.Printf("⚠️ Failed to start silence API: %v", err)
+			}
+		}()
+	}
+
 	// BLOQUEO PRINCIPAL
-	// Esperamos aquí hasta recibir la señal
-	receivedSig := <-sigChan 
+	// Esperamos aquí hasta recibir la señal de parada (SIGHUP sólo recarga)
+	var receivedSig os.Signal
+	for receivedSig == nil {
+		select {
+		case <-hupChan:
+			cfg = stacks.reload(*configPath, cfg)
+		case receivedSig = <-sigChan:
+		}
+	}
 	fmt.Printf("\nSignal received (%v), shutting down stacks...\n", receivedSig)
 	
 	// 1. Ordenamos a todos los sniffers que paren
@@ -116,6 +149,9 @@ func main() {
 	// 2. Esperamos a que terminen limpiamente
 	wg.Wait()
 	
-	notify.Alert("🔴 LoopWarden stopped gracefully")
+	notify.Notify(notifier.SystemEvent("Stopped", "🔴 LoopWarden stopped gracefully"))
+
+	// 3. Damos un margen para vaciar las colas; lo pendiente queda en el spool
+	notify.Close(5 * time.Second)
 	fmt.Println("Goodbye.")
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"sync"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/linkmon"
+	"github.com/soyunomas/loopwarden/internal/notifier"
+)
+
+// stackSet son las pilas en ejecución, una por interfaz de network.interfaces.
+type stackSet struct {
+	ctx    context.Context
+	wg     *sync.WaitGroup
+	notify *notifier.Notifier
+	links  *linkmon.Monitor
+
+	running map[string]*runningStack
+}
+
+type runningStack struct {
+	st     *stack
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func newStackSet(ctx context.Context, wg *sync.WaitGroup, notify *notifier.Notifier, links *linkmon.Monitor) *stackSet {
+	return &stackSet{
+		ctx:     ctx,
+		wg:      wg,
+		notify:  notify,
+		links:   links,
+		running: make(map[string]*runningStack),
+	}
+}
+
+func (ss *stackSet) start(iface string, cfg *config.Config) {
+	ctx, cancel := context.WithCancel(ss.ctx)
+	rs := &runningStack{
+		st:     newStack(iface, cfg, ss.notify, ss.links),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	ss.running[iface] = rs
+
+	ss.wg.Add(1)
+	go func() {
+		defer ss.wg.Done()
+		defer close(rs.done)
+		rs.st.run(ctx)
+	}()
+}
+
+// stop para la pila y espera a que suelte el socket (la interfaz puede volver a añadirse).
+func (ss *stackSet) stop(iface string) {
+	rs, ok := ss.running[iface]
+	if !ok {
+		return
+	}
+	rs.cancel()
+	<-rs.done
+	delete(ss.running, iface)
+	log.Printf("⏹️ Stack removed for %s", iface)
+}
+
+// reload relee el fichero de configuración y lo aplica sin parar la captura (SIGHUP).
+// Si el fichero no carga o no valida se mantiene la configuración en ejecución.
+// Devuelve la configuración vigente tras la recarga.
+func (ss *stackSet) reload(path string, cur *config.Config) *config.Config {
+	log.Printf("♻️ SIGHUP received, reloading %s", path)
+
+	next, err := config.LoadConfig(path)
+	if err == nil {
+		err = next.Validate()
+	}
+	if err != nil {
+		log.Printf("❌ Configuration reload failed: %v", err)
+		ev := notifier.SystemEvent("ConfigReloadFailed", "❌ Configuration reload FAILED").
+			With("FILE", path).
+			With("ERROR", err.Error()).
+			With("ACTION", "Keeping previous configuration.")
+		ev.Severity = notifier.SeverityWarning
+		ss.notify.Notify(ev)
+		return cur
+	}
+
+	// 1. Interfaces retiradas / nuevas
+	wanted := make(map[string]bool, len(next.Network.Interfaces))
+	for _, iface := range next.Network.Interfaces {
+		wanted[iface] = true
+	}
+	var added, removed []string
+	for _, iface := range cur.Network.Interfaces {
+		if !wanted[iface] {
+			ss.stop(iface)
+			removed = append(removed, iface)
+		}
+	}
+	for _, iface := range next.Network.Interfaces {
+		if rs, ok := ss.running[iface]; ok {
+			rs.st.reload(next)
+		} else {
+			ss.start(iface, next)
+			added = append(added, iface)
+		}
+	}
+
+	// 2. Ventanas de mantenimiento: se aplican en caliente
+	if !reflect.DeepEqual(cur.Alerts.Maintenance, next.Alerts.Maintenance) {
+		ss.notify.SetMaintenance(next.Alerts.Maintenance)
+	}
+
+	// 3. Secciones que se leen una sola vez al arrancar
+	var ignored []string
+	curAlerts, nextAlerts := cur.Alerts, next.Alerts
+	curAlerts.Maintenance, nextAlerts.Maintenance = nil, nil
+	if !reflect.DeepEqual(curAlerts, nextAlerts) {
+		ignored = append(ignored, "[alerts]")
+	}
+	if !reflect.DeepEqual(cur.Telemetry, next.Telemetry) {
+		ignored = append(ignored, "[telemetry]")
+	}
+	if !reflect.DeepEqual(cur.System, next.System) {
+		ignored = append(ignored, "[system]")
+	}
+	if len(ignored) > 0 {
+		log.Printf("⚠️ Changes in %v require a restart and were not applied", ignored)
+	}
+
+	mode := "Live (capture not interrupted)"
+	if needsRestart(cur, next) {
+		mode = "Capture stacks relaunched (network/forensics/enabled algorithms changed)"
+	}
+
+	ev := notifier.SystemEvent("ConfigReloaded", "♻️ Configuration reloaded").
+		With("MONITORS", fmt.Sprint(next.Network.Interfaces)).
+		With("ADDED", fmt.Sprint(added)).
+		With("REMOVED", fmt.Sprint(removed)).
+		With("APPLIED", mode)
+	if len(ignored) > 0 {
+		ev = ev.With("IGNORED", fmt.Sprintf("%v (restart required)", ignored))
+	}
+
+	log.Printf("✅ Configuration reloaded (added: %v, removed: %v)", added, removed)
+	ss.notify.Notify(ev)
+	return next
+}
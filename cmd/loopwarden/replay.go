@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/detector"
+	"github.com/soyunomas/loopwarden/internal/notifier"
+	"github.com/soyunomas/loopwarden/internal/pcap"
+	"github.com/soyunomas/loopwarden/internal/sniffer"
+)
+
+// runReplay implementa el subcomando "loopwarden replay".
+// Reproduce una captura pcap/pcapng a través de los algoritmos sin abrir sockets:
+// no requiere root y no envía nada a los canales de alerta reales.
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configPath := fs.String("config", "configs/config.toml", "Path to configuration file")
+	pcapPath := fs.String("pcap", "", "Capture file to replay (pcap or pcapng)")
+	ifaceName := fs.String("iface", "replay", "Interface name used for overrides and metric labels")
+	fast := fs.Bool("fast", false, "Replay as fast as possible (windows still follow capture timestamps)")
+	speed := fs.Float64("speed", 1.0, "Playback speed multiplier in real-time mode")
+	fs.Parse(args)
+
+	if *pcapPath == "" {
+		fmt.Fprintln(os.Stderr, "❌ Missing -pcap <file>")
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	f, err := os.Open(*pcapPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Failed to open capture: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	reader, err := pcap.NewReader(f)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+
+	// Las alertas sólo van al log: reproducir un incidente pasado no debe despertar a nadie.
+	// Dampening desactivado en la práctica para ver todas las detecciones.
+	sensorName := cfg.System.SensorName
+	if sensorName == "" { sensorName = "LoopWarden" }
+	replayAlerts := &config.AlertsConfig{
+		Dampening: config.DampeningConfig{MaxAlertsPerMinute: 1 << 30, MuteDuration: "1s"},
+	}
+	notify := notifier.NewNotifier(replayAlerts, sensorName+"/replay")
+
+	// El reloj virtual debe instalarse antes de construir los algoritmos
+	clk := detector.NewReplayClock(time.Time{})
+	detector.SetClock(clk)
+
+	engine := detector.NewEngine(&cfg.Algorithms, notify, *ifaceName)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	wallStart := time.Now()
+	stats, err := sniffer.Replay(ctx, reader, *ifaceName, cfg, engine, clk, sniffer.ReplayOptions{
+		Fast:  *fast,
+		Speed: *speed,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Replay aborted: %v\n", err)
+	}
+
+	// Las alertas se emiten en goroutines: se espera a que lleguen al log antes del resumen
+	detector.WaitAlerts()
+
+	fmt.Printf("\n⏹️  Replay finished: %d frames read, %d dispatched\n", stats.Packets, stats.Dispatched)
+	if !stats.First.IsZero() {
+		fmt.Printf("    Capture span: %s -> %s (%v)\n",
+			stats.First.Format(time.RFC3339Nano), stats.Last.Format(time.RFC3339Nano), stats.Last.Sub(stats.First))
+	}
+	fmt.Printf("    Wall time:    %v\n", time.Since(wallStart).Round(time.Millisecond))
+	printEngineHits(*ifaceName)
+
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// printEngineHits vuelca loopwarden_engine_hits_total de la interfaz reproducida.
+func printEngineHits(ifaceName string) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return
+	}
+
+	var lines []string
+	for _, mf := range families {
+		if mf.GetName() != "loopwarden_engine_hits_total" {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			labels := make(map[string]string)
+			for _, lp := range m.GetLabel() {
+				labels[lp.GetName()] = lp.GetValue()
+			}
+			if labels["interface"] != ifaceName {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("    %-14s %-18s %6.0f", labels["engine"], labels["threat_type"], m.GetCounter().GetValue()))
+		}
+	}
+
+	if len(lines) == 0 {
+		fmt.Println("    Detections:   none")
+		return
+	}
+	sort.Strings(lines)
+	fmt.Println("    Detections:")
+	fmt.Println(strings.Join(lines, "\n"))
+}
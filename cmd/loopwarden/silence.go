@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/notifier"
+)
+
+const silenceUsage = `Usage:
+  loopwarden silence list   [flags]
+  loopwarden silence add    [flags] -duration 2h [-interface eth0] [-algorithm FlapGuard,EtherFuse] [-mac ...] [-vlan ...]
+  loopwarden silence expire [flags] <id>
+`
+
+// runSilence implementa el subcomando "loopwarden silence": cliente de la API local
+// de silencios del proceso en ejecución ([alerts.silences] listen).
+func runSilence(args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprint(os.Stderr, silenceUsage)
+		os.Exit(2)
+	}
+	action := args[0]
+
+	fs := flag.NewFlagSet("silence "+action, flag.ExitOnError)
+	configPath := fs.String("config", "configs/config.toml", "Path to configuration file (API address and token)")
+	api := fs.String("api", "", "Silence API address (default: [alerts.silences] listen)")
+	ifaces := fs.String("interface", "", "Comma-separated interfaces to silence")
+	algos := fs.String("algorithm", "", "Comma-separated algorithms to silence (EtherFuse, FlapGuard...)")
+	macs := fs.String("mac", "", "Comma-separated MACs (source or target)")
+	vlans := fs.String("vlan", "", "Comma-separated VLANs")
+	duration := fs.String("duration", "1h", "Silence length")
+	comment := fs.String("comment", "", "Reason (shown in the listing and the log)")
+	fs.Parse(args[1:])
+
+	addr, token := *api, ""
+	if cfg, err := config.LoadConfig(*configPath); err == nil {
+		if addr == "" { addr = cfg.Alerts.Silences.Listen }
+		token = cfg.Alerts.Silences.Token
+	} else if addr == "" {
+		fmt.Fprintf(os.Stderr, "❌ Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if addr == "" {
+		fmt.Fprintln(os.Stderr, "❌ Silence API disabled: set [alerts.silences] listen or use -api")
+		os.Exit(1)
+	}
+	call := func(method, path string, body any) []byte {
+		var rd io.Reader
+		if body != nil {
+			b, _ := json.Marshal(body)
+			rd = bytes.NewReader(b)
+		}
+		req, _ := http.NewRequest(method, "http://"+addr+path, rd)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := (&http.Client{Timeout: 5 * time.Second}).Do(req)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+			os.Exit(1)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode >= 300 {
+			var e struct{ Error string }
+			json.Unmarshal(out, &e)
+			fmt.Fprintf(os.Stderr, "❌ %s: %s\n", resp.Status, e.Error)
+			os.Exit(1)
+		}
+		return out
+	}
+
+	switch action {
+	case "list":
+		var list notifier.SilenceList
+		if err := json.Unmarshal(call(http.MethodGet, "/api/v1/silences", nil), &list); err != nil {
+			fmt.Fprintf(os.Stderr, "❌ Invalid response: %v\n", err)
+			os.Exit(1)
+		}
+		printSilences(list)
+
+	case "add":
+		user := os.Getenv("USER")
+		if user == "" { user = "cli" }
+		var sil notifier.Silence
+		json.Unmarshal(call(http.MethodPost, "/api/v1/silences", notifier.SilenceRequest{
+			Interfaces: splitList(*ifaces),
+			Algorithms: splitList(*algos),
+			MACs:       splitList(*macs),
+			VLANs:      splitList(*vlans),
+			Comment:    *comment,
+			CreatedBy:  user,
+			Duration:   *duration,
+		}), &sil)
+		fmt.Printf("🔕 Silence %s active until %s\n", sil.ID, sil.EndsAt.Local().Format("2006-01-02 15:04:05"))
+
+	case "expire":
+		if fs.NArg() != 1 {
+			fmt.Fprint(os.Stderr, silenceUsage)
+			os.Exit(2)
+		}
+		call(http.MethodDelete, "/api/v1/silences/"+fs.Arg(0), nil)
+		fmt.Printf("🔔 Silence %s expired\n", fs.Arg(0))
+
+	default:
+		fmt.Fprint(os.Stderr, silenceUsage)
+		os.Exit(2)
+	}
+}
+
+func printSilences(list notifier.SilenceList) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUNTIL\tMATCHERS\tCREATED BY\tCOMMENT")
+	for _, s := range list.Silences {
+		var m []string
+		for _, c := range []struct {
+			key  string
+			list []string
+		}{{"interface", s.Interfaces}, {"algorithm", s.Algorithms}, {"mac", s.MACs}, {"vlan", s.VLANs}} {
+			if len(c.list) > 0 {
+				m = append(m, c.key+"="+strings.Join(c.list, ","))
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.ID, s.EndsAt.Local().Format("2006-01-02 15:04"),
+			strings.Join(m, " "), s.CreatedBy, s.Comment)
+	}
+	tw.Flush()
+
+	if len(list.Maintenance) > 0 {
+		fmt.Println()
+		tw = tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "MAINTENANCE\tSTATUS")
+		for _, w := range list.Maintenance {
+			status := "next " + w.Next.Local().Format("2006-01-02 15:04")
+			if w.Active {
+				status = "ACTIVE until " + w.Until.Local().Format("2006-01-02 15:04")
+			}
+			fmt.Fprintf(tw, "%s\t%s\n", w.Name, status)
+		}
+		tw.Flush()
+	}
+}
+
+// splitList separa una lista de la línea de comandos ("a,b, c").
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
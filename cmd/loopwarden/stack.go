@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"maps"
+	"reflect"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/detector"
+	"github.com/soyunomas/loopwarden/internal/forensics"
+	"github.com/soyunomas/loopwarden/internal/linkmon"
+	"github.com/soyunomas/loopwarden/internal/notifier"
+	"github.com/soyunomas/loopwarden/internal/sniffer"
+	"github.com/soyunomas/loopwarden/internal/telemetry"
+)
+
+// stack supervisa la pila de captura de una interfaz: la arranca cuando el enlace está
+// operativo, la para cuando cae y la relanza al volver. Sin este supervisor una interfaz
+// ausente al arrancar o un cable desenchufado dejaban la interfaz sin vigilancia hasta
+// reiniciar el daemon.
+type stack struct {
+	iface  string
+	cfg    *config.Config
+	notify *notifier.Notifier
+	links  *linkmon.Monitor // nil: netlink no disponible, se sondea con linkmon.Lookup
+
+	reloads chan *config.Config // SIGHUP: sólo se conserva la última configuración pendiente
+
+	// --- Configuración Efectiva ---
+	flapThreshold int
+	flapWindow    time.Duration
+	restartDelay  time.Duration
+
+	transitions []time.Time // Caídas recientes (detección de flapping)
+	lastFlap    time.Time
+	launches    int
+}
+
+func newStack(iface string, cfg *config.Config, notify *notifier.Notifier, links *linkmon.Monitor) *stack {
+	s := &stack{
+		iface:   iface,
+		notify:  notify,
+		links:   links,
+		reloads: make(chan *config.Config, 1),
+	}
+	s.configure(cfg)
+	return s
+}
+
+// configure calcula la configuración efectiva del supervisor.
+func (s *stack) configure(cfg *config.Config) {
+	s.cfg = cfg
+	s.flapThreshold = cfg.Network.LinkFlapThreshold
+
+	window, err := time.ParseDuration(cfg.Network.LinkFlapWindow)
+	if err != nil && cfg.Network.LinkFlapWindow != "" {
+		log.Printf("⚠️ [LinkMonitor:%s] Invalid LinkFlapWindow '%s', defaulting to 60s", s.iface, cfg.Network.LinkFlapWindow)
+	}
+	s.flapWindow = window
+
+	delay, err := time.ParseDuration(cfg.Network.RestartDelay)
+	if err != nil && cfg.Network.RestartDelay != "" {
+		log.Printf("⚠️ [LinkMonitor:%s] Invalid RestartDelay '%s', defaulting to 5s", s.iface, cfg.Network.RestartDelay)
+	}
+	s.restartDelay = delay
+
+	// Fallbacks de Seguridad
+	if s.flapThreshold <= 0 { s.flapThreshold = 3 }
+	if s.flapWindow <= 0 { s.flapWindow = 60 * time.Second }
+	if s.restartDelay <= 0 { s.restartDelay = 5 * time.Second }
+}
+
+// reload entrega una nueva configuración (ya validada) a la goroutine del supervisor.
+// Si aún no ha consumido la anterior, se sustituye: sólo importa la última.
+func (s *stack) reload(cfg *config.Config) {
+	select {
+	case <-s.reloads:
+	default:
+	}
+	s.reloads <- cfg
+}
+
+// run bloquea hasta que se cancela el contexto.
+func (s *stack) run(ctx context.Context) {
+	var updates <-chan linkmon.State
+	if s.links != nil {
+		updates = s.links.Subscribe(s.iface)
+		defer s.links.Unsubscribe(s.iface, updates)
+	}
+
+	state := linkmon.Lookup(s.iface)
+	if !state.Usable() {
+		s.linkDown(state)
+	}
+
+	for {
+		// 1. Esperar a que el enlace esté operativo
+		for !state.Usable() {
+			retry := time.NewTimer(s.restartDelay)
+			select {
+			case <-ctx.Done():
+				retry.Stop()
+				return
+			case st := <-updates:
+				retry.Stop()
+				state = s.transition(state, st)
+			case cfg := <-s.reloads:
+				// Sin captura en marcha: basta con quedarse con la nueva configuración
+				retry.Stop()
+				s.configure(cfg)
+			case <-retry.C:
+				// Sin netlink (o evento perdido): sondeo
+				if st := linkmon.Lookup(s.iface); st.Usable() != state.Usable() {
+					state = s.transition(state, st)
+				}
+			}
+		}
+
+		// 2. Lanzar la pila
+		err := s.launch(ctx, &state, updates)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("❌ Critical error on interface %s: %v", s.iface, err)
+			ev := notifier.SystemEvent("StackFailure", "❌ Stack failure").
+				With("ERROR", err.Error()).
+				With("ACTION", fmt.Sprintf("Retrying in %s", s.restartDelay))
+			ev.Interface = s.iface
+			ev.Severity = notifier.SeverityCritical
+			s.notify.Notify(ev)
+
+			// Backoff antes de reintentar, atentos a cambios de enlace
+			backoff := time.NewTimer(s.restartDelay)
+		wait:
+			for {
+				select {
+				case <-ctx.Done():
+					backoff.Stop()
+					return
+				case st := <-updates:
+					state = s.transition(state, st)
+				case cfg := <-s.reloads:
+					s.configure(cfg)
+				case <-backoff.C:
+					break wait
+				}
+			}
+			state = linkmon.Lookup(s.iface)
+		}
+	}
+}
+
+// launch ejecuta la captura hasta que el enlace cae, falla o se cancela el contexto.
+func (s *stack) launch(ctx context.Context, state *linkmon.State, updates <-chan linkmon.State) error {
+	workers := s.cfg.Network.Workers
+	if workers <= 0 { workers = 1 }
+	engines := detector.NewEngineGroup(&s.cfg.Algorithms, s.notify, s.iface, workers)
+
+	// Ring forense: evidencia pcap de los últimos segundos al alertar
+	// (uno por interfaz, compartido por todos los workers)
+	if s.cfg.Forensics.Enabled {
+		rec, err := forensics.NewRecorder(&s.cfg.Forensics, s.iface, s.cfg.Network.SnapLen)
+		if err != nil {
+			log.Printf("⚠️ [%s] Forensic ring disabled: %v", s.iface, err)
+		} else {
+			for _, engine := range engines {
+				engine.SetRecorder(rec)
+			}
+		}
+	}
+
+	if s.launches > 0 {
+		telemetry.StackRestarts.WithLabelValues(s.iface).Inc()
+		log.Printf("🔄 Restarting stack for %s", s.iface)
+	} else {
+		log.Printf("🚀 Launching stack for %s", s.iface)
+	}
+	s.launches++
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- sniffer.Run(runCtx, s.iface, s.cfg, engines) }()
+	telemetry.LinkUp.WithLabelValues(s.iface).Set(1)
+	defer telemetry.LinkUp.WithLabelValues(s.iface).Set(0)
+
+	for {
+		select {
+		case err := <-done:
+			if err == nil {
+				log.Printf("⏹️ Stack stopped for %s", s.iface)
+			}
+			return err
+		case st := <-updates:
+			*state = s.transition(*state, st)
+			if !state.Usable() {
+				// Paramos la captura: se relanzará al volver el enlace
+				cancel()
+				<-done
+				log.Printf("⏸️ Stack paused for %s (link down)", s.iface)
+				return nil
+			}
+		case cfg := <-s.reloads:
+			if needsRestart(s.cfg, cfg) {
+				// Backend, workers, forense o algoritmos activos: hay que rehacer la pila
+				s.configure(cfg)
+				cancel()
+				<-done
+				log.Printf("♻️ Stack for %s relaunching to apply new configuration", s.iface)
+				return nil
+			}
+			// Umbrales, cooldowns y listas de confianza: en caliente, sin soltar la captura
+			s.configure(cfg)
+			detector.Reload(engines, &cfg.Algorithms)
+		}
+	}
+}
+
+// needsRestart indica si el cambio de configuración no se puede aplicar en caliente.
+// Los parámetros del monitor de enlace los aplica configure; la lista de interfaces la
+// gestiona main (una pila por interfaz).
+func needsRestart(prev, next *config.Config) bool {
+	pn, nn := prev.Network, next.Network
+	pn.Interfaces, nn.Interfaces = nil, nil
+	pn.LinkFlapThreshold, pn.LinkFlapWindow, pn.RestartDelay = nn.LinkFlapThreshold, nn.LinkFlapWindow, nn.RestartDelay
+
+	return !reflect.DeepEqual(pn, nn) ||
+		!reflect.DeepEqual(prev.Forensics, next.Forensics) ||
+		!maps.Equal(enabledAlgorithms(&prev.Algorithms), enabledAlgorithms(&next.Algorithms))
+}
+
+// enabledAlgorithms devuelve el Enabled de cada algoritmo por nombre de campo. Recorre
+// AlgorithmConfig por reflexión: un algoritmo nuevo entra en la comparación sin tocar
+// esta función.
+func enabledAlgorithms(a *config.AlgorithmConfig) map[string]bool {
+	v := reflect.ValueOf(a).Elem()
+	out := make(map[string]bool, v.NumField())
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).Kind() != reflect.Struct {
+			continue
+		}
+		if enabled := v.Field(i).FieldByName("Enabled"); enabled.Kind() == reflect.Bool {
+			out[v.Type().Field(i).Name] = enabled.Bool()
+		}
+	}
+	return out
+}
+
+// transition emite las alertas de cambio de enlace y devuelve el nuevo estado.
+func (s *stack) transition(prev, cur linkmon.State) linkmon.State {
+	switch {
+	case prev.Usable() && !cur.Usable():
+		s.linkDown(cur)
+		s.checkFlap()
+	case !prev.Usable() && cur.Usable():
+		telemetry.LinkEvents.WithLabelValues(s.iface, "up").Inc()
+		go s.notify.Notify(s.linkEvent("LinkUp", notifier.SeverityInfo, "🔌 LINK UP").
+			With("STATUS", "Link restored with carrier. Capture stack restarting."))
+	}
+	return cur
+}
+
+// linkDown distingue caída administrativa / interfaz eliminada de pérdida de portadora.
+func (s *stack) linkDown(st linkmon.State) {
+	if st.Exists && st.Up && !st.Carrier {
+		telemetry.LinkEvents.WithLabelValues(s.iface, "carrier_lost").Inc()
+		go s.notify.Notify(s.linkEvent("CarrierLost", notifier.SeverityWarning, "📉 CARRIER LOST!").
+			With("STATUS", "Interface is up but has no carrier. Capture paused.").
+			With("CAUSE", "Cable unplugged, remote port shut down (err-disable / BPDU guard) or NIC failure."))
+		return
+	}
+
+	reason := "Administratively down"
+	if !st.Exists {
+		reason = "Interface not present"
+	}
+	telemetry.LinkEvents.WithLabelValues(s.iface, "down").Inc()
+	go s.notify.Notify(s.linkEvent("LinkDown", notifier.SeverityWarning, "🔌 LINK DOWN!").
+		With("STATUS", reason+". Capture paused until the link returns."))
+}
+
+// linkEvent construye un evento del monitor de enlace para esta interfaz.
+func (s *stack) linkEvent(threatType string, severity notifier.Severity, title string) notifier.Event {
+	return notifier.Event{
+		Interface:  s.iface,
+		Algorithm:  "LinkMonitor",
+		ThreatType: threatType,
+		Severity:   severity,
+		Title:      title,
+	}
+}
+
+// checkFlap alerta si el enlace cae flap_threshold veces dentro de flap_window.
+// Un puerto que rebota es en sí un síntoma de bucle (err-disable recovery en ciclo).
+func (s *stack) checkFlap() {
+	now := time.Now()
+	kept := s.transitions[:0]
+	for _, t := range s.transitions {
+		if now.Sub(t) < s.flapWindow {
+			kept = append(kept, t)
+		}
+	}
+	s.transitions = append(kept, now)
+
+	if len(s.transitions) < s.flapThreshold || now.Sub(s.lastFlap) < s.flapWindow {
+		return
+	}
+	s.lastFlap = now
+
+	telemetry.LinkEvents.WithLabelValues(s.iface, "flap").Inc()
+	count := len(s.transitions)
+	go s.notify.Notify(s.linkEvent("LinkFlapping", notifier.SeverityWarning, "🔁 LINK FLAPPING!").
+		With("DOWNS", fmt.Sprintf("%d times in %s", count, s.flapWindow)).
+		With("ANALYSIS", "Port bouncing. Loop protection (err-disable recovery) cycling, bad cabling or failing NIC."))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+func TestNeedsRestart(t *testing.T) {
+	base := func() *config.Config {
+		c := &config.Config{}
+		c.Network.Interfaces = []string{"eth0"}
+		c.Network.CaptureMode = "socket"
+		c.Network.LinkFlapThreshold = 5
+		c.Forensics.Enabled = true
+		c.Algorithms.EtherFuse.Enabled = true
+		c.Algorithms.EtherFuse.AlertThreshold = 50
+		return c
+	}
+	cases := []struct {
+		name   string
+		change func(c *config.Config)
+		want   bool
+	}{
+		{"sin cambios", func(c *config.Config) {}, false},
+		{"lista de interfaces (la gestiona main)", func(c *config.Config) { c.Network.Interfaces = []string{"eth0", "eth1"} }, false},
+		{"monitor de enlace (en caliente)", func(c *config.Config) {
+			c.Network.LinkFlapThreshold, c.Network.LinkFlapWindow, c.Network.RestartDelay = 9, "30s", "5s"
+		}, false},
+		{"umbral de un algoritmo (en caliente)", func(c *config.Config) { c.Algorithms.EtherFuse.AlertThreshold = 80 }, false},
+		{"backend de captura", func(c *config.Config) { c.Network.CaptureMode = "ring" }, true},
+		{"workers", func(c *config.Config) { c.Network.Workers = 4 }, true},
+		{"forense", func(c *config.Config) { c.Forensics.BufferMB = 64 }, true},
+		{"algoritmo desactivado", func(c *config.Config) { c.Algorithms.EtherFuse.Enabled = false }, true},
+		{"algoritmo nuevo activado", func(c *config.Config) { c.Algorithms.McastPolicer.Enabled = true }, true},
+	}
+	for _, c := range cases {
+		next := base()
+		c.change(next)
+		if got := needsRestart(base(), next); got != c.want {
+			t.Errorf("%s: needsRestart = %v, esperaba %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestEnabledAlgorithms_CoversEveryAlgorithm(t *testing.T) {
+	var a config.AlgorithmConfig
+	typ := reflect.TypeOf(a)
+	var algorithms int
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).Type.Kind() == reflect.Struct {
+			algorithms++
+		}
+	}
+	a.McastPolicer.Enabled = true
+	got := enabledAlgorithms(&a)
+	if len(got) != algorithms {
+		t.Errorf("Algoritmos con Enabled: %d de %d (%v)", len(got), algorithms, got)
+	}
+	if !got["McastPolicer"] || got["EtherFuse"] {
+		t.Errorf("Estado incorrecto: %v", got)
+	}
+}
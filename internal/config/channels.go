@@ -0,0 +1,58 @@
+package config
+
+import "strings"
+
+// channelKind es un tipo de canal de alertas. named comprueba "<tipo>:<nombre>" en
+// los tipos con varias instancias (webhooks con nombre); nil = sólo "<tipo>". Como los
+// criterios de las reglas, los nombres de canal no distinguen mayúsculas.
+type channelKind struct {
+	name  string
+	named func(a *AlertsConfig, name string) bool
+}
+
+// channelKinds son los canales que aceptan [alerts.routing] y [alerts.spool] timeouts.
+// Los registra el notifier al arrancar (notifier.Register): la validación no mantiene
+// su propia lista.
+var channelKinds []channelKind
+
+// RegisterChannel añade un tipo de canal. Se llama desde init, antes de validar.
+func RegisterChannel(kind string, named func(a *AlertsConfig, name string) bool) {
+	for _, k := range channelKinds {
+		if k.name == kind {
+			panic("config: channel kind '" + kind + "' registered twice")
+		}
+	}
+	channelKinds = append(channelKinds, channelKind{kind, named})
+}
+
+// checkChannel valida un nombre de canal contra el registro ("" = válido).
+func checkChannel(a *AlertsConfig, ch string) string {
+	kind, name, hasName := strings.Cut(strings.TrimSpace(ch), ":")
+	for _, k := range channelKinds {
+		if !strings.EqualFold(k.name, kind) {
+			continue
+		}
+		switch {
+		case !hasName:
+			return ""
+		case k.named == nil:
+			return "unknown channel '" + ch + "' (" + channelNames() + ")"
+		case !k.named(a, name):
+			return "unknown " + kind + " '" + name + "' (not defined in the configuration)"
+		}
+		return ""
+	}
+	return "unknown channel '" + ch + "' (" + channelNames() + ")"
+}
+
+// channelNames: "webhook|webhook:<name>|syslog|..." para los mensajes de error.
+func channelNames() string {
+	var names []string
+	for _, k := range channelKinds {
+		names = append(names, k.name)
+		if k.named != nil {
+			names = append(names, k.name+":<name>")
+		}
+	}
+	return strings.Join(names, "|")
+}
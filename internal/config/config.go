@@ -12,6 +12,7 @@ type Config struct {
 	Algorithms AlgorithmConfig `toml:"algorithms"`
 	Alerts     AlertsConfig    `toml:"alerts"`
 	Telemetry  TelemetryConfig `toml:"telemetry"`
+	Forensics  ForensicsConfig `toml:"forensics"`
 }
 
 type SystemConfig struct {
@@ -24,12 +25,40 @@ type TelemetryConfig struct {
 	ListenAddress string `toml:"listen_address"`
 }
 
+// ForensicsConfig controla el ring de captura en memoria que se vuelca a pcapng al alertar.
+type ForensicsConfig struct {
+	Enabled      bool   `toml:"enabled"`
+	Directory    string `toml:"directory"`
+	Window       string `toml:"window"`        // Antigüedad máxima de las tramas retenidas (ej: "10s")
+	BufferMB     int    `toml:"buffer_mb"`     // Memoria máxima del ring por interfaz
+	MaxFiles     int    `toml:"max_files"`     // Ficheros pcapng máximos en disco (global)
+	MaxDiskMB    int    `toml:"max_disk_mb"`   // Espacio máximo en disco (global)
+	DumpCooldown string `toml:"dump_cooldown"` // Alertas dentro de este intervalo reutilizan el mismo volcado
+}
+
 type NetworkConfig struct {
 	Interfaces []string `toml:"interfaces"`
 	SnapLen    int      `toml:"snaplen"`
+
+	// Backend de captura: "socket" (recvfrom por trama) o "ring" (TPACKET_V3 mmap)
+	CaptureMode   string `toml:"capture_mode"`
+	RingBlockKB   int    `toml:"ring_block_kb"`
+	RingBlocks    int    `toml:"ring_blocks"`
+	RingTimeoutMs int    `toml:"ring_timeout_ms"`
+
+	// Workers de captura por interfaz (PACKET_FANOUT por MAC origen)
+	Workers int `toml:"workers"`
+
+	// Monitor de enlace (netlink): flapping y reinicio automático de la captura
+	LinkFlapThreshold int    `toml:"link_flap_threshold"`
+	LinkFlapWindow    string `toml:"link_flap_window"`
+	RestartDelay      string `toml:"restart_delay"`
 }
 
 type AlgorithmConfig struct {
+	// HoldDown: tiempo sin detecciones tras el que una condición se da por resuelta
+	HoldDown string `toml:"hold_down"`
+
 	EtherFuse    EtherFuseConfig    `toml:"etherfuse"`
 	ActiveProbe  ActiveProbeConfig  `toml:"active_probe"`
 	MacStorm     MacStormConfig     `toml:"mac_storm"`
@@ -39,6 +68,7 @@ type AlgorithmConfig struct {
 	FlowPanic    FlowPanicConfig    `toml:"flow_panic"`
 	RaGuard      RaGuardConfig      `toml:"ra_guard"`
 	McastPolicer McastPolicerConfig `toml:"mcast_policer"`
+	StpMonitor   StpMonitorConfig   `toml:"stp_monitor"`
 }
 
 // --- ALGORITMOS ---
@@ -162,34 +192,155 @@ type McastPolicerOverride struct {
 	MaxPPS uint64 `toml:"max_pps"`
 }
 
+type StpMonitorConfig struct {
+	Enabled        bool     `toml:"enabled"`
+	TrustedBridges []string `toml:"trusted_bridges"` // MAC base de los puentes autorizados ([] = no se comprueba)
+	MaxTCN         int      `toml:"max_tcn"`         // Cambios de topología por VLAN tolerados en tcn_window
+	TCNWindow      string   `toml:"tcn_window"`
+	BPDUTimeout    string   `toml:"bpdu_timeout"` // Silencio tras el que unas BPDUs vistas se dan por perdidas
+	AlertCooldown  string   `toml:"alert_cooldown"`
+
+	Overrides map[string]StpMonitorOverride `toml:"overrides"`
+}
+
+type StpMonitorOverride struct {
+	TrustedBridges []string `toml:"trusted_bridges"`
+	MaxTCN         int      `toml:"max_tcn"`
+	BPDUTimeout    string   `toml:"bpdu_timeout"`
+}
+
 // --- ALERTAS ---
 
 type AlertsConfig struct {
-	SyslogServer string          `toml:"syslog_server"`
-	Dampening    DampeningConfig `toml:"dampening"` 
-	Webhook      WebhookConfig   `toml:"webhook"`
-	Smtp         SmtpConfig      `toml:"smtp"`
-	Telegram     TelegramConfig  `toml:"telegram"`
+	SyslogServer string              `toml:"syslog_server"` // Atajo clásico: equivale a [alerts.syslog] server (UDP)
+	Syslog       SyslogConfig        `toml:"syslog"`
+	Dampening    DampeningConfig     `toml:"dampening"`
+	Webhook      WebhookConfig       `toml:"webhook"`
+	Webhooks     []WebhookTarget     `toml:"webhooks"` // Webhooks genéricos con nombre (canal "webhook:<name>")
+	Smtp         SmtpConfig          `toml:"smtp"`
+	Telegram     TelegramConfig      `toml:"telegram"`
+	Alertmanager AlertmanagerConfig  `toml:"alertmanager"`
+	PagerDuty    PagerDutyConfig     `toml:"pagerduty"`
+	Opsgenie     OpsgenieConfig      `toml:"opsgenie"`
+	Mqtt         MqttConfig          `toml:"mqtt"`
+	Routing      RoutingConfig       `toml:"routing"`
+	Spool        SpoolConfig         `toml:"spool"`
+	Silences     SilencesConfig      `toml:"silences"`
+	Maintenance  []MaintenanceWindow `toml:"maintenance"` // Ventanas recurrentes (se aplican también con SIGHUP)
+}
+
+// SilencesConfig: silencios creados en caliente con la API local o "loopwarden silence".
+type SilencesConfig struct {
+	File   string `toml:"file"`   // Persistencia entre reinicios ("" = sólo en memoria)
+	Listen string `toml:"listen"` // API local, p.ej. "127.0.0.1:9091" ("" = sin API)
+	Token  string `toml:"token"`  // La API exige "Authorization: Bearer <token>"
+
+	// Sin token cualquier proceso local puede silenciar las alertas: hay que pedirlo
+	// expresamente.
+	AllowUnauthenticated bool `toml:"allow_unauthenticated"`
+}
+
+// MaintenanceWindow: ventana recurrente durante la que no se envían las alertas de
+// su ámbito (siguen contando en métricas y en el log). Los criterios vacíos encajan
+// con cualquier valor.
+type MaintenanceWindow struct {
+	Name       string   `toml:"name"`
+	Days       []string `toml:"days"`     // "mon".."sun"; vacío = todos los días
+	Start      string   `toml:"start"`    // "HH:MM"
+	Duration   string   `toml:"duration"` // "2h" (puede cruzar la medianoche, máximo 7 días)
+	Timezone   string   `toml:"timezone"` // "Europe/Madrid"; "" = hora local del sensor
+	Interfaces []string `toml:"interfaces"`
+	Algorithms []string `toml:"algorithms"`
+	MACs       []string `toml:"macs"`
+	VLANs      []string `toml:"vlans"`
+}
+
+// SpoolConfig: cola persistente por canal con reintentos (las alertas sobreviven a
+// caídas del uplink y a reinicios del proceso).
+type SpoolConfig struct {
+	Directory    string `toml:"directory"`     // "" = cola sólo en memoria
+	MaxAge       string `toml:"max_age"`       // Alertas más antiguas se descartan sin enviar
+	MaxSizeMB    int    `toml:"max_size_mb"`   // Tamaño máximo de la cola de cada canal
+	RetryInitial string `toml:"retry_initial"` // Primer reintento tras un fallo
+	RetryMax     string `toml:"retry_max"`     // Techo del backoff exponencial
+
+	// Timeout de cada intento de envío por canal ("smtp" = "60s"). La clave "webhook"
+	// vale también para los webhooks con nombre que no tengan la suya.
+	Timeouts map[string]string `toml:"timeouts"`
+}
+
+// RoutingConfig decide a qué canales va cada evento. Las reglas se evalúan en orden
+// y gana la primera que encaja; si ninguna encaja se usa Default. Sin reglas ni
+// Default, todos los canales activos reciben todo (comportamiento clásico).
+type RoutingConfig struct {
+	Default []string    `toml:"default"` // Canales del catch-all: "webhook", "webhook:<name>", "syslog", "smtp", "telegram"...
+	Rules   []RouteRule `toml:"rules"`
+}
+
+// RouteRule: los criterios vacíos encajan con cualquier valor.
+type RouteRule struct {
+	MinSeverity string   `toml:"min_severity"` // "info" | "warning" | "critical"
+	Algorithms  []string `toml:"algorithms"`   // "EtherFuse", "ActiveProbe", "System", "LinkMonitor"...
+	ThreatTypes []string `toml:"threat_types"` // "HardLoop", "HostFlood"... (etiqueta de engine_hits)
+	Interfaces  []string `toml:"interfaces"`
+	Channels    []string `toml:"channels"` // Vacío = descartar (sólo queda en el log)
+}
+
+// SyslogConfig: salida RFC 5424 con structured data.
+type SyslogConfig struct {
+	Server     string `toml:"server"`      // "IP:Puerto" (tiene prioridad sobre syslog_server)
+	Protocol   string `toml:"protocol"`    // "udp" | "tcp" | "tls"
+	Facility   string `toml:"facility"`    // "local0".."local7", "daemon", "security"...
+	Hostname   string `toml:"hostname"`    // Default: hostname del sistema
+	AppName    string `toml:"app_name"`    // Default: "loopwarden"
+	CAFile     string `toml:"ca_file"`     // TLS: sólo se confía en esta CA (PEM)
+	ServerName string `toml:"server_name"` // TLS: nombre a verificar (default: host de server)
+	CertFile   string `toml:"cert_file"`   // TLS: certificado cliente (mTLS)
+	KeyFile    string `toml:"key_file"`
 }
 
 type DampeningConfig struct {
 	MaxAlertsPerMinute int    `toml:"max_alerts_per_minute"` 
 	MuteDuration       string `toml:"mute_duration"`         
+	Mode               string `toml:"mode"`            // "" / "mute" (default): silencio total | "digest": resúmenes por grupo
+	DigestInterval     string `toml:"digest_interval"` // Cadencia de los resúmenes (default 1m)
+	BypassSeverity     string `toml:"bypass_severity"` // Desde esta severidad no se agrupa ni silencia ("" = ninguna)
 }
 
 type WebhookConfig struct {
 	Enabled bool   `toml:"enabled"`
 	URL     string `toml:"url"`
+	Format  string `toml:"format"` // "" = {"text", "event"} | "slack" | "teams" | "discord"
+}
+
+// WebhookTarget: webhook genérico con cuerpo por plantilla y firma HMAC.
+type WebhookTarget struct {
+	Name            string            `toml:"name"`
+	Enabled         bool              `toml:"enabled"`
+	URL             string            `toml:"url"`
+	Method          string            `toml:"method"`           // Default: POST
+	ContentType     string            `toml:"content_type"`     // Default: application/json
+	Headers         map[string]string `toml:"headers"`
+	Template        string            `toml:"template"`         // text/template sobre el evento; tiene prioridad sobre format
+	Format          string            `toml:"format"`           // "" = evento en JSON | "slack" | "teams" | "discord"
+	Secret          string            `toml:"secret"`           // Firma HMAC-SHA256 de timestamp + cuerpo
+	SignatureHeader string            `toml:"signature_header"` // Default: X-LoopWarden-Signature
 }
 
 type SmtpConfig struct {
 	Enabled bool   `toml:"enabled"`
 	Host    string `toml:"host"`
 	Port    int    `toml:"port"`
-	User    string `toml:"user"`
+	User    string `toml:"user"` // Vacío = relay sin autenticación
 	Pass    string `toml:"pass"`
 	To      string `toml:"to"`
 	From    string `toml:"from"`
+
+	Recipients         []string `toml:"recipients"`           // Destinatarios adicionales a "to"
+	TLS                string   `toml:"tls"`                  // "" (STARTTLS si se ofrece) | "starttls" | "implicit" | "none"
+	CAFile             string   `toml:"ca_file"`              // CA interna del servidor de correo (PEM)
+	InsecureSkipVerify bool     `toml:"insecure_skip_verify"` // Sólo para laboratorio
+	Subject            string   `toml:"subject"`              // text/template del asunto
 }
 
 type TelegramConfig struct {
@@ -198,6 +349,52 @@ type TelegramConfig struct {
 	ChatID  string `toml:"chat_id"`
 }
 
+// AlertmanagerConfig: canal nativo hacia la API v2 de Prometheus Alertmanager.
+type AlertmanagerConfig struct {
+	Enabled        bool              `toml:"enabled"`
+	URL            string            `toml:"url"`             // "http://alertmanager:9093" (se añade /api/v2/alerts)
+	ResendInterval string            `toml:"resend_interval"` // Refresco de las alertas activas (default 1m)
+	GeneratorURL   string            `toml:"generator_url"`   // Enlace de vuelta (p.ej. Grafana del sensor)
+	Labels         map[string]string `toml:"labels"`          // Etiquetas fijas extra (site, team...)
+	User           string            `toml:"user"`            // Basic auth (opcional)
+	Pass           string            `toml:"pass"`
+}
+
+// PagerDutyConfig: Events API v2 (trigger/resolve con dedup_key por incidente).
+type PagerDutyConfig struct {
+	Enabled    bool   `toml:"enabled"`
+	RoutingKey string `toml:"routing_key"` // Integration key del servicio
+	URL        string `toml:"url"`         // Default: https://events.pagerduty.com/v2/enqueue
+}
+
+// MqttConfig: cada evento se publica en JSON en <topic_prefix>/<sensor>/<interfaz>/<algoritmo>
+// y el estado del sensor ("online"/"offline", retenido y con Last Will) en
+// <topic_prefix>/<sensor>/status.
+type MqttConfig struct {
+	Enabled     bool   `toml:"enabled"`
+	Broker      string `toml:"broker"`       // "tcp://host:1883" | "tls://host:8883"
+	Version     string `toml:"version"`      // "3.1.1" (default) | "5"
+	ClientID    string `toml:"client_id"`    // Default: "loopwarden-<sensor>"
+	User        string `toml:"user"`
+	Pass        string `toml:"pass"`
+	QoS         int    `toml:"qos"`          // 0 | 1 | 2
+	Retain      bool   `toml:"retain"`       // Retener también los eventos (el último de cada topic)
+	TopicPrefix string `toml:"topic_prefix"` // Default: "loopwarden"
+	KeepAlive   string `toml:"keep_alive"`   // Default: 30s
+	CAFile      string `toml:"ca_file"`      // TLS: sólo se confía en esta CA (PEM)
+	ServerName  string `toml:"server_name"`  // TLS: nombre a verificar (default: host del broker)
+	CertFile    string `toml:"cert_file"`    // TLS: certificado cliente (mTLS)
+	KeyFile     string `toml:"key_file"`
+}
+
+// OpsgenieConfig: Alert API v2 (create/close por alias).
+type OpsgenieConfig struct {
+	Enabled bool     `toml:"enabled"`
+	APIKey  string   `toml:"api_key"`
+	URL     string   `toml:"url"` // Default: https://api.opsgenie.com (EU: https://api.eu.opsgenie.com)
+	Tags    []string `toml:"tags"`
+}
+
 func LoadConfig(path string) (*Config, error) {
 	var cfg Config
 	data, err := os.ReadFile(path)
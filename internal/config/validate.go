@@ -0,0 +1,328 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"text/template/parse"
+	"time"
+)
+
+// Validate comprueba lo que de otro modo sólo se detectaría al arrancar cada algoritmo
+// (y se corregiría en silencio con un default). En una recarga (SIGHUP) un fichero
+// inválido se rechaza entero y se mantiene la configuración en ejecución.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+	duration := func(key, value string) {
+		if value == "" {
+			return
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			add("%s: invalid duration '%s'", key, value)
+		}
+	}
+	macs := func(key string, list []string) {
+		for _, m := range list {
+			if _, err := net.ParseMAC(strings.TrimSpace(m)); err != nil {
+				add("%s: invalid MAC '%s'", key, m)
+			}
+		}
+	}
+	cidrs := func(key string, list []string) {
+		for _, cidr := range list {
+			if _, _, err := net.ParseCIDR(strings.TrimSpace(cidr)); err != nil {
+				add("%s: invalid CIDR '%s'", key, cidr)
+			}
+		}
+	}
+
+	// --- Network ---
+	n := &c.Network
+	if len(n.Interfaces) == 0 {
+		add("network.interfaces: no interfaces defined")
+	}
+	seen := make(map[string]bool)
+	for _, iface := range n.Interfaces {
+		if seen[iface] {
+			add("network.interfaces: duplicate interface '%s'", iface)
+		}
+		seen[iface] = true
+	}
+	if n.SnapLen < 64 || n.SnapLen > 65535 {
+		add("network.snaplen: %d out of range (64-65535)", n.SnapLen)
+	}
+	if n.CaptureMode != "" && n.CaptureMode != "socket" && n.CaptureMode != "ring" {
+		add("network.capture_mode: unknown mode '%s' (socket|ring)", n.CaptureMode)
+	}
+	if n.Workers < 0 {
+		add("network.workers: must be >= 0")
+	}
+	duration("network.link_flap_window", n.LinkFlapWindow)
+	duration("network.restart_delay", n.RestartDelay)
+
+	// --- Algoritmos ---
+	a := &c.Algorithms
+	if a.EtherFuse.Enabled && a.EtherFuse.HistorySize <= 0 {
+		add("algorithms.etherfuse.history_size: must be > 0")
+	}
+	duration("algorithms.hold_down", a.HoldDown)
+	duration("algorithms.etherfuse.alert_cooldown", a.EtherFuse.AlertCooldown)
+	duration("algorithms.mac_storm.alert_cooldown", a.MacStorm.AlertCooldown)
+	duration("algorithms.flap_guard.window", a.FlapGuard.Window)
+	duration("algorithms.flap_guard.alert_cooldown", a.FlapGuard.AlertCooldown)
+	for iface, o := range a.FlapGuard.Overrides {
+		duration("algorithms.flap_guard.overrides."+iface+".window", o.Window)
+	}
+	duration("algorithms.arp_watch.alert_cooldown", a.ArpWatch.AlertCooldown)
+
+	macs("algorithms.dhcp_hunter.trusted_macs", a.DhcpHunter.TrustedMacs)
+	cidrs("algorithms.dhcp_hunter.trusted_cidrs", a.DhcpHunter.TrustedCidrs)
+	for iface, o := range a.DhcpHunter.Overrides {
+		macs("algorithms.dhcp_hunter.overrides."+iface+".trusted_macs", o.TrustedMacs)
+		cidrs("algorithms.dhcp_hunter.overrides."+iface+".trusted_cidrs", o.TrustedCidrs)
+	}
+	macs("algorithms.ra_guard.trusted_macs", a.RaGuard.TrustedMacs)
+	for iface, o := range a.RaGuard.Overrides {
+		macs("algorithms.ra_guard.overrides."+iface+".trusted_macs", o.TrustedMacs)
+	}
+	macs("algorithms.stp_monitor.trusted_bridges", a.StpMonitor.TrustedBridges)
+	duration("algorithms.stp_monitor.tcn_window", a.StpMonitor.TCNWindow)
+	duration("algorithms.stp_monitor.bpdu_timeout", a.StpMonitor.BPDUTimeout)
+	duration("algorithms.stp_monitor.alert_cooldown", a.StpMonitor.AlertCooldown)
+	if a.StpMonitor.MaxTCN < 0 {
+		add("algorithms.stp_monitor.max_tcn: must be >= 0")
+	}
+	for iface, o := range a.StpMonitor.Overrides {
+		key := "algorithms.stp_monitor.overrides." + iface
+		macs(key+".trusted_bridges", o.TrustedBridges)
+		duration(key+".bpdu_timeout", o.BPDUTimeout)
+	}
+
+	// --- Alertas y Forense ---
+	duration("alerts.dampening.mute_duration", c.Alerts.Dampening.MuteDuration)
+	duration("alerts.dampening.digest_interval", c.Alerts.Dampening.DigestInterval)
+	switch strings.ToLower(c.Alerts.Dampening.Mode) {
+	case "", "digest", "mute":
+	default:
+		add("alerts.dampening.mode: unknown mode '%s' (digest|mute)", c.Alerts.Dampening.Mode)
+	}
+	switch strings.ToLower(c.Alerts.Dampening.BypassSeverity) {
+	case "", "info", "warning", "critical":
+	default:
+		add("alerts.dampening.bypass_severity: unknown severity '%s' (info|warning|critical)", c.Alerts.Dampening.BypassSeverity)
+	}
+	duration("alerts.spool.max_age", c.Alerts.Spool.MaxAge)
+	duration("alerts.spool.retry_initial", c.Alerts.Spool.RetryInitial)
+	duration("alerts.spool.retry_max", c.Alerts.Spool.RetryMax)
+	if c.Alerts.Spool.MaxSizeMB < 0 {
+		add("alerts.spool.max_size_mb: must be >= 0")
+	}
+	sl := &c.Alerts.Syslog
+	switch strings.ToLower(sl.Protocol) {
+	case "", "udp", "tcp", "tls":
+	default:
+		add("alerts.syslog.protocol: unknown protocol '%s' (udp|tcp|tls)", sl.Protocol)
+	}
+	if sl.Facility != "" && !validFacility(strings.ToLower(sl.Facility)) {
+		add("alerts.syslog.facility: unknown facility '%s'", sl.Facility)
+	}
+	if (sl.CertFile == "") != (sl.KeyFile == "") {
+		add("alerts.syslog: cert_file and key_file must be set together")
+	}
+	httpURL := func(key, value string) {
+		if u, err := url.Parse(value); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			add("%s: invalid URL '%s' (http[s]://host:port)", key, value)
+		}
+	}
+	if am := &c.Alerts.Alertmanager; am.Enabled {
+		httpURL("alerts.alertmanager.url", am.URL)
+		duration("alerts.alertmanager.resend_interval", am.ResendInterval)
+	}
+	if pd := &c.Alerts.PagerDuty; pd.Enabled {
+		if pd.RoutingKey == "" {
+			add("alerts.pagerduty.routing_key: required when enabled")
+		}
+		if pd.URL != "" {
+			httpURL("alerts.pagerduty.url", pd.URL)
+		}
+	}
+	if og := &c.Alerts.Opsgenie; og.Enabled {
+		if og.APIKey == "" {
+			add("alerts.opsgenie.api_key: required when enabled")
+		}
+		if og.URL != "" {
+			httpURL("alerts.opsgenie.url", og.URL)
+		}
+	}
+	if mq := &c.Alerts.Mqtt; mq.Enabled {
+		u, err := url.Parse(mq.Broker)
+		switch {
+		case err != nil || u.Host == "":
+			add("alerts.mqtt.broker: invalid address '%s' (tcp://host:1883 | tls://host:8883)", mq.Broker)
+		case u.Scheme != "tcp" && u.Scheme != "mqtt" && u.Scheme != "tls" && u.Scheme != "ssl" && u.Scheme != "mqtts":
+			add("alerts.mqtt.broker: unknown scheme '%s' (tcp|mqtt|tls|ssl|mqtts)", u.Scheme)
+		}
+		switch mq.Version {
+		case "", "3.1.1", "5":
+		default:
+			add("alerts.mqtt.version: unsupported version '%s' (3.1.1|5)", mq.Version)
+		}
+		if mq.QoS < 0 || mq.QoS > 2 {
+			add("alerts.mqtt.qos: %d out of range (0-2)", mq.QoS)
+		}
+		if strings.ContainsAny(mq.TopicPrefix, "+#") {
+			add("alerts.mqtt.topic_prefix: wildcards '+' and '#' are not allowed")
+		}
+		duration("alerts.mqtt.keep_alive", mq.KeepAlive)
+	}
+	if sm := &c.Alerts.Smtp; sm.Enabled {
+		switch strings.ToLower(sm.TLS) {
+		case "", "starttls", "implicit", "none":
+		default:
+			add("alerts.smtp.tls: unknown mode '%s' (starttls|implicit|none)", sm.TLS)
+		}
+		if sm.To == "" && len(sm.Recipients) == 0 {
+			add("alerts.smtp: at least one recipient required (to / recipients)")
+		}
+		if err := checkTemplate("alerts.smtp.subject", sm.Subject); err != nil {
+			add("%v", err)
+		}
+	}
+	chatFormat := func(key, value string) {
+		switch value {
+		case "", "slack", "teams", "discord":
+		default:
+			add("%s: unknown format '%s' (slack|teams|discord)", key, value)
+		}
+	}
+	chatFormat("alerts.webhook.format", c.Alerts.Webhook.Format)
+	targets := make(map[string]bool) // En minúsculas: el router no distingue mayúsculas
+	for i, w := range c.Alerts.Webhooks {
+		key := fmt.Sprintf("alerts.webhooks[%d]", i)
+		switch {
+		case w.Name == "" || strings.ContainsAny(w.Name, ":/ "):
+			add("%s.name: required, without ':', '/' or spaces", key)
+		case targets[strings.ToLower(w.Name)]:
+			add("%s.name: duplicate webhook '%s'", key, w.Name)
+		}
+		targets[strings.ToLower(w.Name)] = true
+		if !w.Enabled {
+			continue
+		}
+		httpURL(key+".url", w.URL)
+		switch strings.ToUpper(w.Method) {
+		case "", "POST", "PUT", "PATCH":
+		default:
+			add("%s.method: unsupported method '%s' (POST|PUT|PATCH)", key, w.Method)
+		}
+		chatFormat(key+".format", w.Format)
+		if err := checkTemplate(key+".template", w.Template); err != nil {
+			add("%v", err) // "template: alerts.webhooks[0].template:1: ..."
+		}
+	}
+	channels := func(key string, list []string) {
+		for _, ch := range list {
+			if msg := checkChannel(&c.Alerts, ch); msg != "" {
+				add("%s: %s", key, msg)
+			}
+		}
+	}
+	channels("alerts.routing.default", c.Alerts.Routing.Default)
+	timeouts := make([]string, 0, len(c.Alerts.Spool.Timeouts))
+	for ch := range c.Alerts.Spool.Timeouts {
+		timeouts = append(timeouts, ch)
+	}
+	sort.Strings(timeouts)
+	channels("alerts.spool.timeouts", timeouts)
+	for _, ch := range timeouts {
+		key := "alerts.spool.timeouts." + ch
+		if d, err := time.ParseDuration(c.Alerts.Spool.Timeouts[ch]); err != nil || d <= 0 {
+			add("%s: invalid duration '%s'", key, c.Alerts.Spool.Timeouts[ch])
+		}
+	}
+	for i, r := range c.Alerts.Routing.Rules {
+		key := fmt.Sprintf("alerts.routing.rules[%d]", i)
+		switch strings.ToLower(r.MinSeverity) {
+		case "", "info", "warning", "critical":
+		default:
+			add("%s.min_severity: unknown severity '%s' (info|warning|critical)", key, r.MinSeverity)
+		}
+		channels(key+".channels", r.Channels)
+	}
+	if s := c.Alerts.Silences; s.Listen != "" {
+		if _, _, err := net.SplitHostPort(s.Listen); err != nil {
+			add("alerts.silences.listen: invalid address '%s' (host:port)", s.Listen)
+		}
+		if s.Token == "" && !s.AllowUnauthenticated {
+			add("alerts.silences.token: required when listen is set (or allow_unauthenticated = true)")
+		}
+	}
+	windows := make(map[string]bool)
+	for i, w := range c.Alerts.Maintenance {
+		key := fmt.Sprintf("alerts.maintenance[%d]", i)
+		if w.Name == "" || windows[w.Name] {
+			add("%s.name: required and unique", key)
+		}
+		windows[w.Name] = true
+		for _, d := range w.Days {
+			if _, ok := ParseWeekday(d); !ok {
+				add("%s.days: unknown day '%s' (mon..sun)", key, d)
+			}
+		}
+		if _, err := time.Parse("15:04", w.Start); err != nil {
+			add("%s.start: invalid time '%s' (HH:MM)", key, w.Start)
+		}
+		if d, err := time.ParseDuration(w.Duration); err != nil || d <= 0 || d > 7*24*time.Hour {
+			add("%s.duration: invalid duration '%s' (0 < duration <= 168h)", key, w.Duration)
+		}
+		if _, err := time.LoadLocation(w.Timezone); err != nil {
+			add("%s.timezone: %v", key, err)
+		}
+		macs(key+".macs", w.MACs)
+	}
+	duration("forensics.window", c.Forensics.Window)
+	duration("forensics.dump_cooldown", c.Forensics.DumpCooldown)
+
+	return errors.Join(errs...)
+}
+
+// validFacility acepta los nombres de facility de syslog (RFC 5424 §6.2.1).
+func validFacility(name string) bool {
+	switch name {
+	case "kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron", "authpriv",
+		"ftp", "ntp", "security", "console", "solaris-cron",
+		"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7":
+		return true
+	}
+	return false
+}
+
+// checkTemplate comprueba la sintaxis de una plantilla de webhook. Las funciones
+// (json, text...) las aporta el notifier: aquí sólo se valida la estructura.
+func checkTemplate(name, text string) error {
+	t := parse.New(name)
+	t.Mode = parse.SkipFuncCheck
+	_, err := t.Parse(text, "", "", map[string]*parse.Tree{})
+	return err
+}
+
+// ParseWeekday acepta "mon".."sun" y los nombres completos en inglés.
+func ParseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) < 3 {
+		return 0, false
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		full := strings.ToLower(d.String())
+		if name == full || name == full[:3] {
+			return d, true
+		}
+	}
+	return 0, false
+}
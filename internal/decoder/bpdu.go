@@ -0,0 +1,119 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+)
+
+// Tipos y flags de BPDU (802.1D-2004 §9.3, 802.1Q-2014 §14).
+const (
+	BPDUTypeConfig = 0x00 // STP Configuration
+	BPDUTypeRST    = 0x02 // RSTP y MSTP (la CIST viaja en la parte común)
+	BPDUTypeTCN    = 0x80 // STP Topology Change Notification
+
+	BPDUFlagTC  = 0x01 // Topology Change
+	BPDUFlagTCA = 0x80 // Topology Change Acknowledgment
+
+	bpduConfigLen = 35 // Hasta Forward Delay
+)
+
+var (
+	stpDst  = [6]byte{0x01, 0x80, 0xC2, 0x00, 0x00, 0x00}
+	sstpDst = [6]byte{0x01, 0x00, 0x0C, 0xCC, 0xCC, 0xCD}
+	sstpHdr = [8]byte{0xAA, 0xAA, 0x03, 0x00, 0x00, 0x0C, 0x01, 0x0B} // LLC SNAP, OUI Cisco, PID PVST+
+)
+
+// BridgeID es el identificador de puente: prioridad (con el system ID extension) y MAC.
+type BridgeID uint64
+
+// Priority devuelve los 16 bits altos (prioridad + VLAN/instancia en PVST+/MSTP).
+func (id BridgeID) Priority() uint16 { return uint16(id >> 48) }
+
+// MAC devuelve la dirección base del puente.
+func (id BridgeID) MAC() net.HardwareAddr {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(id))
+	return net.HardwareAddr(b[2:])
+}
+
+// String sigue la notación habitual de los switches: "32769.aa:bb:cc:dd:ee:ff".
+func (id BridgeID) String() string {
+	return fmt.Sprintf("%d.%s", id.Priority(), id.MAC())
+}
+
+// BPDU es la vista de una BPDU de Spanning Tree. En una TCN sólo son válidos
+// Version y Type.
+type BPDU struct {
+	Version  uint8 // 0 STP, 2 RSTP, 3 MSTP
+	Type     uint8
+	Flags    uint8
+	RootID   BridgeID
+	RootCost uint32
+	BridgeID BridgeID // Puente que emite la BPDU
+	PortID   uint16
+	Hello    uint16 // Hello Time en 1/256 s
+
+	PVST bool   // Cisco PVST+/SSTP (01:00:0c:cc:cc:cd)
+	VLAN uint16 // VLAN de origen del TLV PVID de PVST+, 0 si no lo lleva
+}
+
+// TCN indica si la BPDU notifica un cambio de topología (TCN o flag TC).
+func (b BPDU) TCN() bool {
+	return b.Type == BPDUTypeTCN || b.Flags&BPDUFlagTC != 0
+}
+
+// BPDU decodifica una BPDU IEEE (LLC 42-42-03) o Cisco PVST+ (SNAP 00-00-0C/010B).
+// Las BPDU viajan en tramas 802.3 (campo longitud en lugar de EtherType).
+func (f *Frame) BPDU() (BPDU, bool) {
+	var b BPDU
+	if !f.Valid || f.Eth.EtherType > 1500 {
+		return b, false
+	}
+	p := f.Data[f.Eth.L3Offset:]
+	switch [6]byte(f.Data[0:6]) {
+	case stpDst:
+		if len(p) < 3 || p[0] != 0x42 || p[1] != 0x42 || p[2] != 0x03 {
+			return b, false
+		}
+		p = p[3:]
+	case sstpDst:
+		if len(p) < len(sstpHdr) || [8]byte(p[:8]) != sstpHdr {
+			return b, false
+		}
+		p = p[8:]
+		b.PVST = true
+	default:
+		return b, false
+	}
+
+	// Protocol Identifier (0x0000) + Version + Type
+	if len(p) < 4 || p[0] != 0 || p[1] != 0 {
+		return b, false
+	}
+	b.Version, b.Type = p[2], p[3]
+	if b.Type == BPDUTypeTCN {
+		return b, true
+	}
+	if (b.Type != BPDUTypeConfig && b.Type != BPDUTypeRST) || len(p) < bpduConfigLen {
+		return b, false
+	}
+	b.Flags = p[4]
+	b.RootID = BridgeID(binary.BigEndian.Uint64(p[5:13]))
+	b.RootCost = binary.BigEndian.Uint32(p[13:17])
+	b.BridgeID = BridgeID(binary.BigEndian.Uint64(p[17:25]))
+	b.PortID = binary.BigEndian.Uint16(p[25:27])
+	b.Hello = binary.BigEndian.Uint16(p[31:33])
+
+	if b.PVST {
+		// TLV PVID (tipo 0, longitud 2) tras la BPDU; RST añade Version 1 Length
+		end := bpduConfigLen
+		if b.Type == BPDUTypeRST {
+			end++
+		}
+		if len(p) >= end+6 && binary.BigEndian.Uint16(p[end:]) == 0 && binary.BigEndian.Uint16(p[end+2:]) == 2 {
+			b.VLAN = binary.BigEndian.Uint16(p[end+4:]) & 0x0FFF
+		}
+	}
+	return b, true
+}
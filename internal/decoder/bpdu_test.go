@@ -0,0 +1,66 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// bpdu construye una Configuration/RST BPDU (35/36 bytes) con root y bridge dados.
+func bpdu(typ, flags byte, root, bridge uint64) []byte {
+	b := make([]byte, 35)
+	b[2], b[3], b[4] = 2, typ, flags
+	binary.BigEndian.PutUint64(b[5:], root)
+	binary.BigEndian.PutUint32(b[13:], 4)
+	binary.BigEndian.PutUint64(b[17:], bridge)
+	binary.BigEndian.PutUint16(b[31:], 2*256) // Hello 2s
+	if typ == BPDUTypeRST {
+		b = append(b, 0) // Version 1 Length
+	}
+	return b
+}
+
+func TestFrame_BPDU(t *testing.T) {
+	root := uint64(0x1000_0011_2233_4455)
+	bridge := uint64(0x8000_0066_7788_99AA)
+
+	// IEEE RSTP sin etiquetar: 802.3 + LLC 42-42-03
+	ieee := []byte{0x01, 0x80, 0xC2, 0, 0, 0, 0x02, 0, 0, 0, 0, 1, 0, 39, 0x42, 0x42, 0x03}
+	ieee = append(ieee, bpdu(BPDUTypeRST, BPDUFlagTC, root, bridge)...)
+
+	// PVST+ en la VLAN 42 (etiquetada) con TLV PVID
+	pvst := []byte{0x01, 0x00, 0x0C, 0xCC, 0xCC, 0xCD, 0x02, 0, 0, 0, 0, 1, 0x81, 0x00, 0, 42, 0, 50}
+	pvst = append(pvst, 0xAA, 0xAA, 0x03, 0x00, 0x00, 0x0C, 0x01, 0x0B)
+	pvst = append(append(pvst, bpdu(BPDUTypeConfig, 0, root, bridge)...), 0, 0, 0, 2, 0, 42)
+
+	tcn := []byte{0x01, 0x80, 0xC2, 0, 0, 0, 0x02, 0, 0, 0, 0, 1, 0, 7, 0x42, 0x42, 0x03, 0, 0, 0, BPDUTypeTCN}
+
+	var f Frame
+	f.Reset(ieee, 0)
+	b, ok := f.BPDU()
+	if !ok || b.PVST || b.Version != 2 || !b.TCN() || uint64(b.RootID) != root || uint64(b.BridgeID) != bridge || b.RootCost != 4 || b.Hello != 512 {
+		t.Errorf("RSTP: %v %+v", ok, b)
+	}
+	if got := b.RootID.String(); got != "4096.00:11:22:33:44:55" {
+		t.Errorf("BridgeID.String: %q", got)
+	}
+
+	f.Reset(pvst, 0)
+	if b, ok := f.BPDU(); !ok || !b.PVST || b.VLAN != 42 || b.TCN() || uint64(b.RootID) != root {
+		t.Errorf("PVST+: %v %+v", ok, b)
+	}
+
+	f.Reset(tcn, 0)
+	if b, ok := f.BPDU(); !ok || b.Type != BPDUTypeTCN || !b.TCN() {
+		t.Errorf("TCN: %v %+v", ok, b)
+	}
+
+	// Ni una trama Ethernet II ni una BPDU truncada son BPDUs
+	f.Reset(frame(), 0)
+	if _, ok := f.BPDU(); ok {
+		t.Error("Trama IPv4 aceptada como BPDU")
+	}
+	f.Reset(ieee[:30], 0)
+	if _, ok := f.BPDU(); ok {
+		t.Error("BPDU truncada aceptada")
+	}
+}
@@ -0,0 +1,89 @@
+package decoder
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// TPIDs de etiquetas VLAN reconocidas.
+const (
+	EtherTypeVLAN       = 0x8100 // 802.1Q C-tag
+	EtherTypeQinQ       = 0x88A8 // 802.1ad S-tag
+	EtherTypeQinQLegacy = 0x9100 // S-tag pre-estándar (Cisco/Juniper antiguos)
+
+	// MaxTags limita el recorrido de la pila de etiquetas (protección contra tramas malformadas)
+	MaxTags = 4
+)
+
+// IsVLANTPID indica si el EtherType es una etiqueta VLAN apilable.
+func IsVLANTPID(etherType uint16) bool {
+	return etherType == EtherTypeVLAN || etherType == EtherTypeQinQ || etherType == EtherTypeQinQLegacy
+}
+
+// Ethernet es la vista decodificada de la cabecera L2 de una trama.
+// Dst = frame[0:6], Src = frame[6:12]; no se copian para no reservar memoria.
+type Ethernet struct {
+	OuterVLAN uint16 // S-tag (o único tag 802.1Q)
+	InnerVLAN uint16 // C-tag en tramas QinQ, 0 si no existe
+	Tags      uint8  // Etiquetas encontradas (incluida la retirada por el driver)
+	EtherType uint16 // EtherType real tras la pila de etiquetas
+	L3Offset  int    // Inicio de la carga L3 dentro de la trama
+}
+
+// Decode recorre la pila de etiquetas VLAN (0x8100, 0x88A8, 0x9100, en cualquier combinación).
+//
+// auxVLAN es el tag que el kernel retiró de la trama por VLAN offload (0 si no hubo):
+// en ese caso es el tag exterior y cualquier etiqueta que siga en línea es la interior.
+// Devuelve false si la trama está truncada.
+func Decode(frame []byte, auxVLAN uint16) (Ethernet, bool) {
+	var eth Ethernet
+	if len(frame) < 14 {
+		return eth, false
+	}
+
+	if auxVLAN != 0 {
+		eth.OuterVLAN = auxVLAN & 0x0FFF
+		eth.Tags = 1
+	}
+
+	off := 12
+	for {
+		etherType := binary.BigEndian.Uint16(frame[off : off+2])
+		if !IsVLANTPID(etherType) || eth.Tags >= MaxTags {
+			eth.EtherType = etherType
+			eth.L3Offset = off + 2
+			return eth, true
+		}
+
+		// TPID(2) + TCI(2) + siguiente EtherType(2)
+		if len(frame) < off+6 {
+			return eth, false
+		}
+		vid := binary.BigEndian.Uint16(frame[off+2:off+4]) & 0x0FFF
+		switch eth.Tags {
+		case 0:
+			eth.OuterVLAN = vid
+		case 1:
+			eth.InnerVLAN = vid
+		}
+		eth.Tags++
+		off += 4
+	}
+}
+
+// VLANString representa las etiquetas para las alertas: "Native", "42" o "100/42 (QinQ)".
+func (e Ethernet) VLANString() string {
+	return FormatVLAN(e.OuterVLAN, e.InnerVLAN)
+}
+
+// FormatVLAN es VLANString para cuando sólo se conservan los IDs (alertas en goroutines).
+func FormatVLAN(outer, inner uint16) string {
+	switch {
+	case outer == 0 && inner == 0:
+		return "Native"
+	case inner == 0:
+		return fmt.Sprintf("%d", outer)
+	default:
+		return fmt.Sprintf("%d/%d (QinQ S/C)", outer, inner)
+	}
+}
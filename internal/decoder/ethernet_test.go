@@ -0,0 +1,89 @@
+package decoder
+
+import "testing"
+
+func frame(tags ...uint16) []byte {
+	// dst(6) + src(6) + pares TPID/TCI + EtherType IPv4 + carga mínima
+	f := make([]byte, 12)
+	for i := 0; i+1 < len(tags); i += 2 {
+		f = append(f, byte(tags[i]>>8), byte(tags[i]), byte(tags[i+1]>>8), byte(tags[i+1]))
+	}
+	f = append(f, 0x08, 0x00)
+	return append(f, make([]byte, 20)...)
+}
+
+func TestDecode(t *testing.T) {
+	cases := []struct {
+		name         string
+		frame        []byte
+		aux          uint16
+		outer, inner uint16
+		tags         uint8
+		l3           int
+		vlan         string
+	}{
+		{"untagged", frame(), 0, 0, 0, 0, 14, "Native"},
+		{"dot1q", frame(0x8100, 42), 0, 42, 0, 1, 18, "42"},
+		{"qinq", frame(0x88A8, 100, 0x8100, 42), 0, 100, 42, 2, 22, "100/42 (QinQ S/C)"},
+		{"legacy-qinq", frame(0x9100, 100, 0x8100, 42), 0, 100, 42, 2, 22, "100/42 (QinQ S/C)"},
+		{"pcp-bits", frame(0x8100, 0xE000|42), 0, 42, 0, 1, 18, "42"},
+		// El driver retiró el S-tag: el C-tag en línea pasa a ser el interior
+		{"aux+inline", frame(0x8100, 42), 100, 100, 42, 2, 18, "100/42 (QinQ S/C)"},
+		{"aux-only", frame(), 7, 7, 0, 1, 14, "7"},
+	}
+
+	for _, c := range cases {
+		eth, ok := Decode(c.frame, c.aux)
+		if !ok {
+			t.Errorf("%s: Decode devolvió false", c.name)
+			continue
+		}
+		if eth.OuterVLAN != c.outer || eth.InnerVLAN != c.inner || eth.Tags != c.tags || eth.L3Offset != c.l3 {
+			t.Errorf("%s: obtuve %+v", c.name, eth)
+		}
+		if eth.EtherType != 0x0800 {
+			t.Errorf("%s: EtherType 0x%04x, esperaba IPv4", c.name, eth.EtherType)
+		}
+		if got := eth.VLANString(); got != c.vlan {
+			t.Errorf("%s: VLANString %q, esperaba %q", c.name, got, c.vlan)
+		}
+	}
+}
+
+func TestDecode_Truncated(t *testing.T) {
+	if _, ok := Decode(make([]byte, 10), 0); ok {
+		t.Error("Trama de 10 bytes aceptada")
+	}
+	// TPID sin TCI completo
+	f := make([]byte, 16)
+	f[12], f[13] = 0x81, 0x00
+	if _, ok := Decode(f, 0); ok {
+		t.Error("Tag 802.1Q truncado aceptado")
+	}
+}
+
+func TestFrame_LazyLayers(t *testing.T) {
+	// DHCP Offer (UDP 67 -> 68) dentro de QinQ
+	f := frame(0x88A8, 100, 0x8100, 42)
+	ip := f[22:]
+	ip[0] = 0x45
+	ip[9] = ProtoUDP
+	copy(ip[12:16], []byte{10, 0, 0, 1})
+	f = append(f, 0, 67, 0, 68, 0, 8, 0, 0)
+
+	var fr Frame
+	fr.Reset(f, 0)
+	udp, ok := fr.UDP()
+	if !ok || udp.SrcPort != 67 || udp.DstPort != 68 || udp.PayloadOffset != len(f) {
+		t.Fatalf("UDP mal decodificado: %+v (%v)", udp, ok)
+	}
+	if l3, _ := fr.IP(); l3.Version != 4 || l3.Src[0] != 10 || l3.L4Offset != 42 {
+		t.Errorf("IPv4 mal decodificado: %+v", l3)
+	}
+
+	// Reset invalida la caché de capas
+	fr.Reset(frame(), 0)
+	if _, ok := fr.UDP(); ok {
+		t.Error("UDP cacheado de la trama anterior")
+	}
+}
@@ -0,0 +1,135 @@
+package decoder
+
+import "encoding/binary"
+
+// EtherTypes y protocolos que el Frame sabe abrir.
+const (
+	EtherTypeIPv4 = 0x0800
+	EtherTypeIPv6 = 0x86DD
+
+	ProtoICMPv6 = 58
+	ProtoUDP    = 17
+)
+
+// IP es la vista de la cabecera IPv4/IPv6. Src apunta dentro de la trama (sin copia).
+type IP struct {
+	Version  uint8
+	Protocol uint8 // IPv4 Protocol / IPv6 Next Header (no se recorren cabeceras de extensión)
+	Src      []byte
+	L4Offset int
+}
+
+// UDP es la vista de la cabecera UDP.
+type UDP struct {
+	SrcPort       uint16
+	DstPort       uint16
+	PayloadOffset int
+}
+
+// Frame es la trama que el Engine entrega a los algoritmos: la cabecera L2 se decodifica
+// una vez por paquete en Reset(); L3/L4 sólo cuando algún algoritmo lo pide, y se cachean
+// para los siguientes.
+//
+// OPTIMIZACIÓN: El Engine reutiliza el mismo Frame para todos los paquetes (zero-alloc).
+// Un algoritmo no debe retenerlo (ni sus slices) fuera de OnFrame: copiar lo necesario
+// antes de lanzar la goroutine de alerta.
+type Frame struct {
+	Data  []byte // Trama capturada (ya recortada a su longitud)
+	Eth   Ethernet
+	Valid bool // Cabecera Ethernet (y pila de etiquetas) completa
+
+	ipDone, ipOK   bool
+	ip             IP
+	udpDone, udpOK bool
+	udp            UDP
+}
+
+// Reset carga una nueva trama. auxVLAN es el tag retirado por el kernel (ver Decode).
+func (f *Frame) Reset(data []byte, auxVLAN uint16) {
+	f.Data = data
+	f.Eth, f.Valid = Decode(data, auxVLAN)
+	f.ipDone, f.ipOK = false, false
+	f.udpDone, f.udpOK = false, false
+}
+
+// Dst devuelve la MAC destino. Requiere Valid.
+func (f *Frame) Dst() []byte { return f.Data[0:6] }
+
+// Src devuelve la MAC origen. Requiere Valid.
+func (f *Frame) Src() []byte { return f.Data[6:12] }
+
+// Payload devuelve todo lo que sigue a la pila de etiquetas (carga L3).
+func (f *Frame) Payload() []byte {
+	if !f.Valid {
+		return nil
+	}
+	return f.Data[f.Eth.L3Offset:]
+}
+
+// VLANString es Ethernet.VLANString para la trama actual.
+func (f *Frame) VLANString() string { return f.Eth.VLANString() }
+
+// IP decodifica (una vez) la cabecera IPv4 o IPv6.
+func (f *Frame) IP() (IP, bool) {
+	if f.ipDone {
+		return f.ip, f.ipOK
+	}
+	f.ipDone = true
+	if !f.Valid {
+		return f.ip, false
+	}
+
+	off := f.Eth.L3Offset
+	switch f.Eth.EtherType {
+	case EtherTypeIPv4:
+		if len(f.Data) < off+20 {
+			return f.ip, false
+		}
+		ihl := int(f.Data[off]&0x0F) * 4
+		if ihl < 20 || len(f.Data) < off+ihl {
+			return f.ip, false
+		}
+		f.ip = IP{Version: 4, Protocol: f.Data[off+9], Src: f.Data[off+12 : off+16], L4Offset: off + ihl}
+	case EtherTypeIPv6:
+		// Cabecera fija de 40 bytes
+		if len(f.Data) < off+40 {
+			return f.ip, false
+		}
+		f.ip = IP{Version: 6, Protocol: f.Data[off+6], Src: f.Data[off+8 : off+24], L4Offset: off + 40}
+	default:
+		return f.ip, false
+	}
+
+	f.ipOK = true
+	return f.ip, true
+}
+
+// UDP decodifica (una vez) la cabecera UDP sobre IPv4 o IPv6.
+func (f *Frame) UDP() (UDP, bool) {
+	if f.udpDone {
+		return f.udp, f.udpOK
+	}
+	f.udpDone = true
+
+	ip, ok := f.IP()
+	if !ok || ip.Protocol != ProtoUDP || len(f.Data) < ip.L4Offset+8 {
+		return f.udp, false
+	}
+	off := ip.L4Offset
+	f.udp = UDP{
+		SrcPort:       binary.BigEndian.Uint16(f.Data[off : off+2]),
+		DstPort:       binary.BigEndian.Uint16(f.Data[off+2 : off+4]),
+		PayloadOffset: off + 8,
+	}
+	f.udpOK = true
+	return f.udp, true
+}
+
+// ICMPv6Type devuelve el tipo del mensaje ICMPv6 que sigue a la cabecera IPv6 fija.
+func (f *Frame) ICMPv6Type() (uint8, bool) {
+	ip, ok := f.IP()
+	if !ok || ip.Version != 6 || ip.Protocol != ProtoICMPv6 || len(f.Data) < ip.L4Offset+1 {
+		return 0, false
+	}
+	return f.Data[ip.L4Offset], true
+}
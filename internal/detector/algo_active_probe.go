@@ -1,6 +1,7 @@
 package detector
 
 import (
+	"context"
 	"bytes"
 	"encoding/binary"
 	"fmt"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/mdlayher/packet"
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
+	"github.com/soyunomas/loopwarden/internal/forensics"
 	"github.com/soyunomas/loopwarden/internal/notifier"
 	"github.com/soyunomas/loopwarden/internal/telemetry"
 	"github.com/soyunomas/loopwarden/internal/utils"
@@ -19,6 +22,8 @@ import (
 const ProbeAlertCooldown = 10 * time.Second
 
 type ActiveProbe struct {
+	lifecycle // Goroutines de fondo: Stop() las cancela y espera
+
 	cfg        *config.ActiveProbeConfig
 	notify     *notifier.Notifier
 	myMAC      net.HardwareAddr
@@ -34,6 +39,9 @@ type ActiveProbe struct {
 
 	mu        sync.Mutex
 	lastAlert time.Time
+	incidents *incidents // Ciclo de vida: repeticiones y evento "resolved"
+
+	recorder *forensics.Recorder // Evidencia pcap (opcional)
 }
 
 func NewActiveProbe(cfg *config.ActiveProbeConfig, n *notifier.Notifier, ifaceName string) *ActiveProbe {
@@ -41,6 +49,7 @@ func NewActiveProbe(cfg *config.ActiveProbeConfig, n *notifier.Notifier, ifaceNa
 		cfg:       cfg,
 		notify:    n,
 		ifaceName: ifaceName,
+		incidents: newIncidents(n),
 	}
 }
 
@@ -48,9 +57,69 @@ func (ap *ActiveProbe) Name() string {
 	return "ActiveProbe"
 }
 
-func (ap *ActiveProbe) Start(conn *packet.Conn, iface *net.Interface) error {
+func (ap *ActiveProbe) incidentTracker() *incidents { return ap.incidents }
+
+func (ap *ActiveProbe) setRecorder(r *forensics.Recorder) { ap.recorder = r }
+
+func (ap *ActiveProbe) Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error {
+	ap.begin(ctx)
 	ap.myMAC = iface.HardwareAddr
-	
+	ap.destAddr = &packet.Addr{
+		HardwareAddr: net.HardwareAddr{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+	}
+
+	ap.configure(iface.Name)
+
+	log.Printf("✅ [ActiveProbe:%s] Active. EtherType: 0x%X", ap.ifaceName, ap.ethertype)
+	ap.every(1*time.Second, ap.incidents.sweep)
+
+	// Sin socket (modo replay) no hay nada que inyectar: sólo analizamos lo capturado
+	if conn == nil {
+		log.Printf("ℹ️ [ActiveProbe:%s] Passive mode (no socket), probe injection disabled", ap.ifaceName)
+		return nil
+	}
+
+	// 2. Usar Intervalo Efectivo en el Ticker (releído en cada tick por si hay recarga)
+	ap.spawn(func(ctx context.Context) {
+		ap.mu.Lock()
+		interval := ap.intervalMs
+		ap.mu.Unlock()
+
+		ticker := time.NewTicker(time.Duration(interval) * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			ap.mu.Lock()
+			frame := ap.probeFrame
+			if ap.intervalMs != interval {
+				interval = ap.intervalMs
+				ticker.Reset(time.Duration(interval) * time.Millisecond)
+			}
+			ap.mu.Unlock()
+
+			_, _ = conn.WriteTo(frame, ap.destAddr)
+		}
+	})
+
+	return nil
+}
+
+// reconfigure aplica una recarga de configuración (SIGHUP) sin perder estado.
+func (ap *ActiveProbe) reconfigure(cfg *config.AlgorithmConfig) {
+	ap.mu.Lock()
+	ap.cfg = &cfg.ActiveProbe
+	ap.configure(ap.ifaceName)
+	ap.mu.Unlock()
+}
+
+// configure calcula la configuración efectiva (global + override) y regenera la sonda.
+func (ap *ActiveProbe) configure(ifaceName string) {
 	// 1. Calcular Configuración Efectiva
 	ap.intervalMs = ap.cfg.IntervalMs
 	ap.ethertype = ap.cfg.Ethertype
@@ -61,7 +130,7 @@ func (ap *ActiveProbe) Start(conn *packet.Conn, iface *net.Interface) error {
 		ap.domain = "default"
 	}
 
-	if override, ok := ap.cfg.Overrides[iface.Name]; ok {
+	if override, ok := ap.cfg.Overrides[ifaceName]; ok {
 		if override.IntervalMs > 0 {
 			ap.intervalMs = override.IntervalMs
 		}
@@ -70,12 +139,9 @@ func (ap *ActiveProbe) Start(conn *packet.Conn, iface *net.Interface) error {
 		}
 	}
 	
-	log.Printf("🔧 [ActiveProbe] Config for %s: Interval=%dms, Domain='%s'", iface.Name, ap.intervalMs, ap.domain)
-
-	broadcastHW := net.HardwareAddr{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
-	ap.destAddr = &packet.Addr{
-		HardwareAddr: broadcastHW,
-	}
+	if ap.intervalMs <= 0 { ap.intervalMs = 1000 }
+	
+	log.Printf("🔧 [ActiveProbe] Config for %s: Interval=%dms, Domain='%s'", ifaceName, ap.intervalMs, ap.domain)
 
 	typeBytes := make([]byte, 2)
 	binary.BigEndian.PutUint16(typeBytes, ap.ethertype)
@@ -87,46 +153,19 @@ func (ap *ActiveProbe) Start(conn *packet.Conn, iface *net.Interface) error {
 	payloadBytes := []byte(fullPayload)
 
 	frame := make([]byte, 0, 14+len(payloadBytes))
-	frame = append(frame, broadcastHW...)
+	frame = append(frame, ap.destAddr.HardwareAddr...)
 	frame = append(frame, ap.myMAC...)
 	frame = append(frame, typeBytes...)
 	frame = append(frame, payloadBytes...)
 
 	ap.probeFrame = frame
-
-	log.Printf("✅ [ActiveProbe:%s] Active. EtherType: 0x%X", ap.ifaceName, ap.ethertype)
-
-	// 2. Usar Intervalo Efectivo en el Ticker
-	go func() {
-		ticker := time.NewTicker(time.Duration(ap.intervalMs) * time.Millisecond)
-		defer ticker.Stop()
-
-		for range ticker.C {
-			_, _ = conn.WriteTo(ap.probeFrame, ap.destAddr)
-		}
-	}()
-
-	return nil
 }
 
-func (ap *ActiveProbe) OnPacket(data []byte, length int, vlanID uint16) {
-	headerSize := 14
-	etherTypeOffset := 12
-	if vlanID != 0 {
-		headerSize = 18
-		etherTypeOffset = 16
-	}
-
-	if length < headerSize {
-		return
-	}
-
+func (ap *ActiveProbe) OnFrame(f *decoder.Frame) {
 	// -------------------------------------------------------------------------
 	// OPTIMIZACIÓN: Chequeo rápido de EtherType primero.
 	// -------------------------------------------------------------------------
-	etherType := binary.BigEndian.Uint16(data[etherTypeOffset : etherTypeOffset+2])
-	
-	if etherType != ap.ethertype {
+	if !f.Valid || f.Eth.EtherType != ap.ethertype {
 		return
 	}
 
@@ -134,7 +173,7 @@ func (ap *ActiveProbe) OnPacket(data []byte, length int, vlanID uint16) {
 	// LÓGICA V2: Análisis de Dominio y MAC
 	// -------------------------------------------------------------------------
 
-	payload := data[headerSize:length]
+	payload := f.Payload()
 	
 	// Magic check rápido
 	magicPrefix := []byte(ap.cfg.MagicPayload + "|")
@@ -145,11 +184,7 @@ func (ap *ActiveProbe) OnPacket(data []byte, length int, vlanID uint16) {
 	ap.mu.Lock()
 	defer ap.mu.Unlock()
 	
-	now := time.Now()
-	// Si hemos alertado recientemente, salimos (Throttling)
-	if now.Sub(ap.lastAlert) <= ProbeAlertCooldown {
-		return
-	}
+	now := clock.Now()
 
 	// Parsear el payload completo
 	// Formato esperado: MAGIC|IFACE|DOMAIN
@@ -168,7 +203,7 @@ func (ap *ActiveProbe) OnPacket(data []byte, length int, vlanID uint16) {
 	}
 
 	// MAC de origen del paquete
-	srcMac := data[6:12]
+	srcMac := f.Src()
 	
 	// --- MATRIZ DE DECISIÓN ---
 	
@@ -176,17 +211,16 @@ func (ap *ActiveProbe) OnPacket(data []byte, length int, vlanID uint16) {
 	isSameDomain := (remoteDomain == ap.domain)
 	
 	var alertType string
-	var alertMsg string
+	var ev notifier.Event
 	shouldAlert := false
 
 	if isSelfMac {
 		// CASO 1: AUTO-BUCLE (Hard Loop)
 		shouldAlert = true
 		alertType = "HardLoop"
-		alertMsg = fmt.Sprintf("[%s] 🚨 LOOP CONFIRMED! (Self-Loop)\n"+
-			"    INTERFACE: %s\n"+
-			"    STATUS:    Cable connects interface back to itself.\n"+
-			"    ACTION:    IMMEDIATE DISCONNECT.", ap.ifaceName, ap.ifaceName)
+		ev = notifier.Event{Title: "🚨 LOOP CONFIRMED! (Self-Loop)"}.
+			With("STATUS", "Cable connects interface back to itself.").
+			With("ACTION", "IMMEDIATE DISCONNECT.")
 
 	} else {
 		// Viene de OTRA MAC
@@ -198,27 +232,43 @@ func (ap *ActiveProbe) OnPacket(data []byte, length int, vlanID uint16) {
 			shouldAlert = true
 			alertType = "CrossDomainLoop"
 			
-			// --- CORRECCIÓN AQUÍ: Añadido ap.ifaceName dos veces ---
-			alertMsg = fmt.Sprintf("[%s] ☣️ CRITICAL TOPOLOGY ERROR (Cross-Domain)!\n"+
-				"    INTERFACE: %s (Domain: %s)\n"+
-				"    REMOTE:    %s (Domain: %s)\n"+
-				"    DETECTED:  Physical bridge between two different networks.\n"+
-				"    ACTION:    Check cabling between these two segments immediately.", 
-				ap.ifaceName, ap.ifaceName, ap.domain, remoteIface, remoteDomain)
+			ev = notifier.Event{Title: "☣️ CRITICAL TOPOLOGY ERROR (Cross-Domain)!"}.
+				With("DOMAIN", ap.domain).
+				With("REMOTE", fmt.Sprintf("%s (Domain: %s)", remoteIface, remoteDomain)).
+				With("DETECTED", "Physical bridge between two different networks.").
+				With("ACTION", "Check cabling between these two segments immediately.")
 		}
 	}
 
-	if shouldAlert {
-		telemetry.EngineHits.WithLabelValues(ap.ifaceName, "ActiveProbe", alertType).Inc()
-		
-		dstMac := data[0:6] 
-		retInfo := utils.ClassifyMAC(dstMac)
-		
-		fullMsg := fmt.Sprintf("%s\n    SOURCE MAC: %s\n    DEST TYPE:  %s", 
-			alertMsg, net.HardwareAddr(srcMac).String(), retInfo.Description)
-		
-		go ap.notify.Alert(fullMsg)
-
-		ap.lastAlert = now
+	if !shouldAlert {
+		return
 	}
+
+	// El bucle sigue presente aunque el cooldown silencie la alerta
+	ap.incidents.seen(alertType, "", 0)
+	if now.Sub(ap.lastAlert) <= ProbeAlertCooldown {
+		return
+	}
+
+	telemetry.EngineHits.WithLabelValues(ap.ifaceName, "ActiveProbe", alertType).Inc()
+	
+	dstMac := f.Dst()
+	retInfo := utils.ClassifyMAC(dstMac)
+
+	ev.Interface = ap.ifaceName
+	ev.Algorithm = "ActiveProbe"
+	ev.ThreatType = alertType
+	ev.Severity = notifier.SeverityCritical
+	ev = frameVLAN(f).apply(ev)
+	ev.SrcMAC = net.HardwareAddr(srcMac).String()
+	ev = ev.With("DEST TYPE", retInfo.Description)
+
+	alerts.Add(1)
+	go func(ev notifier.Event, reason string) {
+		defer alerts.Done()
+		ev.Evidence = evidencePath(ap.recorder, reason)
+		ap.notify.Notify(ap.incidents.fire("", ev))
+	}(ev, "ActiveProbe-"+alertType)
+
+	ap.lastAlert = now
 }
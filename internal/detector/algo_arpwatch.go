@@ -1,6 +1,7 @@
 package detector
 
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"log"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/mdlayher/packet"
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
 	"github.com/soyunomas/loopwarden/internal/notifier"
 	"github.com/soyunomas/loopwarden/internal/telemetry"
 )
@@ -29,6 +31,8 @@ type arpStats struct {
 }
 
 type ArpWatchdog struct {
+	lifecycle // Goroutines de fondo: Stop() las cancela y espera
+
 	cfg       *config.ArpWatchConfig
 	notify    *notifier.Notifier
 	ifaceName string // Identidad de la interfaz
@@ -42,6 +46,7 @@ type ArpWatchdog struct {
 
 	sources       map[[6]byte]*arpStats
 	alertRegistry map[[6]byte]time.Time
+	incidents     *incidents // Ciclo de vida: repeticiones y evento "resolved"
 }
 
 func NewArpWatchdog(cfg *config.ArpWatchConfig, n *notifier.Notifier, ifaceName string) *ArpWatchdog {
@@ -51,12 +56,33 @@ func NewArpWatchdog(cfg *config.ArpWatchConfig, n *notifier.Notifier, ifaceName
 		ifaceName:     ifaceName,
 		sources:       make(map[[6]byte]*arpStats, 100),
 		alertRegistry: make(map[[6]byte]time.Time),
+		incidents:     newIncidents(n),
 	}
 }
 
 func (aw *ArpWatchdog) Name() string { return "ArpWatchdog" }
 
-func (aw *ArpWatchdog) Start(conn *packet.Conn, iface *net.Interface) error {
+func (aw *ArpWatchdog) incidentTracker() *incidents { return aw.incidents }
+
+func (aw *ArpWatchdog) Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error {
+	aw.begin(ctx)
+	aw.configure(iface.Name)
+
+	aw.every(1*time.Second, aw.analyzeAndReset)
+	aw.every(1*time.Second, aw.incidents.sweep)
+	return nil
+}
+
+// reconfigure aplica una recarga de configuración (SIGHUP) sin perder estado.
+func (aw *ArpWatchdog) reconfigure(cfg *config.AlgorithmConfig) {
+	aw.mu.Lock()
+	aw.cfg = &cfg.ArpWatch
+	aw.configure(aw.ifaceName)
+	aw.mu.Unlock()
+}
+
+// configure calcula la configuración efectiva (global + override).
+func (aw *ArpWatchdog) configure(ifaceName string) {
 	// 1. Cargar Defaults Globales
 	aw.limitPPS = aw.cfg.MaxPPS
 	aw.scanThreshold = aw.cfg.ScanIPThreshold
@@ -65,25 +91,25 @@ func (aw *ArpWatchdog) Start(conn *packet.Conn, iface *net.Interface) error {
 	// Parseo de Cooldown Global
 	dur, err := time.ParseDuration(aw.cfg.AlertCooldown)
 	if err != nil {
-		log.Printf("⚠️ [ArpWatch:%s] Invalid AlertCooldown '%s', defaulting to 30s", iface.Name, aw.cfg.AlertCooldown)
+		log.Printf("⚠️ [ArpWatch:%s] Invalid AlertCooldown '%s', defaulting to 30s", ifaceName, aw.cfg.AlertCooldown)
 		aw.cooldown = 30 * time.Second
 	} else {
 		aw.cooldown = dur
 	}
 
 	// 2. Aplicar Overrides
-	if override, ok := aw.cfg.Overrides[iface.Name]; ok {
+	if override, ok := aw.cfg.Overrides[ifaceName]; ok {
 		if override.MaxPPS > 0 {
 			aw.limitPPS = override.MaxPPS
-			log.Printf("🔧 [ArpWatch:%s] Override MaxPPS = %d", iface.Name, aw.limitPPS)
+			log.Printf("🔧 [ArpWatch:%s] Override MaxPPS = %d", ifaceName, aw.limitPPS)
 		}
 		if override.ScanIPThreshold > 0 {
 			aw.scanThreshold = override.ScanIPThreshold
-			log.Printf("🔧 [ArpWatch:%s] Override ScanIPThreshold = %d", iface.Name, aw.scanThreshold)
+			log.Printf("🔧 [ArpWatch:%s] Override ScanIPThreshold = %d", ifaceName, aw.scanThreshold)
 		}
 		if override.ScanModePPS > 0 {
 			aw.scanLimitPPS = override.ScanModePPS
-			log.Printf("🔧 [ArpWatch:%s] Override ScanModePPS = %d", iface.Name, aw.scanLimitPPS)
+			log.Printf("🔧 [ArpWatch:%s] Override ScanModePPS = %d", ifaceName, aw.scanLimitPPS)
 		}
 		// Nota: ArpWatchOverride no tiene AlertCooldown en config.go fase 1, se mantiene el global.
 	}
@@ -95,16 +121,7 @@ func (aw *ArpWatchdog) Start(conn *packet.Conn, iface *net.Interface) error {
 	if aw.cooldown == 0 { aw.cooldown = 30 * time.Second }
 
 	log.Printf("✅ [ArpWatch:%s] Active. Limit: %d pps (Scan Mode: >%d targets -> %d pps)", 
-		iface.Name, aw.limitPPS, aw.scanThreshold, aw.scanLimitPPS)
-
-	go func() {
-		ticker := time.NewTicker(1 * time.Second)
-		defer ticker.Stop()
-		for range ticker.C {
-			aw.analyzeAndReset()
-		}
-	}()
-	return nil
+		ifaceName, aw.limitPPS, aw.scanThreshold, aw.scanLimitPPS)
 }
 
 func ipToUint32(ip []byte) uint32 {
@@ -120,20 +137,12 @@ func uint32ToIP(nn uint32) net.IP {
 	return ip
 }
 
-func (aw *ArpWatchdog) OnPacket(data []byte, length int, vlanID uint16) {
-	ethOffset := 14
-	ethTypeOffset := 12
-	if vlanID != 0 {
-		ethOffset = 18
-		ethTypeOffset = 16
-	}
-
-	if length < ethOffset+8 { return }
-
-	if binary.BigEndian.Uint16(data[ethTypeOffset:ethTypeOffset+2]) != EtherTypeARP { return }
+func (aw *ArpWatchdog) OnFrame(f *decoder.Frame) {
+	if !f.Valid || f.Eth.EtherType != EtherTypeARP { return }
 
-	arpBase := ethOffset
-	if length < arpBase+28 { return }
+	data := f.Data
+	arpBase := f.Eth.L3Offset
+	if len(data) < arpBase+28 { return }
 
 	opCode := binary.BigEndian.Uint16(data[arpBase+6 : arpBase+8])
 	if opCode != OpCodeRequest { return }
@@ -197,53 +206,59 @@ func (aw *ArpWatchdog) analyzeAndReset() {
 		}
 
 		if stats.pps > threshold {
+			var pattern, details, metricType string
+
+			if isScanning {
+				pattern = "SUBNET SCANNING (SWEEP)"
+				metricType = "NetworkScan"
+				ipStart := uint32ToIP(stats.minIP)
+				ipEnd := uint32ToIP(stats.maxIP)
+				details = fmt.Sprintf("Scanning Range: %s -> %s (%d IPs)", ipStart, ipEnd, uniqueTargets)
+			} else if uniqueTargets == 1 {
+				pattern = "SINGLE TARGET ATTACK / LOOP"
+				metricType = "SingleTargetLoop"
+				ipTarget := uint32ToIP(stats.minIP)
+				details = fmt.Sprintf("Hammering Target: %s", ipTarget)
+			} else {
+				pattern = "HIGH VOLUME ARP ANOMALY"
+				metricType = "ArpNoise"
+				details = fmt.Sprintf("Multiple Targets (%d IPs)", uniqueTargets)
+			}
+
+			capturedMAC := net.HardwareAddr(macArray[:]).String()
+			aw.incidents.seen(metricType, capturedMAC, stats.pps)
+
 			lastAlert, alerted := aw.alertRegistry[macArray]
 			
 			// USAR VARIABLE DE INSTANCIA (Cooldown)
-			if !alerted || time.Since(lastAlert) > aw.cooldown {
-				var pattern, details, metricType string
-
-				if isScanning {
-					pattern = "SUBNET SCANNING (SWEEP)"
-					metricType = "NetworkScan"
-					ipStart := uint32ToIP(stats.minIP)
-					ipEnd := uint32ToIP(stats.maxIP)
-					details = fmt.Sprintf("Scanning Range: %s -> %s (%d IPs)", ipStart, ipEnd, uniqueTargets)
-				} else if uniqueTargets == 1 {
-					pattern = "SINGLE TARGET ATTACK / LOOP"
-					metricType = "SingleTargetLoop"
-					ipTarget := uint32ToIP(stats.minIP)
-					details = fmt.Sprintf("Hammering Target: %s", ipTarget)
-				} else {
-					pattern = "HIGH VOLUME ARP ANOMALY"
-					metricType = "ArpNoise"
-					details = fmt.Sprintf("Multiple Targets (%d IPs)", uniqueTargets)
-				}
-
+			if !alerted || clock.Now().Sub(lastAlert) > aw.cooldown {
 				telemetry.EngineHits.WithLabelValues(aw.ifaceName, "ArpWatchdog", metricType).Inc()
 
 				capturedPPS := stats.pps
-				capturedMAC := net.HardwareAddr(macArray[:]).String()
-
-				go func(iface, m, p, d string, rate uint64, lim uint64) {
-					msg := fmt.Sprintf("[ArpWatchdog] 🐶 DISCOVERY STORM DETECTED!\n"+
-						"    INTERFACE:  %s\n"+
-						"    RATE:       %d req/s (Threshold: %d)\n"+
-						"    SOURCE:     %s\n"+
-						"    PATTERN:    %s\n"+
-						"    DETAILS:    %s",
-						iface, rate, lim, m, p, d)
-					aw.notify.Alert(msg)
-				}(currentIface, capturedMAC, pattern, details, capturedPPS, threshold)
-
-				aw.alertRegistry[macArray] = time.Now()
+
+				alerts.Add(1)
+				go func(ev notifier.Event) {
+					defer alerts.Done()
+					aw.notify.Notify(aw.incidents.fire(ev.SrcMAC, ev))
+				}(notifier.Event{
+					Interface:  currentIface,
+					Algorithm:  "ArpWatchdog",
+					ThreatType: metricType,
+					Severity:   notifier.SeverityWarning,
+					Title:      "🐶 DISCOVERY STORM DETECTED!",
+					SrcMAC:     capturedMAC,
+					Rate:       capturedPPS,
+					Threshold:  threshold,
+				}.With("PATTERN", pattern).With("DETAILS", details))
+
+				aw.alertRegistry[macArray] = clock.Now()
 			}
 		}
 
 		if len(aw.alertRegistry) > MaxTrackedArpSources {
 			// Limpieza de memoria simple
 			for k, t := range aw.alertRegistry {
-				if time.Since(t) > aw.cooldown*2 {
+				if clock.Now().Sub(t) > aw.cooldown*2 {
 					delete(aw.alertRegistry, k)
 				}
 			}
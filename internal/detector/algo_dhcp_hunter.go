@@ -1,8 +1,7 @@
 package detector
 
 import (
-	"encoding/binary"
-	"fmt"
+	"context"
 	"log"
 	"net"
 	"strings"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/mdlayher/packet"
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
 	"github.com/soyunomas/loopwarden/internal/notifier"
 	"github.com/soyunomas/loopwarden/internal/telemetry"
 )
@@ -24,6 +24,8 @@ const (
 )
 
 type DhcpHunter struct {
+	lifecycle // Goroutines de fondo: Stop() las cancela y espera
+
 	cfg         *config.DhcpHunterConfig
 	notify      *notifier.Notifier
 	ifaceName   string // Identidad de la interfaz
@@ -33,6 +35,7 @@ type DhcpHunter struct {
 	
 	mu        sync.Mutex
 	lastAlert time.Time
+	incidents *incidents // Ciclo de vida: repeticiones y evento "resolved"
 }
 
 func NewDhcpHunter(cfg *config.DhcpHunterConfig, n *notifier.Notifier, ifaceName string) *DhcpHunter {
@@ -42,20 +45,43 @@ func NewDhcpHunter(cfg *config.DhcpHunterConfig, n *notifier.Notifier, ifaceName
 		ifaceName:   ifaceName,
 		trustedMacs: make(map[string]bool),
 		trustedNets: make([]*net.IPNet, 0),
+		incidents:   newIncidents(n),
 	}
 }
 
 func (d *DhcpHunter) Name() string { return "DhcpHunter" }
 
-func (d *DhcpHunter) Start(conn *packet.Conn, iface *net.Interface) error {
+func (d *DhcpHunter) incidentTracker() *incidents { return d.incidents }
+
+func (d *DhcpHunter) Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error {
+	d.begin(ctx)
+	d.configure(iface.Name)
+	d.every(1*time.Second, d.incidents.sweep)
+	return nil
+}
+
+// reconfigure aplica una recarga de configuración (SIGHUP) sin perder estado.
+func (d *DhcpHunter) reconfigure(cfg *config.AlgorithmConfig) {
+	d.mu.Lock()
+	d.cfg = &cfg.DhcpHunter
+	d.configure(d.ifaceName)
+	d.mu.Unlock()
+}
+
+// configure calcula la configuración efectiva (global + override).
+func (d *DhcpHunter) configure(ifaceName string) {
+	// Las listas se reconstruyen desde cero: una recarga puede retirar MACs/CIDRs de confianza
+	d.trustedMacs = make(map[string]bool)
+	d.trustedNets = make([]*net.IPNet, 0)
+
 	// 1. Construir lista maestra de MACs (Global + Override)
 	var rawMacs []string
 	
 	rawMacs = append(rawMacs, d.cfg.TrustedMacs...)
 	
-	if override, ok := d.cfg.Overrides[iface.Name]; ok {
+	if override, ok := d.cfg.Overrides[ifaceName]; ok {
 		log.Printf("🔧 [DhcpHunter] Applying overrides for interface %s (Extra MACs: %d, Extra CIDRs: %d)", 
-			iface.Name, len(override.TrustedMacs), len(override.TrustedCidrs))
+			ifaceName, len(override.TrustedMacs), len(override.TrustedCidrs))
 		rawMacs = append(rawMacs, override.TrustedMacs...)
 	}
 
@@ -74,7 +100,7 @@ func (d *DhcpHunter) Start(conn *packet.Conn, iface *net.Interface) error {
 	var rawCidrs []string
 	rawCidrs = append(rawCidrs, d.cfg.TrustedCidrs...)
 	
-	if override, ok := d.cfg.Overrides[iface.Name]; ok {
+	if override, ok := d.cfg.Overrides[ifaceName]; ok {
 		rawCidrs = append(rawCidrs, override.TrustedCidrs...)
 	}
 
@@ -90,55 +116,24 @@ func (d *DhcpHunter) Start(conn *packet.Conn, iface *net.Interface) error {
 	}
 	
 	log.Printf("✅ [DhcpHunter:%s] Active. AllowList: %d MACs, %d Subnets", 
-		iface.Name, len(d.trustedMacs), len(d.trustedNets))
-	
-	return nil
+		ifaceName, len(d.trustedMacs), len(d.trustedNets))
 }
 
-func (d *DhcpHunter) OnPacket(data []byte, length int, vlanID uint16) {
-	ethOffset := 14
-	ethTypeOffset := 12
-	if vlanID != 0 {
-		ethOffset = 18
-		ethTypeOffset = 16
-	}
-
-	if length < ethOffset {
+func (d *DhcpHunter) OnFrame(f *decoder.Frame) {
+	if f.Eth.EtherType != EtherTypeIPv4 {
 		return
 	}
 
-	ethType := binary.BigEndian.Uint16(data[ethTypeOffset : ethTypeOffset+2])
-	if ethType != EtherTypeIPv4 {
+	udp, ok := f.UDP()
+	if !ok {
 		return
 	}
 
-	if length < ethOffset+20 {
-		return
-	}
-
-	ihl := int(data[ethOffset]&0x0F) * 4
-	if ihl < 20 {
-		return
-	}
-
-	protocol := data[ethOffset+9]
-	if protocol != IPProtoUDP {
-		return
-	}
-
-	srcIP := net.IP(data[ethOffset+12 : ethOffset+16])
-
-	udpStart := ethOffset + ihl
-	if length < udpStart+8 {
-		return
-	}
-
-	srcPort := binary.BigEndian.Uint16(data[udpStart : udpStart+2])
-	dstPort := binary.BigEndian.Uint16(data[udpStart+2 : udpStart+4])
-
-	if srcPort == DhcpServerPort && dstPort == DhcpClientPort {
+	if udp.SrcPort == DhcpServerPort && udp.DstPort == DhcpClientPort {
 		
-		srcMacSlice := data[6:12]
+		ip, _ := f.IP()
+		srcIP := net.IP(ip.Src)
+		srcMacSlice := f.Src()
 		srcMacStr := net.HardwareAddr(srcMacSlice).String()
 		
 		isTrusted := false
@@ -157,8 +152,10 @@ func (d *DhcpHunter) OnPacket(data []byte, length int, vlanID uint16) {
 		}
 
 		if !isTrusted {
+			d.incidents.seen("RogueServer", srcMacStr, 0)
+
 			d.mu.Lock()
-			now := time.Now()
+			now := clock.Now()
 			if now.Sub(d.lastAlert) > DhcpCooldown {
 				
 				// UPDATED: Added d.ifaceName label
@@ -170,21 +167,19 @@ func (d *DhcpHunter) OnPacket(data []byte, length int, vlanID uint16) {
 				// CAPTURE VARIABLE FOR SAFETY
 				currentIface := d.ifaceName
 				
-				go func(iface, ip, mac string, vlan uint16) {
-					vlanStr := "Native"
-					if vlan != 0 {
-						vlanStr = fmt.Sprintf("%d", vlan)
-					}
-					
-					msg := fmt.Sprintf("[DhcpHunter] 🚨 ROGUE DHCP SERVER DETECTED!\n"+
-						"    INTERFACE: %s\n"+
-						"    VLAN:      %s\n"+
-						"    ROGUE MAC: %s\n"+
-						"    ROGUE IP:  %s\n"+
-						"    ACTION:    Investigate immediately. Possible Man-in-the-Middle.",
-						iface, vlanStr, mac, ip)
-					d.notify.Alert(msg)
-				}(currentIface, capturedSrcIP, capturedSrcMAC, vlanID)
+				alerts.Add(1)
+				go func(iface, ip, mac string, vlan vlanTag) {
+					defer alerts.Done()
+					d.notify.Notify(d.incidents.fire(mac, vlan.apply(notifier.Event{
+						Interface:  iface,
+						Algorithm:  "DhcpHunter",
+						ThreatType: "RogueServer",
+						Severity:   notifier.SeverityCritical,
+						Title:      "🚨 ROGUE DHCP SERVER DETECTED!",
+						SrcMAC:     mac,
+						SrcIP:      ip,
+					}).With("ACTION", "Investigate immediately. Possible Man-in-the-Middle.")))
+				}(currentIface, capturedSrcIP, capturedSrcMAC, frameVLAN(f))
 				
 				d.lastAlert = now
 			}
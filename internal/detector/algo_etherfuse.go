@@ -1,6 +1,7 @@
 package detector
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/mdlayher/packet"
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
+	"github.com/soyunomas/loopwarden/internal/forensics"
 	"github.com/soyunomas/loopwarden/internal/notifier"
 	"github.com/soyunomas/loopwarden/internal/telemetry"
 	"github.com/soyunomas/loopwarden/internal/utils"
@@ -20,6 +23,8 @@ const (
 )
 
 type EtherFuse struct {
+	lifecycle // Goroutines de fondo: Stop() las cancela y espera
+
 	cfg       *config.EtherFuseConfig
 	notify    *notifier.Notifier
 	ifaceName string
@@ -34,9 +39,21 @@ type EtherFuse struct {
 	lookupTable map[uint64]uint8
 	writeCursor int
 
+	pending uint64           // Tramas vistas por este worker desde el último volcado al global
+	global  *etherFuseGlobal // PPS de tormenta y cooldown de la interfaz
+
+	recorder *forensics.Recorder // Evidencia pcap (opcional)
+}
+
+// etherFuseGlobal es el estado de EtherFuse que pertenece a la interfaz y no al worker:
+// con PACKET_FANOUT cada worker tiene su historial de hashes, pero la tormenta se mide
+// sobre el total y un mismo incidente no debe alertar una vez por worker.
+type etherFuseGlobal struct {
+	mu            sync.Mutex
 	packetsSec    uint64
 	lastReset     time.Time
 	lastAlertTime time.Time
+	incidents     *incidents // Tormenta y bucle se siguen por interfaz, no por worker
 }
 
 func NewEtherFuse(cfg *config.EtherFuseConfig, n *notifier.Notifier, ifaceName string) *EtherFuse {
@@ -47,27 +64,55 @@ func NewEtherFuse(cfg *config.EtherFuseConfig, n *notifier.Notifier, ifaceName s
 		ringBuffer:  make([]uint64, cfg.HistorySize),
 		lookupTable: make(map[uint64]uint8, cfg.HistorySize),
 		writeCursor: 0,
-		lastReset:   time.Now(),
+		global:      &etherFuseGlobal{lastReset: clock.Now(), incidents: newIncidents(n)},
 	}
 }
 
 func (ef *EtherFuse) Name() string { return "EtherFuse" }
 
-func (ef *EtherFuse) Start(conn *packet.Conn, iface *net.Interface) error {
+func (ef *EtherFuse) incidentTracker() *incidents { return ef.global.incidents }
+
+func (ef *EtherFuse) setRecorder(r *forensics.Recorder) { ef.recorder = r }
+
+func (ef *EtherFuse) Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error {
+	ef.begin(ctx)
+	ef.configure(iface.Name)
+	// Los workers comparten global: barrer varias veces el mismo tracker es inocuo
+	ef.every(1*time.Second, ef.global.incidents.sweep)
+	return nil
+}
+
+// reconfigure aplica una recarga de configuración (SIGHUP) sin perder estado.
+func (ef *EtherFuse) reconfigure(cfg *config.AlgorithmConfig) {
+	ef.mu.Lock()
+	ef.cfg = &cfg.EtherFuse
+	ef.configure(ef.ifaceName)
+	ef.mu.Unlock()
+}
+
+// configure calcula la configuración efectiva (global + override).
+func (ef *EtherFuse) configure(ifaceName string) {
+	// El historial sólo se rehace si cambia su tamaño (una recarga no debe olvidar lo visto)
+	if len(ef.ringBuffer) != ef.cfg.HistorySize {
+		ef.ringBuffer = make([]uint64, ef.cfg.HistorySize)
+		ef.lookupTable = make(map[uint64]uint8, ef.cfg.HistorySize)
+		ef.writeCursor = 0
+	}
+
 	// 1. Base Global
 	ef.alertThreshold = ef.cfg.AlertThreshold
 	ef.stormPPSLimit = ef.cfg.StormPPSLimit
 	
 	dur, err := time.ParseDuration(ef.cfg.AlertCooldown)
 	if err != nil {
-		log.Printf("⚠️ [EtherFuse:%s] Invalid AlertCooldown '%s', defaulting to 5s", iface.Name, ef.cfg.AlertCooldown)
+		log.Printf("⚠️ [EtherFuse:%s] Invalid AlertCooldown '%s', defaulting to 5s", ifaceName, ef.cfg.AlertCooldown)
 		ef.cooldown = 5 * time.Second
 	} else {
 		ef.cooldown = dur
 	}
 
 	// 2. Override
-	if override, ok := ef.cfg.Overrides[iface.Name]; ok {
+	if override, ok := ef.cfg.Overrides[ifaceName]; ok {
 		if override.AlertThreshold > 0 {
 			ef.alertThreshold = override.AlertThreshold
 		}
@@ -75,13 +120,11 @@ func (ef *EtherFuse) Start(conn *packet.Conn, iface *net.Interface) error {
 			ef.stormPPSLimit = override.StormPPSLimit
 		}
 		log.Printf("🔧 [EtherFuse:%s] Override Threshold=%d, StormLimit=%d",
-			iface.Name, ef.alertThreshold, ef.stormPPSLimit)
+			ifaceName, ef.alertThreshold, ef.stormPPSLimit)
 	}
 	
 	// 3. Fallback
 	if ef.cooldown == 0 { ef.cooldown = 5 * time.Second }
-	
-	return nil
 }
 
 func hashBody(data []byte) uint64 {
@@ -93,41 +136,57 @@ func hashBody(data []byte) uint64 {
 	return hash
 }
 
-func (ef *EtherFuse) OnPacket(data []byte, length int, vlanID uint16) {
+func (ef *EtherFuse) OnFrame(f *decoder.Frame) {
+	data := f.Data
+	length := len(data)
+
 	// 1. Calcular Hash
-	sum := hashBody(data[:length])
+	sum := hashBody(data)
 
 	ef.mu.Lock()
 
 	// Check de tormenta global (PPS)
-	ef.packetsSec++
-	if ef.packetsSec&0x3FF == 0 {
-		now := time.Now()
-		if now.Sub(ef.lastReset) >= time.Second {
-			if ef.packetsSec > ef.stormPPSLimit {
+	// OPTIMIZACIÓN: Contador local; sólo tocamos el estado compartido cada 1024 tramas
+	ef.pending++
+	if ef.pending&0x3FF == 0 {
+		g := ef.global
+		g.mu.Lock()
+		g.packetsSec += ef.pending
+		ef.pending = 0
+
+		now := clock.Now()
+		if now.Sub(g.lastReset) >= time.Second {
+			if g.packetsSec > ef.stormPPSLimit {
+				g.incidents.seen("GlobalStorm", "", g.packetsSec)
 				// Usamos variable configurada
-				if now.Sub(ef.lastAlertTime) > ef.cooldown {
+				if now.Sub(g.lastAlertTime) > ef.cooldown {
 					telemetry.EngineHits.WithLabelValues(ef.ifaceName, "EtherFuse", "GlobalStorm").Inc()
 
-					loc := "Native"
-					if vlanID != 0 {
-						loc = fmt.Sprintf("%d", vlanID)
-					}
-					pps := ef.packetsSec
+					loc := frameVLAN(f)
+					pps := g.packetsSec
 					currentIface := ef.ifaceName
 
-					go func(iface string, l string, p uint64) {
-						ef.notify.Alert(fmt.Sprintf("[EtherFuse] ⛈️ GLOBAL STORM DETECTED!\n"+
-							"    INTERFACE: %s\n"+
-							"    VLAN:      %s\n"+
-							"    RATE:      %d pps", iface, l, p))
-					}(currentIface, loc, pps)
-					ef.lastAlertTime = now
+					alerts.Add(1)
+					go func(iface string, l vlanTag, p, limit uint64) {
+						defer alerts.Done()
+						ef.notify.Notify(g.incidents.fire("", l.apply(notifier.Event{
+							Interface:  iface,
+							Algorithm:  "EtherFuse",
+							ThreatType: "GlobalStorm",
+							Severity:   notifier.SeverityCritical,
+							Title:      "⛈️ GLOBAL STORM DETECTED!",
+							Rate:       p,
+							Threshold:  limit,
+							Evidence:   evidencePath(ef.recorder, "EtherFuse-GlobalStorm"),
+						})))
+					}(currentIface, loc, pps, ef.stormPPSLimit)
+					g.lastAlertTime = now
 				}
 			}
-			ef.packetsSec = 0
-			ef.lastReset = now
+			g.packetsSec = 0
+			g.lastReset = now
 		}
+		g.mu.Unlock()
 	}
 
 	// 2. Lógica de detección de bucle
@@ -139,8 +198,17 @@ func (ef *EtherFuse) OnPacket(data []byte, length int, vlanID uint16) {
 
 		if int(newCount) > ef.alertThreshold {
 			// Usamos variable configurada
-			if time.Since(ef.lastAlertTime) > ef.cooldown {
+			g := ef.global
+			g.incidents.seen("LoopDetected", "", 0)
+			g.mu.Lock()
+			now := clock.Now()
+			canAlert := now.Sub(g.lastAlertTime) > ef.cooldown
+			if canAlert {
+				g.lastAlertTime = now
+			}
+			g.mu.Unlock()
 
+			if canAlert {
 				telemetry.EngineHits.WithLabelValues(ef.ifaceName, "EtherFuse", "LoopDetected").Inc()
 
 				var dstMacBytes, srcMacBytes []byte
@@ -151,37 +219,36 @@ func (ef *EtherFuse) OnPacket(data []byte, length int, vlanID uint16) {
 					copy(srcMacBytes, data[6:12])
 				}
 
-				vlanStr := "Native"
-				if vlanID != 0 {
-					vlanStr = fmt.Sprintf("%d", vlanID)
-				}
+				vlan := frameVLAN(f)
 				
 				currentIface := ef.ifaceName
 
-				go func(iface string, v string, sMac, dMac []byte, h uint64, reps uint8) {
+				alerts.Add(1)
+				go func(iface string, v vlanTag, sMac, dMac []byte, h uint64, reps uint8) {
+					defer alerts.Done()
 					targetInfo := utils.ClassifyMAC(dMac)
 					impact := "User Traffic"
 					if targetInfo.IsCritical {
 						impact = "🔥 CRITICAL INFRASTRUCTURE FAILURE"
 					}
 
-					srcStr := net.HardwareAddr(sMac).String()
-					dstStr := net.HardwareAddr(dMac).String()
-
-					msg := fmt.Sprintf("[EtherFuse] 🚨 LOOP DETECTED!\n"+
-						"    INTERFACE:   %s\n"+
-						"    VLAN:        %s\n"+
-						"    SOURCE MAC:  %s\n"+
-						"    TARGET MAC:  %s (%s)\n"+
-						"    PROTOCOL:    %s\n"+
-						"    IMPACT:      %s\n"+
-						"    REPETITIONS: %d (Hash: %x)",
-						iface, v, srcStr, dstStr, targetInfo.Name, targetInfo.Description, impact, reps, h)
-
-					ef.notify.Alert(msg)
-				}(currentIface, vlanStr, srcMacBytes, dstMacBytes, sum, newCount)
+					ev := v.apply(notifier.Event{
+						Interface:  iface,
+						Algorithm:  "EtherFuse",
+						ThreatType: "LoopDetected",
+						Severity:   notifier.SeverityCritical, // Bucle confirmado: siempre despierta a guardia
+						Title:      "🚨 LOOP DETECTED!",
+						SrcMAC:     net.HardwareAddr(sMac).String(),
+						DstMAC:     net.HardwareAddr(dMac).String(),
+					})
+					ev = ev.With("TARGET TYPE", targetInfo.Name).
+						With("PROTOCOL", targetInfo.Description).
+						With("IMPACT", impact).
+						With("REPETITIONS", fmt.Sprintf("%d (Hash: %x)", reps, h))
+					ev.Evidence = evidencePath(ef.recorder, "EtherFuse-LoopDetected")
 
-				ef.lastAlertTime = time.Now()
+					ef.notify.Notify(g.incidents.fire("", ev))
+				}(currentIface, vlan, srcMacBytes, dstMacBytes, sum, newCount)
 			}
 			ef.lookupTable[sum] = 0
 		}
@@ -1,6 +1,7 @@
 package detector
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/mdlayher/packet"
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
 	"github.com/soyunomas/loopwarden/internal/notifier"
 	"github.com/soyunomas/loopwarden/internal/telemetry"
 	"github.com/soyunomas/loopwarden/internal/utils"
@@ -21,11 +23,13 @@ const (
 type flapEntry struct {
 	lastSeen  int64
 	lastAlert int64
-	lastVLAN  uint16
+	lastVLAN  uint32 // outer<<16 | inner: un salto entre C-tags de la misma S-VLAN también cuenta
 	flapCount uint16
 }
 
 type FlapGuard struct {
+	lifecycle // Goroutines de fondo: Stop() las cancela y espera
+
 	cfg       *config.FlapGuardConfig
 	notify    *notifier.Notifier
 	ifaceName string 
@@ -35,8 +39,9 @@ type FlapGuard struct {
 	windowNano   int64
 	cooldownNano int64
 
-	mu       sync.Mutex
-	registry map[[6]byte]flapEntry
+	mu        sync.Mutex
+	registry  map[[6]byte]flapEntry
+	incidents *incidents // Ciclo de vida: repeticiones y evento "resolved"
 }
 
 func NewFlapGuard(cfg *config.FlapGuardConfig, n *notifier.Notifier, ifaceName string) *FlapGuard {
@@ -45,43 +50,83 @@ func NewFlapGuard(cfg *config.FlapGuardConfig, n *notifier.Notifier, ifaceName s
 		notify:    n,
 		ifaceName: ifaceName,
 		registry:  make(map[[6]byte]flapEntry, 1000),
+		incidents: newIncidents(n),
 	}
 }
 
 func (fg *FlapGuard) Name() string { return "FlapGuard" }
 
-func (fg *FlapGuard) Start(conn *packet.Conn, iface *net.Interface) error {
+func (fg *FlapGuard) incidentTracker() *incidents { return fg.incidents }
+
+func (fg *FlapGuard) Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error {
+	fg.begin(ctx)
+	fg.configure(iface.Name)
+	fg.every(1*time.Second, fg.incidents.sweep)
+
+	// Tarea de limpieza de memoria
+	fg.every(30*time.Second, func() {
+		fg.mu.Lock()
+		now := clock.Now().UnixNano()
+		expiry := int64(60 * time.Second) // Limpieza agresiva si está lleno
+
+		if len(fg.registry) > MaxFlapEntries {
+			expiry = int64(10 * time.Second)
+		}
+		
+		// Convertir a nanosegundos para la comparación
+		expiryNano := expiry
+
+		for mac, entry := range fg.registry {
+			if now-entry.lastSeen > expiryNano {
+				delete(fg.registry, mac)
+			}
+		}
+		fg.mu.Unlock()
+	})
+	return nil
+}
+
+// reconfigure aplica una recarga de configuración (SIGHUP) sin perder estado.
+func (fg *FlapGuard) reconfigure(cfg *config.AlgorithmConfig) {
+	fg.mu.Lock()
+	fg.cfg = &cfg.FlapGuard
+	fg.configure(fg.ifaceName)
+	fg.mu.Unlock()
+}
+
+// configure calcula la configuración efectiva (global + override).
+func (fg *FlapGuard) configure(ifaceName string) {
 	// 1. Defaults Globales
 	fg.threshold = uint16(fg.cfg.Threshold)
 	
 	// Parseo de Window Global
 	winDur, err := time.ParseDuration(fg.cfg.Window)
 	if err != nil {
-		log.Printf("⚠️ [FlapGuard:%s] Invalid Window '%s', defaulting to 1s", iface.Name, fg.cfg.Window)
+		log.Printf("⚠️ [FlapGuard:%s] Invalid Window '%s', defaulting to 1s", ifaceName, fg.cfg.Window)
 		winDur = 1 * time.Second
 	}
 	
 	// Parseo de Cooldown Global
 	coolDur, err := time.ParseDuration(fg.cfg.AlertCooldown)
 	if err != nil {
-		log.Printf("⚠️ [FlapGuard:%s] Invalid AlertCooldown '%s', defaulting to 30s", iface.Name, fg.cfg.AlertCooldown)
+		log.Printf("⚠️ [FlapGuard:%s] Invalid AlertCooldown '%s', defaulting to 30s", ifaceName, fg.cfg.AlertCooldown)
 		coolDur = 30 * time.Second
 	}
 
 	// 2. Overrides
-	if override, ok := fg.cfg.Overrides[iface.Name]; ok {
+	if override, ok := fg.cfg.Overrides[ifaceName]; ok {
 		if override.Threshold > 0 {
 			fg.threshold = uint16(override.Threshold)
-			log.Printf("🔧 [FlapGuard:%s] Override Threshold = %d", iface.Name, fg.threshold)
+			log.Printf("🔧 [FlapGuard:%s] Override Threshold = %d", ifaceName, fg.threshold)
 		}
 		// Override de Window si existe
 		if override.Window != "" {
 			ovWin, err := time.ParseDuration(override.Window)
 			if err == nil {
 				winDur = ovWin
-				log.Printf("🔧 [FlapGuard:%s] Override Window = %v", iface.Name, ovWin)
+				log.Printf("🔧 [FlapGuard:%s] Override Window = %v", ifaceName, ovWin)
 			} else {
-				log.Printf("⚠️ [FlapGuard:%s] Invalid Override Window '%s', ignoring", iface.Name, override.Window)
+				log.Printf("⚠️ [FlapGuard:%s] Invalid Override Window '%s', ignoring", ifaceName, override.Window)
 			}
 		}
 	}
@@ -94,44 +139,18 @@ func (fg *FlapGuard) Start(conn *packet.Conn, iface *net.Interface) error {
 	fg.windowNano = winDur.Nanoseconds()
 	fg.cooldownNano = coolDur.Nanoseconds()
 
-	log.Printf("✅ [FlapGuard:%s] Active. Threshold: %d moves / %v", iface.Name, fg.threshold, winDur)
-
-	// Goroutine de limpieza de memoria
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-
-		for range ticker.C {
-			fg.mu.Lock()
-			now := time.Now().UnixNano()
-			expiry := int64(60 * time.Second) // Limpieza agresiva si está lleno
-
-			if len(fg.registry) > MaxFlapEntries {
-				expiry = int64(10 * time.Second)
-			}
-			
-			// Convertir a nanosegundos para la comparación
-			expiryNano := expiry
-
-			for mac, entry := range fg.registry {
-				if now-entry.lastSeen > expiryNano {
-					delete(fg.registry, mac)
-				}
-			}
-			fg.mu.Unlock()
-		}
-	}()
-	return nil
+	log.Printf("✅ [FlapGuard:%s] Active. Threshold: %d moves / %v", ifaceName, fg.threshold, winDur)
 }
 
-func (fg *FlapGuard) OnPacket(data []byte, length int, vlanID uint16) {
-	if length < 12 { return }
+func (fg *FlapGuard) OnFrame(f *decoder.Frame) {
+	if !f.Valid { return }
+	vlanKey := uint32(f.Eth.OuterVLAN)<<16 | uint32(f.Eth.InnerVLAN)
 
 	var srcMac [6]byte
-	copy(srcMac[:], data[6:12])
+	copy(srcMac[:], f.Src())
 
 	// Hot Path: UnixNano es mucho más rápido que instanciar objetos time.Time
-	now := time.Now().UnixNano()
+	now := clock.Now().UnixNano()
 
 	fg.mu.Lock()
 	entry, exists := fg.registry[srcMac]
@@ -144,14 +163,14 @@ func (fg *FlapGuard) OnPacket(data []byte, length int, vlanID uint16) {
 		}
 
 		fg.registry[srcMac] = flapEntry{
-			lastVLAN: vlanID,
+			lastVLAN: vlanKey,
 			lastSeen: now,
 		}
 		fg.mu.Unlock()
 		return
 	}
 
-	if entry.lastVLAN != vlanID {
+	if entry.lastVLAN != vlanKey {
 		// USAR VARIABLE DE INSTANCIA (Calculada en Start)
 		if (now - entry.lastSeen) < fg.windowNano {
 			entry.flapCount++
@@ -159,10 +178,12 @@ func (fg *FlapGuard) OnPacket(data []byte, length int, vlanID uint16) {
 			entry.flapCount = 1
 		}
 
-		entry.lastVLAN = vlanID
+		entry.lastVLAN = vlanKey
 		entry.lastSeen = now
 
 		if entry.flapCount >= fg.threshold {
+			fg.incidents.seen("MacFlapping", net.HardwareAddr(srcMac[:]).String(), 0)
+
 			// USAR VARIABLE DE INSTANCIA
 			if (now - entry.lastAlert) > fg.cooldownNano {
 				entry.lastAlert = now
@@ -173,7 +194,8 @@ func (fg *FlapGuard) OnPacket(data []byte, length int, vlanID uint16) {
 				fg.mu.Unlock()
 
 				currentIface := fg.ifaceName
-				go fg.sendAlert(currentIface, srcMac, entry.flapCount, vlanID)
+				alerts.Add(1)
+				go fg.sendAlert(currentIface, srcMac, entry.flapCount, time.Duration(fg.windowNano), frameVLAN(f))
 				return
 			}
 		}
@@ -190,28 +212,32 @@ func (fg *FlapGuard) OnPacket(data []byte, length int, vlanID uint16) {
 	fg.mu.Unlock()
 }
 
-func (fg *FlapGuard) sendAlert(iface string, mac [6]byte, count uint16, vlanID uint16) {
+func (fg *FlapGuard) sendAlert(iface string, mac [6]byte, count uint16, window time.Duration, vlan vlanTag) {
+	defer alerts.Done()
 	macSlice := mac[:]
 	info := utils.ClassifyMAC(macSlice)
 
-	macStr := net.HardwareAddr(macSlice).String()
 	identity := "Host"
 	if info.Name != "Unicast" {
 		identity = fmt.Sprintf("%s (%s)", info.Name, info.Description)
 	}
 
-	severity := "⚠️ WARNING"
+	severity, label := notifier.SeverityWarning, "⚠️ WARNING"
 	if info.IsCritical {
-		severity = "🔥 CRITICAL"
+		severity, label = notifier.SeverityCritical, "🔥 CRITICAL"
 	}
 
-	msg := fmt.Sprintf("[FlapGuard] %s: TOPOLOGY CHANGE DETECTED!\n"+
-		"    INTERFACE: %s\n"+
-		"    IDENTITY:  %s\n"+
-		"    MAC:       %s\n"+
-		"    MOVES:     %d times in %s (Current VLAN: %d)\n"+
-		"    ANALYSIS:  Device is jumping between VLANs. Possible cabling loop or leaking configuration.",
-		severity, iface, identity, macStr, count, time.Duration(fg.windowNano), vlanID)
-
-	fg.notify.Alert(msg)
+	ev := vlan.apply(notifier.Event{
+		Interface:  iface,
+		Algorithm:  "FlapGuard",
+		ThreatType: "MacFlapping",
+		Severity:   severity,
+		Title:      label + ": TOPOLOGY CHANGE DETECTED!",
+		SrcMAC:     net.HardwareAddr(macSlice).String(),
+	})
+	ev = ev.With("IDENTITY", identity).
+		With("MOVES", fmt.Sprintf("%d times in %s", count, window)).
+		With("ANALYSIS", "Device is jumping between VLANs. Possible cabling loop or leaking configuration.")
+
+	fg.notify.Notify(fg.incidents.fire(ev.SrcMAC, ev))
 }
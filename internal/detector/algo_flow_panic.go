@@ -1,8 +1,8 @@
 package detector
 
 import (
+	"context"
 	"encoding/binary"
-	"fmt"
 	"log"
 	"net"
 	"sync"
@@ -10,6 +10,7 @@ import (
 
 	"github.com/mdlayher/packet"
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
 	"github.com/soyunomas/loopwarden/internal/notifier"
 	"github.com/soyunomas/loopwarden/internal/telemetry"
 )
@@ -21,6 +22,8 @@ const (
 )
 
 type FlowPanic struct {
+	lifecycle // Goroutines de fondo: Stop() las cancela y espera
+
 	cfg       *config.FlowPanicConfig
 	notify    *notifier.Notifier
 	ifaceName string // Identidad de la interfaz
@@ -32,6 +35,7 @@ type FlowPanic struct {
 	packetCount uint64
 	lastReset   time.Time
 	lastAlert   time.Time
+	incidents   *incidents // Ciclo de vida: repeticiones y evento "resolved"
 }
 
 func NewFlowPanic(cfg *config.FlowPanicConfig, n *notifier.Notifier, ifaceName string) *FlowPanic {
@@ -39,70 +43,86 @@ func NewFlowPanic(cfg *config.FlowPanicConfig, n *notifier.Notifier, ifaceName s
 		cfg:       cfg,
 		notify:    n,
 		ifaceName: ifaceName,
-		lastReset: time.Now(),
+		lastReset: clock.Now(),
+		incidents: newIncidents(n),
 	}
 }
 
 func (fp *FlowPanic) Name() string { return "FlowPanic" }
 
-func (fp *FlowPanic) Start(conn *packet.Conn, iface *net.Interface) error {
+func (fp *FlowPanic) incidentTracker() *incidents { return fp.incidents }
+
+func (fp *FlowPanic) Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error {
+	fp.begin(ctx)
+	fp.configure(iface.Name)
+	fp.every(1*time.Second, fp.incidents.sweep)
+	return nil
+}
+
+// reconfigure aplica una recarga de configuración (SIGHUP) sin perder estado.
+func (fp *FlowPanic) reconfigure(cfg *config.AlgorithmConfig) {
+	fp.mu.Lock()
+	fp.cfg = &cfg.FlowPanic
+	fp.configure(fp.ifaceName)
+	fp.mu.Unlock()
+}
+
+// configure calcula la configuración efectiva (global + override).
+func (fp *FlowPanic) configure(ifaceName string) {
 	// 1. Base Global
 	fp.maxPausePPS = fp.cfg.MaxPausePPS
 
 	// 2. Override
-	if override, ok := fp.cfg.Overrides[iface.Name]; ok {
+	if override, ok := fp.cfg.Overrides[ifaceName]; ok {
 		if override.MaxPausePPS > 0 {
 			fp.maxPausePPS = override.MaxPausePPS
-			log.Printf("🔧 [FlowPanic] Override applied for %s: MaxPausePPS = %d", iface.Name, fp.maxPausePPS)
+			log.Printf("🔧 [FlowPanic] Override applied for %s: MaxPausePPS = %d", ifaceName, fp.maxPausePPS)
 		}
 	}
-	return nil
 }
 
-func (fp *FlowPanic) OnPacket(data []byte, length int, vlanID uint16) {
-	ethTypeOffset := 12
-	payloadOffset := 14
-	if vlanID != 0 {
-		ethTypeOffset = 16
-		payloadOffset = 18
-	}
-
-	if length < payloadOffset+2 {
+func (fp *FlowPanic) OnFrame(f *decoder.Frame) {
+	payload := f.Payload()
+	if len(payload) < 2 {
 		return
 	}
 
-	ethType := binary.BigEndian.Uint16(data[ethTypeOffset : ethTypeOffset+2])
-	
-	if ethType == EtherTypeMacControl {
-		opCode := binary.BigEndian.Uint16(data[payloadOffset : payloadOffset+2])
+	if f.Eth.EtherType == EtherTypeMacControl {
+		opCode := binary.BigEndian.Uint16(payload[0:2])
 		
 		if opCode == OpCodePause {
 			fp.mu.Lock()
 			fp.packetCount++
 			
-			now := time.Now()
+			now := clock.Now()
 			if now.Sub(fp.lastReset) >= time.Second {
 				// USO DE VARIABLE LOCAL
 				if fp.packetCount > fp.maxPausePPS {
+					fp.incidents.seen("PauseFlood", "", fp.packetCount)
 					if now.Sub(fp.lastAlert) > PauseAlertCooldown {
 						
 						// UPDATED: Added fp.ifaceName label
 						telemetry.EngineHits.WithLabelValues(fp.ifaceName, "FlowPanic", "PauseFlood").Inc()
 
 						count := fp.packetCount
-						srcMac := net.HardwareAddr(data[6:12]).String()
+						srcMac := net.HardwareAddr(f.Src()).String()
 						
 						// CAPTURE VARIABLE FOR SAFETY
 						currentIface := fp.ifaceName
 
+						alerts.Add(1)
 						go func(iface string, c uint64, mac string, limit uint64) {
-							msg := fmt.Sprintf("[FlowPanic] ⏸️ PAUSE FRAME FLOOD (DoS)!\n"+
-								"    INTERFACE: %s\n"+
-								"    SOURCE:    %s\n"+
-								"    RATE:      %d frames/sec (Limit: %d)\n"+
-								"    IMPACT:    Network stuck. NIC hardware failure or loop.",
-								iface, mac, c, limit)
-							fp.notify.Alert(msg)
+							defer alerts.Done()
+							fp.notify.Notify(fp.incidents.fire("", notifier.Event{
+								Interface:  iface,
+								Algorithm:  "FlowPanic",
+								ThreatType: "PauseFlood",
+								Severity:   notifier.SeverityCritical,
+								Title:      "⏸️ PAUSE FRAME FLOOD (DoS)!",
+								SrcMAC:     mac,
+								Rate:       c,
+								Threshold:  limit,
+							}.With("IMPACT", "Network stuck. NIC hardware failure or loop.")))
 						}(currentIface, count, srcMac, fp.maxPausePPS)
 						
 						fp.lastAlert = now
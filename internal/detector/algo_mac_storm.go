@@ -1,6 +1,7 @@
 package detector
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net"
@@ -9,12 +10,16 @@ import (
 
 	"github.com/mdlayher/packet"
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
+	"github.com/soyunomas/loopwarden/internal/forensics"
 	"github.com/soyunomas/loopwarden/internal/notifier"
 	"github.com/soyunomas/loopwarden/internal/telemetry"
 	"github.com/soyunomas/loopwarden/internal/utils"
 )
 
 type MacStorm struct {
+	lifecycle // Goroutines de fondo: Stop() las cancela y espera
+
 	cfg       *config.MacStormConfig
 	notify    *notifier.Notifier
 	ifaceName string
@@ -27,6 +32,9 @@ type MacStorm struct {
 	mu         sync.Mutex
 	counters   map[[6]byte]uint64
 	alertState map[[6]byte]time.Time
+	incidents  *incidents // Ciclo de vida: repeticiones y evento "resolved"
+
+	recorder *forensics.Recorder // Evidencia pcap (opcional)
 }
 
 func NewMacStorm(cfg *config.MacStormConfig, n *notifier.Notifier, ifaceName string) *MacStorm {
@@ -36,29 +44,78 @@ func NewMacStorm(cfg *config.MacStormConfig, n *notifier.Notifier, ifaceName str
 		ifaceName:  ifaceName,
 		counters:   make(map[[6]byte]uint64, 1000),
 		alertState: make(map[[6]byte]time.Time),
+		incidents:  newIncidents(n),
 	}
 }
 
 func (ms *MacStorm) Name() string { return "MacStorm" }
 
-func (ms *MacStorm) Start(conn *packet.Conn, iface *net.Interface) error {
+func (ms *MacStorm) incidentTracker() *incidents { return ms.incidents }
+
+func (ms *MacStorm) setRecorder(r *forensics.Recorder) { ms.recorder = r }
+
+func (ms *MacStorm) Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error {
+	ms.begin(ctx)
+	ms.configure(iface.Name)
+
+	// Ventana de tasa (1s)
+	ms.every(1*time.Second, func() {
+		ms.mu.Lock()
+		// Cierre de ventana: cada host por encima del límite sigue inundando
+		for mac, count := range ms.counters {
+			if count > ms.limitPPS {
+				ms.incidents.seen("HostFlood", net.HardwareAddr(mac[:]).String(), count)
+			}
+		}
+		// Precepto #12: Map reset
+		ms.counters = make(map[[6]byte]uint64, 1000)
+		ms.mu.Unlock()
+	})
+
+	ms.every(1*time.Second, ms.incidents.sweep)
+
+	// Limpieza del registro de alertas
+	ms.every(60*time.Second, func() {
+		ms.mu.Lock()
+		now := clock.Now()
+		expiry := ms.cooldown * 2
+		for mac, lastAlert := range ms.alertState {
+			if now.Sub(lastAlert) > expiry {
+				delete(ms.alertState, mac)
+			}
+		}
+		ms.mu.Unlock()
+	})
+	return nil
+}
+
+// reconfigure aplica una recarga de configuración (SIGHUP) sin perder estado.
+func (ms *MacStorm) reconfigure(cfg *config.AlgorithmConfig) {
+	ms.mu.Lock()
+	ms.cfg = &cfg.MacStorm
+	ms.configure(ms.ifaceName)
+	ms.mu.Unlock()
+}
+
+// configure calcula la configuración efectiva (global + override).
+func (ms *MacStorm) configure(ifaceName string) {
 	// 1. Defaults Globales
 	ms.limitPPS = ms.cfg.MaxPPSPerMac
 	ms.maxTracked = ms.cfg.MaxTrackedMacs
 	
 	dur, err := time.ParseDuration(ms.cfg.AlertCooldown)
 	if err != nil {
-		log.Printf("⚠️ [MacStorm:%s] Invalid AlertCooldown '%s', defaulting to 30s", iface.Name, ms.cfg.AlertCooldown)
+		log.Printf("⚠️ [MacStorm:%s] Invalid AlertCooldown '%s', defaulting to 30s", ifaceName, ms.cfg.AlertCooldown)
 		ms.cooldown = 30 * time.Second
 	} else {
 		ms.cooldown = dur
 	}
 
 	// 2. Overrides
-	if override, ok := ms.cfg.Overrides[iface.Name]; ok {
+	if override, ok := ms.cfg.Overrides[ifaceName]; ok {
 		if override.MaxPPSPerMac > 0 {
 			ms.limitPPS = override.MaxPPSPerMac
-			log.Printf("🔧 [MacStorm:%s] Override MaxPPS = %d", iface.Name, ms.limitPPS)
+			log.Printf("🔧 [MacStorm:%s] Override MaxPPS = %d", ifaceName, ms.limitPPS)
 		}
 	}
 
@@ -68,43 +125,14 @@ func (ms *MacStorm) Start(conn *packet.Conn, iface *net.Interface) error {
 	if ms.cooldown == 0 { ms.cooldown = 30 * time.Second }
 
 	log.Printf("✅ [MacStorm:%s] Active. Limit: %d pps, MemLimit: %d hosts, Cooldown: %v", 
-		iface.Name, ms.limitPPS, ms.maxTracked, ms.cooldown)
-
-	go func() {
-		rateTicker := time.NewTicker(1 * time.Second)
-		cleanupTicker := time.NewTicker(60 * time.Second)
-		defer rateTicker.Stop()
-		defer cleanupTicker.Stop()
-
-		for {
-			select {
-			case <-rateTicker.C:
-				ms.mu.Lock()
-				// Precepto #12: Map reset
-				ms.counters = make(map[[6]byte]uint64, 1000)
-				ms.mu.Unlock()
-
-			case <-cleanupTicker.C:
-				ms.mu.Lock()
-				now := time.Now()
-				expiry := ms.cooldown * 2
-				for mac, lastAlert := range ms.alertState {
-					if now.Sub(lastAlert) > expiry {
-						delete(ms.alertState, mac)
-					}
-				}
-				ms.mu.Unlock()
-			}
-		}
-	}()
-	return nil
+		ifaceName, ms.limitPPS, ms.maxTracked, ms.cooldown)
 }
 
-func (ms *MacStorm) OnPacket(data []byte, length int, vlanID uint16) {
-	if length < 14 { return }
+func (ms *MacStorm) OnFrame(f *decoder.Frame) {
+	if !f.Valid { return }
 
 	var srcMac [6]byte
-	copy(srcMac[:], data[6:12])
+	copy(srcMac[:], f.Src())
 
 	ms.mu.Lock()
 
@@ -124,18 +152,19 @@ func (ms *MacStorm) OnPacket(data []byte, length int, vlanID uint16) {
 		lastAlert, hasAlerted := ms.alertState[srcMac]
 		
 		// Usamos ms.cooldown configurado
-		if !hasAlerted || time.Since(lastAlert) > ms.cooldown {
+		if !hasAlerted || clock.Now().Sub(lastAlert) > ms.cooldown {
 
 			telemetry.EngineHits.WithLabelValues(ms.ifaceName, "MacStorm", "HostFlood").Inc()
 
-			ms.alertState[srcMac] = time.Now()
+			ms.alertState[srcMac] = clock.Now()
 			ms.mu.Unlock()
 
 			var dstMacSample [6]byte
-			copy(dstMacSample[:], data[0:6])
+			copy(dstMacSample[:], f.Dst())
 
 			currentIface := ms.ifaceName
-			go ms.sendAlert(currentIface, srcMac, dstMacSample, newCount, vlanID)
+			alerts.Add(1)
+			go ms.sendAlert(currentIface, srcMac, dstMacSample, newCount, ms.limitPPS, frameVLAN(f))
 			return
 		}
 	}
@@ -143,27 +172,26 @@ func (ms *MacStorm) OnPacket(data []byte, length int, vlanID uint16) {
 	ms.mu.Unlock()
 }
 
-func (ms *MacStorm) sendAlert(iface string, srcMac [6]byte, dstSample [6]byte, count uint64, vlanID uint16) {
-	location := "Native VLAN"
-	if vlanID != 0 {
-		location = fmt.Sprintf("VLAN %d", vlanID)
-	}
-
+func (ms *MacStorm) sendAlert(iface string, srcMac [6]byte, dstSample [6]byte, count, limit uint64, vlan vlanTag) {
+	defer alerts.Done()
 	targetInfo := utils.ClassifyMAC(dstSample[:])
 	floodType := "Unicast Flood"
 	if targetInfo.Name != "Unicast" {
 		floodType = fmt.Sprintf("%s (%s)", targetInfo.Name, targetInfo.Description)
 	}
 
-	srcStr := net.HardwareAddr(srcMac[:]).String()
-
-	msg := fmt.Sprintf("[MacStorm] 🌪️ HOST FLOODING DETECTED!\n"+
-		"    INTERFACE: %s\n"+
-		"    VLAN:      %s\n"+
-		"    HOST:      %s\n"+
-		"    RATE:      > %d pps (Current: %d)\n"+
-		"    PATTERN:   Flooding %s",
-		iface, location, srcStr, ms.limitPPS, count, floodType)
-
-	ms.notify.Alert(msg)
+	ev := vlan.apply(notifier.Event{
+		Interface:  iface,
+		Algorithm:  "MacStorm",
+		ThreatType: "HostFlood",
+		Severity:   notifier.SeverityWarning,
+		Title:      "🌪️ HOST FLOODING DETECTED!",
+		SrcMAC:     net.HardwareAddr(srcMac[:]).String(),
+		Rate:       count,
+		Threshold:  limit,
+	})
+	ev = ev.With("PATTERN", "Flooding "+floodType)
+	ev.Evidence = evidencePath(ms.recorder, "MacStorm-HostFlood")
+
+	ms.notify.Notify(ms.incidents.fire(ev.SrcMAC, ev))
 }
@@ -1,7 +1,7 @@
 package detector
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"net"
 	"sync"
@@ -9,11 +9,14 @@ import (
 
 	"github.com/mdlayher/packet"
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
 	"github.com/soyunomas/loopwarden/internal/notifier"
 	"github.com/soyunomas/loopwarden/internal/telemetry"
 )
 
 type McastPolicer struct {
+	lifecycle // Goroutines de fondo: Stop() las cancela y espera
+
 	cfg       *config.McastPolicerConfig
 	notify    *notifier.Notifier
 	ifaceName string // Identidad de la interfaz
@@ -25,6 +28,7 @@ type McastPolicer struct {
 	packetCount uint64
 	lastReset   time.Time
 	lastAlert   time.Time
+	incidents   *incidents // Ciclo de vida: repeticiones y evento "resolved"
 }
 
 func NewMcastPolicer(cfg *config.McastPolicerConfig, n *notifier.Notifier, ifaceName string) *McastPolicer {
@@ -32,28 +36,47 @@ func NewMcastPolicer(cfg *config.McastPolicerConfig, n *notifier.Notifier, iface
 		cfg:       cfg,
 		notify:    n,
 		ifaceName: ifaceName,
-		lastReset: time.Now(),
+		lastReset: clock.Now(),
+		incidents: newIncidents(n),
 	}
 }
 
 func (mp *McastPolicer) Name() string { return "McastPolicer" }
 
-func (mp *McastPolicer) Start(conn *packet.Conn, iface *net.Interface) error {
+func (mp *McastPolicer) incidentTracker() *incidents { return mp.incidents }
+
+func (mp *McastPolicer) Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error {
+	mp.begin(ctx)
+	mp.configure(iface.Name)
+	mp.every(1*time.Second, mp.incidents.sweep)
+	return nil
+}
+
+// reconfigure aplica una recarga de configuración (SIGHUP) sin perder estado.
+func (mp *McastPolicer) reconfigure(cfg *config.AlgorithmConfig) {
+	mp.mu.Lock()
+	mp.cfg = &cfg.McastPolicer
+	mp.configure(mp.ifaceName)
+	mp.mu.Unlock()
+}
+
+// configure calcula la configuración efectiva (global + override).
+func (mp *McastPolicer) configure(ifaceName string) {
 	// 1. Base Global
 	mp.maxPPS = mp.cfg.MaxPPS
 
 	// 2. Override
-	if override, ok := mp.cfg.Overrides[iface.Name]; ok {
+	if override, ok := mp.cfg.Overrides[ifaceName]; ok {
 		if override.MaxPPS > 0 {
 			mp.maxPPS = override.MaxPPS
-			log.Printf("🔧 [McastPolicer] Override applied for %s: MaxPPS = %d", iface.Name, mp.maxPPS)
+			log.Printf("🔧 [McastPolicer] Override applied for %s: MaxPPS = %d", ifaceName, mp.maxPPS)
 		}
 	}
-	return nil
 }
 
-func (mp *McastPolicer) OnPacket(data []byte, length int, vlanID uint16) {
-	if length < 6 { return }
+func (mp *McastPolicer) OnFrame(f *decoder.Frame) {
+	data := f.Data
+	if len(data) < 6 { return }
 
 	isMulticast := false
 	
@@ -69,10 +92,11 @@ func (mp *McastPolicer) OnPacket(data []byte, length int, vlanID uint16) {
 		mp.mu.Lock()
 		mp.packetCount++
 
-		now := time.Now()
+		now := clock.Now()
 		if now.Sub(mp.lastReset) >= time.Second {
 			// USO DE VARIABLE LOCAL
 			if mp.packetCount > mp.maxPPS {
+				mp.incidents.seen("MulticastStorm", "", mp.packetCount)
 				if now.Sub(mp.lastAlert) > 10*time.Second {
 					
 					// UPDATED: Added mp.ifaceName label
@@ -83,19 +107,19 @@ func (mp *McastPolicer) OnPacket(data []byte, length int, vlanID uint16) {
 					// CAPTURE VARIABLE FOR SAFETY
 					currentIface := mp.ifaceName
 
-					go func(iface string, count uint64, vlan uint16, limit uint64) {
-						vlanStr := "Native"
-						if vlan != 0 {
-							vlanStr = fmt.Sprintf("%d", vlan)
-						}
-						msg := fmt.Sprintf("[McastPolicer] 👻 MULTICAST STORM DETECTED!\n"+
-							"    INTERFACE: %s\n"+
-							"    VLAN:      %s\n"+
-							"    RATE:      %d pps (Limit: %d)\n"+
-							"    CAUSE:     Likely Ghost/FOG cloning or Video Streaming gone wrong.",
-							iface, vlanStr, count, limit)
-						mp.notify.Alert(msg)
-					}(currentIface, pps, vlanID, mp.maxPPS)
+					alerts.Add(1)
+					go func(iface string, count uint64, vlan vlanTag, limit uint64) {
+						defer alerts.Done()
+						mp.notify.Notify(mp.incidents.fire("", vlan.apply(notifier.Event{
+							Interface:  iface,
+							Algorithm:  "McastPolicer",
+							ThreatType: "MulticastStorm",
+							Severity:   notifier.SeverityWarning,
+							Title:      "👻 MULTICAST STORM DETECTED!",
+							Rate:       count,
+							Threshold:  limit,
+						}).With("CAUSE", "Likely Ghost/FOG cloning or Video Streaming gone wrong.")))
+					}(currentIface, pps, frameVLAN(f), mp.maxPPS)
 
 					mp.lastAlert = now
 				}
@@ -1,8 +1,7 @@
 package detector
 
 import (
-	"encoding/binary"
-	"fmt"
+	"context"
 	"log"
 	"net"
 	"strings"
@@ -11,6 +10,7 @@ import (
 
 	"github.com/mdlayher/packet"
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
 	"github.com/soyunomas/loopwarden/internal/notifier"
 	"github.com/soyunomas/loopwarden/internal/telemetry"
 )
@@ -23,12 +23,15 @@ const (
 )
 
 type RaGuard struct {
+	lifecycle // Goroutines de fondo: Stop() las cancela y espera
+
 	cfg         *config.RaGuardConfig
 	notify      *notifier.Notifier
 	ifaceName   string // Identidad de la interfaz
 	trustedMacs map[string]bool
 	mu          sync.Mutex
 	lastAlert   time.Time
+	incidents   *incidents // Ciclo de vida: repeticiones y evento "resolved"
 }
 
 func NewRaGuard(cfg *config.RaGuardConfig, n *notifier.Notifier, ifaceName string) *RaGuard {
@@ -37,12 +40,34 @@ func NewRaGuard(cfg *config.RaGuardConfig, n *notifier.Notifier, ifaceName strin
 		notify:      n,
 		ifaceName:   ifaceName,
 		trustedMacs: make(map[string]bool),
+		incidents:   newIncidents(n),
 	}
 }
 
 func (r *RaGuard) Name() string { return "RaGuard" }
 
-func (r *RaGuard) Start(conn *packet.Conn, iface *net.Interface) error {
+func (r *RaGuard) incidentTracker() *incidents { return r.incidents }
+
+func (r *RaGuard) Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error {
+	r.begin(ctx)
+	r.configure(iface.Name)
+	r.every(1*time.Second, r.incidents.sweep)
+	return nil
+}
+
+// reconfigure aplica una recarga de configuración (SIGHUP) sin perder estado.
+func (r *RaGuard) reconfigure(cfg *config.AlgorithmConfig) {
+	r.mu.Lock()
+	r.cfg = &cfg.RaGuard
+	r.configure(r.ifaceName)
+	r.mu.Unlock()
+}
+
+// configure calcula la configuración efectiva (global + override).
+func (r *RaGuard) configure(ifaceName string) {
+	// El map se reconstruye desde cero: una recarga puede retirar routers de confianza
+	r.trustedMacs = make(map[string]bool)
+
 	// 1. Recopilación de MACs (Global + Override)
 	var rawMacs []string
 	
@@ -50,9 +75,9 @@ func (r *RaGuard) Start(conn *packet.Conn, iface *net.Interface) error {
 	rawMacs = append(rawMacs, r.cfg.TrustedMacs...)
 	
 	// B. Override
-	if override, ok := r.cfg.Overrides[iface.Name]; ok {
+	if override, ok := r.cfg.Overrides[ifaceName]; ok {
 		log.Printf("🔧 [RaGuard] Applying overrides for interface %s (Extra MACs: %d)", 
-			iface.Name, len(override.TrustedMacs))
+			ifaceName, len(override.TrustedMacs))
 		rawMacs = append(rawMacs, override.TrustedMacs...)
 	}
 
@@ -67,75 +92,53 @@ func (r *RaGuard) Start(conn *packet.Conn, iface *net.Interface) error {
 		}
 	}
 	
-	log.Printf("✅ [RaGuard:%s] Active. Trusted Routers: %d", iface.Name, len(r.trustedMacs))
-	return nil
+	log.Printf("✅ [RaGuard:%s] Active. Trusted Routers: %d", ifaceName, len(r.trustedMacs))
 }
 
-func (r *RaGuard) OnPacket(data []byte, length int, vlanID uint16) {
-	ethOffset := 14
-	ethTypeOffset := 12
-	if vlanID != 0 {
-		ethOffset = 18
-		ethTypeOffset = 16
-	}
-
-	if length < ethOffset { return }
-
-	ethType := binary.BigEndian.Uint16(data[ethTypeOffset : ethTypeOffset+2])
-	
+func (r *RaGuard) OnFrame(f *decoder.Frame) {
 	// Usamos la constante compartida del paquete detector
-	if ethType != EtherTypeIPv6 { return }
-
-	// IPv6 Header is fixed 40 bytes
-	if length < ethOffset+40 { return }
-
-	// Next Header field is at offset 6 in IPv6 header
-	nextHeader := data[ethOffset+6]
-	
-	if nextHeader == ProtoICMPv6 {
-		icmpOffset := ethOffset + 40
-		if length < icmpOffset+1 { return }
-
-		icmpType := data[icmpOffset]
-		
-		if icmpType == ICMPv6TypeRA {
-			srcMacSlice := data[6:12]
-			srcMacStr := net.HardwareAddr(srcMacSlice).String() // Returns lower-case
-
-			if !r.trustedMacs[srcMacStr] {
-				r.mu.Lock()
-				now := time.Now()
-				if now.Sub(r.lastAlert) > RaAlertCooldown {
-					
-					// UPDATED: Added r.ifaceName label
-					telemetry.EngineHits.WithLabelValues(r.ifaceName, "RaGuard", "RogueRA").Inc()
-
-					// Get Src IPv6 (Offset 8 in IPv6 header)
-					srcIP := net.IP(data[ethOffset+8 : ethOffset+24])
-					ipStr := srcIP.String()
-
-					// CAPTURE VARIABLE FOR SAFETY
-					currentIface := r.ifaceName
-
-					go func(iface, mac, ip string, vlan uint16) {
-						vlanStr := "Native"
-						if vlan != 0 {
-							vlanStr = fmt.Sprintf("%d", vlan)
-						}
-						msg := fmt.Sprintf("[RaGuard] 📡 ROGUE IPv6 ROUTER ADVERTISEMENT!\n"+
-							"    INTERFACE: %s\n"+
-							"    VLAN:      %s\n"+
-							"    ROGUE MAC: %s\n"+
-							"    ROGUE IP:  %s\n"+
-							"    IMPACT:    Clients will lose connectivity (Man-in-the-Middle).",
-							iface, vlanStr, mac, ip)
-						r.notify.Alert(msg)
-					}(currentIface, srcMacStr, ipStr, vlanID)
-
-					r.lastAlert = now
-				}
-				r.mu.Unlock()
-			}
+	if f.Eth.EtherType != EtherTypeIPv6 { return }
+
+	// IPv6 (cabecera fija de 40 bytes) + Next Header ICMPv6: lo resuelve el decoder
+	icmpType, ok := f.ICMPv6Type()
+	if !ok || icmpType != ICMPv6TypeRA { return }
+
+	srcMacSlice := f.Src()
+	srcMacStr := net.HardwareAddr(srcMacSlice).String() // Returns lower-case
+
+	if !r.trustedMacs[srcMacStr] {
+		r.incidents.seen("RogueRA", srcMacStr, 0)
+
+		r.mu.Lock()
+		now := clock.Now()
+		if now.Sub(r.lastAlert) > RaAlertCooldown {
+			
+			// UPDATED: Added r.ifaceName label
+			telemetry.EngineHits.WithLabelValues(r.ifaceName, "RaGuard", "RogueRA").Inc()
+
+			ip, _ := f.IP()
+			srcIP := net.IP(ip.Src)
+			ipStr := srcIP.String()
+
+			// CAPTURE VARIABLE FOR SAFETY
+			currentIface := r.ifaceName
+
+			alerts.Add(1)
+			go func(iface, mac, ip string, vlan vlanTag) {
+				defer alerts.Done()
+				r.notify.Notify(r.incidents.fire(mac, vlan.apply(notifier.Event{
+					Interface:  iface,
+					Algorithm:  "RaGuard",
+					ThreatType: "RogueRA",
+					Severity:   notifier.SeverityCritical,
+					Title:      "📡 ROGUE IPv6 ROUTER ADVERTISEMENT!",
+					SrcMAC:     mac,
+					SrcIP:      ip,
+				}).With("IMPACT", "Clients will lose connectivity (Man-in-the-Middle).")))
+			}(currentIface, srcMacStr, ipStr, frameVLAN(f))
+
+			r.lastAlert = now
 		}
+		r.mu.Unlock()
 	}
 }
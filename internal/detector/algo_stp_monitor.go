@@ -0,0 +1,299 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mdlayher/packet"
+	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
+	"github.com/soyunomas/loopwarden/internal/notifier"
+	"github.com/soyunomas/loopwarden/internal/telemetry"
+)
+
+// stpInstance es el árbol observado en una VLAN (0 = CST/IEEE sin etiquetar).
+type stpInstance struct {
+	root     decoder.BridgeID
+	cost     uint32
+	bridge   decoder.BridgeID // Último puente designado que emitió BPDUs
+	version  uint8
+	tc       bool          // Flag TC de la última BPDU: se cuentan los flancos, no cada BPDU
+	hello    time.Duration // Hello Time anunciado por el root
+	lastSeen time.Time
+	lost     bool
+
+	tcCount     int
+	windowStart time.Time
+
+	lastRootAlert  time.Time
+	lastTCNAlert   time.Time
+	lastRogueAlert time.Time
+}
+
+// StpMonitor decodifica las BPDUs (STP/RSTP/MSTP y Cisco PVST+/SSTP) y sigue por VLAN el
+// root bridge, el coste al root y los cambios de topología. Los problemas de
+// spanning-tree casi siempre preceden a un bucle.
+type StpMonitor struct {
+	lifecycle // Goroutines de fondo: Stop() las cancela y espera
+
+	cfg       *config.StpMonitorConfig
+	notify    *notifier.Notifier
+	ifaceName string // Identidad de la interfaz
+
+	// Configuración Efectiva
+	trusted     map[string]bool // MAC base de los puentes autorizados (vacío = sin lista)
+	maxTCN      int
+	tcnWindow   time.Duration
+	bpduTimeout time.Duration
+	cooldown    time.Duration
+
+	mu        sync.Mutex
+	instances map[uint16]*stpInstance
+	incidents *incidents // Ciclo de vida: repeticiones y evento "resolved"
+}
+
+func NewStpMonitor(cfg *config.StpMonitorConfig, n *notifier.Notifier, ifaceName string) *StpMonitor {
+	return &StpMonitor{
+		cfg:       cfg,
+		notify:    n,
+		ifaceName: ifaceName,
+		trusted:   make(map[string]bool),
+		instances: make(map[uint16]*stpInstance),
+		incidents: newIncidents(n),
+	}
+}
+
+func (s *StpMonitor) Name() string { return "StpMonitor" }
+
+func (s *StpMonitor) incidentTracker() *incidents { return s.incidents }
+
+func (s *StpMonitor) Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error {
+	s.begin(ctx)
+	s.mu.Lock()
+	s.configure(iface.Name)
+	s.mu.Unlock()
+	s.every(1*time.Second, s.incidents.sweep)
+	s.every(1*time.Second, s.checkLoss)
+	return nil
+}
+
+// reconfigure aplica una recarga de configuración (SIGHUP) sin perder estado.
+func (s *StpMonitor) reconfigure(cfg *config.AlgorithmConfig) {
+	s.mu.Lock()
+	s.cfg = &cfg.StpMonitor
+	s.configure(s.ifaceName)
+	s.mu.Unlock()
+}
+
+// configure calcula la configuración efectiva (global + override). Requiere s.mu.
+func (s *StpMonitor) configure(ifaceName string) {
+	// La lista se reconstruye desde cero: una recarga puede retirar puentes autorizados
+	s.trusted = make(map[string]bool)
+	rawMacs := append([]string(nil), s.cfg.TrustedBridges...)
+
+	s.maxTCN = s.cfg.MaxTCN
+	s.tcnWindow = stpDuration(ifaceName, "TCNWindow", s.cfg.TCNWindow, time.Minute)
+	s.bpduTimeout = stpDuration(ifaceName, "BPDUTimeout", s.cfg.BPDUTimeout, 10*time.Second)
+	s.cooldown = stpDuration(ifaceName, "AlertCooldown", s.cfg.AlertCooldown, 30*time.Second)
+
+	if override, ok := s.cfg.Overrides[ifaceName]; ok {
+		log.Printf("🔧 [StpMonitor] Applying overrides for interface %s (Extra bridges: %d)",
+			ifaceName, len(override.TrustedBridges))
+		rawMacs = append(rawMacs, override.TrustedBridges...)
+		if override.MaxTCN > 0 {
+			s.maxTCN = override.MaxTCN
+		}
+		if override.BPDUTimeout != "" {
+			s.bpduTimeout = stpDuration(ifaceName, "Override BPDUTimeout", override.BPDUTimeout, s.bpduTimeout)
+		}
+	}
+	if s.maxTCN == 0 { s.maxTCN = 5 }
+
+	for _, m := range rawMacs {
+		mac, err := net.ParseMAC(strings.TrimSpace(m))
+		if err == nil {
+			s.trusted[mac.String()] = true
+		} else {
+			log.Printf("⚠️ [StpMonitor] Invalid trusted bridge MAC ignored: '%s'", m)
+		}
+	}
+
+	log.Printf("✅ [StpMonitor:%s] Active. Trusted bridges: %d, TCN limit: %d / %v, BPDU timeout: %v",
+		ifaceName, len(s.trusted), s.maxTCN, s.tcnWindow, s.bpduTimeout)
+}
+
+// stpDuration interpreta una duración de [algorithms.stp_monitor] con su default.
+func stpDuration(ifaceName, key, value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("⚠️ [StpMonitor:%s] Invalid %s '%s', defaulting to %v", ifaceName, key, value, def)
+		return def
+	}
+	return d
+}
+
+func (s *StpMonitor) OnFrame(f *decoder.Frame) {
+	bpdu, ok := f.BPDU()
+	if !ok {
+		return
+	}
+	vlan := f.Eth.OuterVLAN
+	if bpdu.VLAN != 0 {
+		vlan = bpdu.VLAN // PVST+: la VLAN de origen manda sobre la etiqueta
+	}
+	vlanStr := decoder.FormatVLAN(vlan, 0)
+	srcMac := net.HardwareAddr(f.Src()).String()
+	now := clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	inst, exists := s.instances[vlan]
+	if !exists {
+		inst = &stpInstance{windowStart: now}
+		s.instances[vlan] = inst
+	}
+	inst.lastSeen = now
+	inst.lost = false
+
+	// 1. Cambios de topología: cada TCN y cada flanco de subida del flag TC
+	topologyChange := bpdu.Type == decoder.BPDUTypeTCN || (bpdu.TCN() && !inst.tc)
+	if bpdu.Type != decoder.BPDUTypeTCN {
+		inst.tc = bpdu.Flags&decoder.BPDUFlagTC != 0
+	}
+	if topologyChange {
+		if now.Sub(inst.windowStart) >= s.tcnWindow {
+			inst.windowStart, inst.tcCount = now, 0
+		}
+		inst.tcCount++
+		if inst.tcCount > s.maxTCN {
+			s.incidents.seen("TCNStorm", vlanStr, 0)
+			if now.Sub(inst.lastTCNAlert) > s.cooldown {
+				inst.lastTCNAlert = now
+				telemetry.EngineHits.WithLabelValues(s.ifaceName, "StpMonitor", "TCNStorm").Inc()
+				ev := s.event("TCNStorm", notifier.SeverityWarning, "🌪️ SPANNING-TREE TCN STORM!", vlan, srcMac).
+					With("CHANGES", fmt.Sprintf("%d in %v (limit %d)", inst.tcCount, s.tcnWindow, s.maxTCN)).
+					With("ROOT BRIDGE", inst.root.String()).
+					With("ANALYSIS", "Ports keep flapping: MAC tables are flushed on every change. Look for the port that keeps going up/down.")
+				goNotify(s.notify, s.incidents.fire(vlanStr, ev))
+			}
+		}
+	}
+	if bpdu.Type == decoder.BPDUTypeTCN {
+		return // Una TCN no lleva información del árbol
+	}
+
+	// 2. Puentes no autorizados (emisor o root anunciado)
+	if len(s.trusted) > 0 {
+		rogue, role := bpdu.BridgeID, "Sending bridge"
+		if s.trusted[rogue.MAC().String()] {
+			rogue, role = bpdu.RootID, "Announced root bridge"
+		}
+		if !s.trusted[rogue.MAC().String()] {
+			subject := rogue.MAC().String()
+			s.incidents.seen("RogueBPDU", subject, 0)
+			if now.Sub(inst.lastRogueAlert) > s.cooldown {
+				inst.lastRogueAlert = now
+				telemetry.EngineHits.WithLabelValues(s.ifaceName, "StpMonitor", "RogueBPDU").Inc()
+				ev := s.event("RogueBPDU", notifier.SeverityCritical, "🚨 ROGUE BPDU DETECTED!", vlan, srcMac).
+					With("UNTRUSTED", fmt.Sprintf("%s %s", role, rogue)).
+					With("PROTOCOL", stpProtocol(bpdu)).
+					With("ACTION", "Unknown switch speaking spanning-tree. Enable BPDU Guard on access ports.")
+				goNotify(s.notify, s.incidents.fire(subject, ev))
+			}
+		}
+	}
+
+	// 3. Cambio de root bridge
+	if exists && inst.root != 0 && bpdu.RootID != inst.root {
+		s.incidents.seen("RootChange", vlanStr, 0)
+		if now.Sub(inst.lastRootAlert) > s.cooldown {
+			inst.lastRootAlert = now
+			telemetry.EngineHits.WithLabelValues(s.ifaceName, "StpMonitor", "RootChange").Inc()
+			ev := s.event("RootChange", notifier.SeverityCritical, "👑 SPANNING-TREE ROOT BRIDGE CHANGED!", vlan, srcMac).
+				With("OLD ROOT", fmt.Sprintf("%s (cost %d)", inst.root, inst.cost)).
+				With("NEW ROOT", fmt.Sprintf("%s (cost %d)", bpdu.RootID, bpdu.RootCost)).
+				With("DESIGNATED BRIDGE", bpdu.BridgeID.String()).
+				With("PROTOCOL", stpProtocol(bpdu)).
+				With("ANALYSIS", "The whole tree reconverges. If unplanned: new switch with a better priority, or the root lost its uplinks.")
+			goNotify(s.notify, s.incidents.fire(vlanStr, ev))
+		}
+	}
+	inst.root, inst.cost, inst.bridge, inst.version = bpdu.RootID, bpdu.RootCost, bpdu.BridgeID, bpdu.Version
+	inst.hello = time.Duration(bpdu.Hello) * time.Second / 256
+}
+
+// checkLoss alerta de las VLANs que dejan de recibir BPDUs: un puerto bloqueado que
+// deja de oír al designado pasa a forwarding (enlace unidireccional, bpdufilter...).
+// Se llama cada segundo.
+func (s *StpMonitor) checkLoss() {
+	now := clock.Now()
+	var alerts []notifier.Event
+
+	s.mu.Lock()
+	for vlan, inst := range s.instances {
+		if inst.root == 0 {
+			continue // Sólo TCNs: no hay puente designado cuyas BPDUs esperar
+		}
+		timeout := s.bpduTimeout
+		if inst.hello*3 > timeout {
+			timeout = inst.hello * 3
+		}
+		silent := now.Sub(inst.lastSeen)
+		if silent < timeout {
+			continue
+		}
+		vlanStr := decoder.FormatVLAN(vlan, 0)
+		s.incidents.seen("BPDULoss", vlanStr, 0) // Mantiene el incidente mientras sigan ausentes
+		if inst.lost {
+			continue
+		}
+		inst.lost = true
+		telemetry.EngineHits.WithLabelValues(s.ifaceName, "StpMonitor", "BPDULoss").Inc()
+		ev := s.event("BPDULoss", notifier.SeverityWarning, "🔇 SPANNING-TREE BPDUs LOST!", vlan, "").
+			With("SILENT FOR", silent.Truncate(time.Second).String()).
+			With("LAST ROOT", fmt.Sprintf("%s (cost %d)", inst.root, inst.cost)).
+			With("LAST BRIDGE", inst.bridge.String()).
+			With("ANALYSIS", "BPDUs stopped on a port where they were seen: unidirectional link, bpdufilter or a switch replaced by an unmanaged one. Loop risk.")
+		alerts = append(alerts, s.incidents.fire(vlanStr, ev))
+	}
+	s.mu.Unlock()
+
+	for _, ev := range alerts {
+		s.notify.Notify(ev)
+	}
+}
+
+func (s *StpMonitor) event(threat string, sev notifier.Severity, title string, vlan uint16, srcMac string) notifier.Event {
+	return vlanTag{outer: vlan}.apply(notifier.Event{
+		Interface:  s.ifaceName,
+		Algorithm:  "StpMonitor",
+		ThreatType: threat,
+		Severity:   sev,
+		Title:      title,
+		SrcMAC:     srcMac,
+	})
+}
+
+// stpProtocol nombra la variante de la BPDU para las alertas.
+func stpProtocol(b decoder.BPDU) string {
+	name := "STP"
+	switch b.Version {
+	case 2:
+		name = "RSTP"
+	case 3:
+		name = "MSTP"
+	}
+	if b.PVST {
+		name = "PVST+ (" + name + ")"
+	}
+	return name
+}
@@ -0,0 +1,119 @@
+package detector
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock abstrae la fuente de tiempo de los algoritmos.
+// En producción es el reloj del sistema; en modo replay es un reloj virtual
+// guiado por los timestamps de la captura, de forma que las ventanas por segundo
+// se comportan igual que en vivo aunque el fichero se procese a máxima velocidad.
+type Clock interface {
+	Now() time.Time
+	// Every ejecuta fn periódicamente cada d (sustituto de los time.Ticker internos)
+	// hasta que se cancela ctx. Si la tarea necesita una goroutine se registra en wg,
+	// de forma que el llamante pueda esperar a que termine.
+	Every(ctx context.Context, wg *sync.WaitGroup, d time.Duration, fn func())
+}
+
+// clock es el reloj compartido por todos los algoritmos del proceso.
+var clock Clock = systemClock{}
+
+// SetClock sustituye el reloj de los algoritmos.
+// Debe llamarse antes de NewEngine: los constructores ya toman referencias temporales.
+func SetClock(c Clock) {
+	clock = c
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) Every(ctx context.Context, wg *sync.WaitGroup, d time.Duration, fn func()) {
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(d)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fn()
+			}
+		}
+	}()
+}
+
+// ReplayClock es un reloj virtual que sólo avanza cuando se le indica.
+// Las tareas periódicas se ejecutan de forma síncrona dentro de Advance,
+// lo que hace el replay determinista (mismo fichero => mismas alertas).
+type ReplayClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	tasks []*replayTask
+}
+
+type replayTask struct {
+	ctx   context.Context
+	every time.Duration
+	next  time.Time
+	fn    func()
+}
+
+func NewReplayClock(start time.Time) *ReplayClock {
+	return &ReplayClock{now: start}
+}
+
+func (c *ReplayClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Every registra la tarea (sin goroutine: se ejecuta dentro de Advance).
+func (c *ReplayClock) Every(ctx context.Context, wg *sync.WaitGroup, d time.Duration, fn func()) {
+	c.mu.Lock()
+	c.tasks = append(c.tasks, &replayTask{ctx: ctx, every: d, next: c.now.Add(d), fn: fn})
+	c.mu.Unlock()
+}
+
+// Advance mueve el reloj hasta t y dispara las tareas vencidas.
+// Los saltos hacia atrás (capturas mezcladas sin ordenar) se ignoran.
+// Igual que time.Ticker, los ticks perdidos en un hueco largo se agrupan en uno solo.
+func (c *ReplayClock) Advance(t time.Time) {
+	c.mu.Lock()
+	if !t.After(c.now) {
+		c.mu.Unlock()
+		return
+	}
+	c.now = t
+
+	var due []func()
+	live := c.tasks[:0]
+	for _, task := range c.tasks {
+		// Las tareas de algoritmos parados se descartan
+		if task.ctx.Err() != nil {
+			continue
+		}
+		live = append(live, task)
+		if task.next.After(t) {
+			continue
+		}
+		due = append(due, task.fn)
+		task.next = task.next.Add(task.every)
+		if !task.next.After(t) {
+			task.next = t.Add(task.every)
+		}
+	}
+	c.tasks = live
+	c.mu.Unlock()
+
+	// Fuera del lock: las tareas consultan Now()
+	for _, fn := range due {
+		fn()
+	}
+}
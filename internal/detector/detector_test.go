@@ -2,12 +2,19 @@ package detector
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"encoding/json"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
 	"github.com/soyunomas/loopwarden/internal/notifier"
 )
 
@@ -17,6 +24,13 @@ func mockNotifier() *notifier.Notifier {
 	return notifier.NewNotifier(cfg, "TEST_SENSOR")
 }
 
+// frameOf decodifica la trama igual que Engine.DispatchPacket
+func frameOf(data []byte, length int, vlanID uint16) *decoder.Frame {
+	f := &decoder.Frame{}
+	f.Reset(data[:length], vlanID)
+	return f
+}
+
 // =============================================================================
 //  TEST 1: EtherFuse (Detección de Duplicados - O(1) Check)
 // =============================================================================
@@ -35,13 +49,14 @@ func TestEtherFuse_Detection(t *testing.T) {
 	ef := NewEtherFuse(cfg, mockNotifier(), "test0")
 	
 	dummyIface := &net.Interface{Name: "eth0"}
-	ef.Start(nil, dummyIface)
+	ef.Start(context.Background(), nil, dummyIface)
+	defer ef.Stop()
 
 	packet := []byte("PAYLOAD_TEST")
 	expectedHash := hashBody(packet)
 
 	// 1. Primer paquete: Se registra en la tabla con count=1
-	ef.OnPacket(packet, len(packet), 0)
+	ef.OnFrame(frameOf(packet, len(packet), 0))
 	
 	ef.mu.Lock()
 	count := ef.lookupTable[expectedHash]
@@ -53,7 +68,7 @@ func TestEtherFuse_Detection(t *testing.T) {
 
 	// 2. Inyectamos hasta llegar al umbral
 	for i := 0; i < 4; i++ {
-		ef.OnPacket(packet, len(packet), 0)
+		ef.OnFrame(frameOf(packet, len(packet), 0))
 	}
 	
 	ef.mu.Lock()
@@ -65,7 +80,7 @@ func TestEtherFuse_Detection(t *testing.T) {
 	}
 
 	// 3. Trigger de Alerta (packet #6) -> Reset
-	ef.OnPacket(packet, len(packet), 0)
+	ef.OnFrame(frameOf(packet, len(packet), 0))
 
 	ef.mu.Lock()
 	count = ef.lookupTable[expectedHash]
@@ -91,7 +106,8 @@ func TestMacStorm_Counter(t *testing.T) {
 	ms := NewMacStorm(cfg, mockNotifier(), "test0")
 	
 	dummyIface := &net.Interface{Name: "eth0"}
-	ms.Start(nil, dummyIface)
+	ms.Start(context.Background(), nil, dummyIface)
+	defer ms.Stop()
 
 	srcMac := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
 	packet := make([]byte, 14)
@@ -99,7 +115,7 @@ func TestMacStorm_Counter(t *testing.T) {
 
 	// Inyectamos 150 paquetes (superando el max de 100)
 	for i := 0; i < 150; i++ {
-		ms.OnPacket(packet, 14, 0)
+		ms.OnFrame(frameOf(packet, 14, 0))
 	}
 
 	var key [6]byte
@@ -148,7 +164,7 @@ func TestActiveProbe_VlanOffset(t *testing.T) {
 	packetNative = append(packetNative, []byte("MAGIC|test_iface")...)                          
 
 	ap.lastAlert = time.Time{}
-	ap.OnPacket(packetNative, len(packetNative), 0)
+	ap.OnFrame(frameOf(packetNative, len(packetNative), 0))
 
 	ap.mu.Lock()
 	if ap.lastAlert.IsZero() {
@@ -173,7 +189,8 @@ func TestFlapGuard_Flapping(t *testing.T) {
 	fg := NewFlapGuard(cfg, mockNotifier(), "test0")
 	
 	dummyIface := &net.Interface{Name: "eth0"}
-	fg.Start(nil, dummyIface)
+	fg.Start(context.Background(), nil, dummyIface)
+	defer fg.Stop()
 
 	srcMac := []byte{0xCA, 0xFE, 0xBA, 0xBE, 0x00, 0x01}
 	
@@ -181,13 +198,13 @@ func TestFlapGuard_Flapping(t *testing.T) {
 	copy(packet[6:12], srcMac)
 
 	// Salto 1
-	fg.OnPacket(packet, 14, 10)
+	fg.OnFrame(frameOf(packet, 14, 10))
 	// Salto 2
-	fg.OnPacket(packet, 14, 20)
+	fg.OnFrame(frameOf(packet, 14, 20))
 	// Salto 3
-	fg.OnPacket(packet, 14, 10)
+	fg.OnFrame(frameOf(packet, 14, 10))
 	// Salto 4 (Trigger)
-	fg.OnPacket(packet, 14, 20)
+	fg.OnFrame(frameOf(packet, 14, 20))
 
 	var key [6]byte
 	copy(key[:], srcMac)
@@ -221,14 +238,15 @@ func TestArpWatchdog_ParserAndLimit(t *testing.T) {
 	aw := NewArpWatchdog(cfg, mockNotifier(), "test0")
 	
 	dummyIface := &net.Interface{Name: "eth0"}
-	aw.Start(nil, dummyIface)
+	aw.Start(context.Background(), nil, dummyIface)
+	defer aw.Stop()
 
 	ethPacket := make([]byte, 14+28)
 	binary.BigEndian.PutUint16(ethPacket[12:14], 0x0806) // EtherType ARP
 	binary.BigEndian.PutUint16(ethPacket[20:22], 1) // OpCode Request
 
 	for i := 0; i < int(maxPPS)+5; i++ {
-		aw.OnPacket(ethPacket, len(ethPacket), 0)
+		aw.OnFrame(frameOf(ethPacket, len(ethPacket), 0))
 	}
 
 	aw.mu.Lock()
@@ -243,7 +261,7 @@ func TestArpWatchdog_ParserAndLimit(t *testing.T) {
 	}
 
 	binary.BigEndian.PutUint16(ethPacket[20:22], 2) // Reply
-	aw.OnPacket(ethPacket, len(ethPacket), 0)
+	aw.OnFrame(frameOf(ethPacket, len(ethPacket), 0))
 
 	aw.mu.Lock()
 	var countAfterReply uint64
@@ -257,11 +275,376 @@ func TestArpWatchdog_ParserAndLimit(t *testing.T) {
 	}
 }
 
+// =============================================================================
+//  TEST 6: ReplayClock (Ventanas guiadas por timestamps de captura)
+// =============================================================================
+
+func TestReplayClock_MacStormWindow(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	clk := NewReplayClock(start)
+	SetClock(clk)
+	defer SetClock(systemClock{})
+
+	cfg := &config.MacStormConfig{
+		Enabled:      true,
+		MaxPPSPerMac: 1000,
+		Overrides:    make(map[string]config.MacStormOverride),
+	}
+	ms := NewMacStorm(cfg, mockNotifier(), "replay0")
+	ms.Start(context.Background(), nil, &net.Interface{Name: "replay0"})
+	defer ms.Stop()
+
+	srcMac := []byte{0x02, 0x00, 0x00, 0x00, 0x00, 0x01}
+	packet := make([]byte, 14)
+	copy(packet[6:12], srcMac)
+	var key [6]byte
+	copy(key[:], srcMac)
+
+	// 600 tramas en el primer segundo virtual y 600 en el siguiente: nunca > 1000 pps
+	for sec := 0; sec < 2; sec++ {
+		for i := 0; i < 600; i++ {
+			clk.Advance(start.Add(time.Duration(sec)*time.Second + time.Duration(i)*time.Millisecond))
+			ms.OnFrame(frameOf(packet, 14, 0))
+		}
+	}
+
+	ms.mu.Lock()
+	count := ms.counters[key]
+	_, alerted := ms.alertState[key]
+	ms.mu.Unlock()
+
+	if count != 600 {
+		t.Errorf("La ventana virtual debería haberse reiniciado: esperaba 600, obtuve %d", count)
+	}
+	if alerted {
+		t.Error("MacStorm no debería alertar: ningún segundo virtual supera el límite")
+	}
+}
+
+// =============================================================================
+//  TEST 7: Reload (SIGHUP) en caliente sobre un grupo de workers
+// =============================================================================
+
+func TestReload_AppliesWithoutLosingState(t *testing.T) {
+	// Reloj virtual: la ventana de 1s de MacStorm no debe vaciar los contadores durante el test
+	SetClock(NewReplayClock(time.Unix(1700000000, 0)))
+	defer SetClock(systemClock{})
+
+	cfg := &config.AlgorithmConfig{
+		MacStorm:   config.MacStormConfig{Enabled: true, MaxPPSPerMac: 1000},
+		DhcpHunter: config.DhcpHunterConfig{Enabled: true, TrustedMacs: []string{"00:11:22:33:44:55"}},
+	}
+	engines := NewEngineGroup(cfg, mockNotifier(), "test0", 2)
+	dummyIface := &net.Interface{Name: "test0"}
+	for _, e := range engines {
+		e.StartAll(context.Background(), nil, dummyIface)
+		defer e.StopAll()
+	}
+
+	srcMac := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+	packet := make([]byte, 14)
+	copy(packet[6:12], srcMac)
+	for i := 0; i < 10; i++ {
+		engines[1].DispatchPacket(packet, 14, 0)
+	}
+
+	next := &config.AlgorithmConfig{
+		MacStorm: config.MacStormConfig{
+			Enabled:      true,
+			MaxPPSPerMac: 1000,
+			Overrides:    map[string]config.MacStormOverride{"test0": {MaxPPSPerMac: 50}},
+		},
+		DhcpHunter: config.DhcpHunterConfig{Enabled: true},
+	}
+	Reload(engines, next)
+
+	for w, e := range engines {
+		ms := e.algorithms[0].(*MacStorm)
+		if ms.limitPPS != 50 {
+			t.Errorf("Worker %d: esperaba MaxPPS 50 tras la recarga, obtuve %d", w, ms.limitPPS)
+		}
+	}
+
+	var key [6]byte
+	copy(key[:], srcMac)
+	if count := engines[1].algorithms[0].(*MacStorm).counters[key]; count != 10 {
+		t.Errorf("La recarga no debe perder los contadores: esperaba 10, obtuve %d", count)
+	}
+
+	// DhcpHunter es compartido: la MAC retirada deja de ser de confianza
+	if d := engines[0].algorithms[1].(*DhcpHunter); len(d.trustedMacs) != 0 {
+		t.Errorf("Esperaba 0 MACs de confianza tras la recarga, obtuve %d", len(d.trustedMacs))
+	}
+}
+
+// =============================================================================
+//  TEST 8: Ciclo de vida (sin goroutines huérfanas tras el apagado)
+// =============================================================================
+
+// allAlgorithms activa los 10 motores con la configuración mínima.
+func allAlgorithms() *config.AlgorithmConfig {
+	return &config.AlgorithmConfig{
+		EtherFuse:    config.EtherFuseConfig{Enabled: true, HistorySize: 64},
+		ActiveProbe:  config.ActiveProbeConfig{Enabled: true, Ethertype: 0xFFFF},
+		MacStorm:     config.MacStormConfig{Enabled: true},
+		FlapGuard:    config.FlapGuardConfig{Enabled: true},
+		ArpWatch:     config.ArpWatchConfig{Enabled: true},
+		DhcpHunter:   config.DhcpHunterConfig{Enabled: true},
+		FlowPanic:    config.FlowPanicConfig{Enabled: true},
+		RaGuard:      config.RaGuardConfig{Enabled: true},
+		McastPolicer: config.McastPolicerConfig{Enabled: true},
+		StpMonitor:   config.StpMonitorConfig{Enabled: true},
+	}
+}
+
+// waitGoroutines espera a que el número de goroutines vuelva a base.
+func waitGoroutines(t *testing.T, base int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > base {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			n := runtime.Stack(buf, true)
+			t.Fatalf("Goroutines filtradas: %d (esperaba %d)\n%s", runtime.NumGoroutine(), base, buf[:n])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestEngine_StopAllNoLeaks(t *testing.T) {
+	notify := mockNotifier()
+	dummyIface := &net.Interface{Name: "test0", HardwareAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, 1}}
+	base := runtime.NumGoroutine()
+
+	engines := NewEngineGroup(allAlgorithms(), notify, "test0", 3)
+	for _, e := range engines {
+		e.StartAll(context.Background(), nil, dummyIface)
+	}
+
+	// MacStorm (2), FlapGuard (1) y ArpWatchdog (1) por worker
+	if got := runtime.NumGoroutine() - base; got < 12 {
+		t.Fatalf("Esperaba al menos 12 tareas de fondo, hay %d", got)
+	}
+
+	for _, e := range engines {
+		e.StopAll()
+	}
+	waitGoroutines(t, base)
+
+	// Stop es idempotente
+	for _, e := range engines {
+		e.StopAll()
+	}
+}
+
+func TestEngine_ContextCancelNoLeaks(t *testing.T) {
+	notify := mockNotifier()
+	dummyIface := &net.Interface{Name: "test0", HardwareAddr: net.HardwareAddr{0x02, 0, 0, 0, 0, 1}}
+	base := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e := NewEngine(allAlgorithms(), notify, "test0")
+	e.StartAll(ctx, nil, dummyIface)
+
+	cancel()
+	waitGoroutines(t, base)
+	e.StopAll() // No debe bloquear con las tareas ya terminadas
+}
+
+func TestReplayClock_StoppedTasksDropped(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	clk := NewReplayClock(start)
+
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	runs := 0
+	clk.Every(ctx, &wg, time.Second, func() { runs++ })
+
+	clk.Advance(start.Add(1 * time.Second))
+	cancel()
+	clk.Advance(start.Add(2 * time.Second))
+
+	if runs != 1 {
+		t.Errorf("La tarea no debe ejecutarse tras cancelar su contexto: %d ejecuciones", runs)
+	}
+	if len(clk.tasks) != 0 {
+		t.Errorf("La tarea cancelada debería haberse retirado del reloj (%d pendientes)", len(clk.tasks))
+	}
+	wg.Wait() // El reloj virtual no lanza goroutines
+}
+
+// =============================================================================
+//  TEST 9: Ciclo de vida de las alertas (firing -> resolved tras hold_down)
+// =============================================================================
+
+// captureNotifier entrega por webhook a un servidor local y devuelve los eventos recibidos.
+func captureNotifier(t *testing.T) (*notifier.Notifier, func() []notifier.Event) {
+	t.Helper()
+	var mu sync.Mutex
+	var events []notifier.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct{ Event notifier.Event }
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		events = append(events, payload.Event)
+		mu.Unlock()
+	}))
+	t.Cleanup(srv.Close)
+
+	n := notifier.NewNotifier(&config.AlertsConfig{
+		Webhook: config.WebhookConfig{Enabled: true, URL: srv.URL},
+		Spool:   config.SpoolConfig{Directory: t.TempDir()},
+	}, "TEST_SENSOR")
+	return n, func() []notifier.Event {
+		n.Close(5 * time.Second)
+		mu.Lock()
+		defer mu.Unlock()
+		return events
+	}
+}
+
+func TestIncidents_ResolvedAfterHoldDown(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	clk := NewReplayClock(start)
+	SetClock(clk)
+	defer SetClock(systemClock{})
+
+	n, collect := captureNotifier(t)
+	tr := newIncidents(n)
+	tr.setHoldDown(30 * time.Second)
+
+	alert := notifier.Event{
+		Interface:  "test0",
+		Algorithm:  "McastPolicer",
+		ThreatType: "MulticastStorm",
+		Title:      "👻 MULTICAST STORM DETECTED!",
+		Rate:       5000,
+	}
+
+	// Segundo 0: detección con alerta; segundos 1-2: detecciones silenciadas por el cooldown
+	tr.seen("MulticastStorm", "", 5000)
+	n.Notify(tr.fire("", alert))
+	for i, pps := range []uint64{8000, 6000} {
+		clk.Advance(start.Add(time.Duration(i+1) * time.Second))
+		tr.seen("MulticastStorm", "", pps)
+	}
+
+	// La condición sigue activa dentro del hold_down
+	clk.Advance(start.Add(31 * time.Second))
+	tr.sweep()
+	if len(tr.active) != 1 {
+		t.Fatalf("El incidente no debe cerrarse antes de hold_down (%d activos)", len(tr.active))
+	}
+
+	clk.Advance(start.Add(32 * time.Second))
+	tr.sweep()
+	tr.sweep() // Un segundo barrido no debe repetir el resolved
+
+	events := collect()
+	if len(events) != 2 {
+		t.Fatalf("Esperaba firing + resolved, recibí %d eventos", len(events))
+	}
+	fired, resolved := events[0], events[1]
+	if fired.Status != notifier.StatusFiring || fired.Incident != "test0/McastPolicer/MulticastStorm" {
+		t.Errorf("Alerta inicial inesperada: status=%s incident=%s", fired.Status, fired.Incident)
+	}
+	if !resolved.Resolved() || resolved.Incident != fired.Incident {
+		t.Fatalf("El segundo evento debe resolver el mismo incidente: %+v", resolved)
+	}
+	if resolved.Duration != 2 || resolved.Peak != 8000 || resolved.Repeats != 2 {
+		t.Errorf("Resumen del incidente: duración=%v pico=%d repeticiones=%d (esperaba 2s, 8000, 2)",
+			resolved.Duration, resolved.Peak, resolved.Repeats)
+	}
+	if resolved.Title != "✅ RESOLVED: MULTICAST STORM DETECTED!" || !resolved.StartedAt.Equal(start) {
+		t.Errorf("Titular o inicio incorrectos: %q, %v", resolved.Title, resolved.StartedAt)
+	}
+}
+
+// =============================================================================
+//  TEST 10: StpMonitor (root bridge, TCN, puentes no autorizados, BPDUs perdidas)
+// =============================================================================
+
+// rstpFrame construye una RST BPDU IEEE sin etiquetar.
+func rstpFrame(root, bridge uint64, flags byte) []byte {
+	f := []byte{0x01, 0x80, 0xC2, 0, 0, 0, 0x02, 0, 0, 0, 0, 1, 0, 39, 0x42, 0x42, 0x03}
+	b := make([]byte, 36)
+	b[2], b[3], b[4] = 2, decoder.BPDUTypeRST, flags
+	binary.BigEndian.PutUint64(b[5:], root)
+	binary.BigEndian.PutUint64(b[17:], bridge)
+	binary.BigEndian.PutUint16(b[31:], 2*256)
+	return append(f, b...)
+}
+
+func TestStpMonitor_Alerts(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	clk := NewReplayClock(start)
+	SetClock(clk)
+	defer SetClock(systemClock{})
+
+	n, collect := captureNotifier(t)
+	s := NewStpMonitor(&config.StpMonitorConfig{
+		Enabled:        true,
+		TrustedBridges: []string{"00:00:00:00:00:0a", "00:00:00:00:00:0b"},
+		MaxTCN:         2,
+	}, n, "test0")
+	s.Start(context.Background(), nil, &net.Interface{Name: "test0"})
+	defer s.Stop()
+
+	const a, b, rogue = 0x8000_0000_0000_000A, 0x1000_0000_0000_000B, 0x8000_0000_0000_00CC
+	at := func(sec int, data []byte) {
+		clk.Advance(start.Add(time.Duration(sec) * time.Second))
+		s.OnFrame(frameOf(data, len(data), 0))
+	}
+
+	at(0, rstpFrame(a, a, 0))
+	at(2, rstpFrame(a, a, 0)) // Mismo root: nada que avisar
+	at(4, rstpFrame(b, b, 0)) // Root nuevo
+	// Tres cambios de topología (flancos de TC) con max_tcn = 2; las BPDUs con el flag
+	// todavía activo no cuentan como cambios nuevos
+	for i, flags := range []byte{1, 1, 0, 1, 0, 1} {
+		at(5+i, rstpFrame(b, b, flags))
+	}
+	at(12, rstpFrame(b, rogue, 0)) // Puente desconocido bajo un root autorizado
+
+	// Sin BPDUs durante más de bpdu_timeout (10s por defecto)
+	for sec := 13; sec <= 25; sec++ {
+		clk.Advance(start.Add(time.Duration(sec) * time.Second))
+	}
+
+	s.mu.Lock()
+	inst := s.instances[0]
+	if inst.tcCount != 3 || !inst.lost || uint64(inst.root) != b {
+		t.Errorf("Estado de la VLAN: tc=%d lost=%v root=%s", inst.tcCount, inst.lost, inst.root)
+	}
+	s.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond) // Las alertas de OnFrame salen en goroutines
+	got := map[string]notifier.Event{}
+	for _, ev := range collect() {
+		got[ev.ThreatType] = ev
+	}
+	if len(got) != 4 {
+		t.Fatalf("Esperaba RootChange, TCNStorm, RogueBPDU y BPDULoss: %v", got)
+	}
+	if ev := got["RootChange"]; ev.Severity != notifier.SeverityCritical || ev.Details[1].Value != "4096.00:00:00:00:00:0b (cost 0)" {
+		t.Errorf("RootChange: %+v", ev)
+	}
+	if ev := got["TCNStorm"]; ev.Details[0].Value != "3 in 1m0s (limit 2)" {
+		t.Errorf("TCNStorm: %+v", ev)
+	}
+	if ev := got["RogueBPDU"]; ev.Incident != "test0/StpMonitor/RogueBPDU/00:00:00:00:00:cc" {
+		t.Errorf("RogueBPDU: %+v", ev)
+	}
+	if ev := got["BPDULoss"]; ev.VLAN != "Native" {
+		t.Errorf("BPDULoss: %+v", ev)
+	}
+}
+
 // =============================================================================
 //  BENCHMARKS
 // =============================================================================
 
-func BenchmarkEtherFuse_OnPacket(b *testing.B) {
+func BenchmarkEtherFuse_OnFrame(b *testing.B) {
 	cfg := &config.EtherFuseConfig{
 		Enabled:        true,
 		HistorySize:    4096,
@@ -271,19 +654,23 @@ func BenchmarkEtherFuse_OnPacket(b *testing.B) {
 	}
 	// UPDATED
 	ef := NewEtherFuse(cfg, mockNotifier(), "bench")
-	ef.Start(nil, &net.Interface{Name: "bench"})
+	ef.Start(context.Background(), nil, &net.Interface{Name: "bench"})
+	defer ef.Stop()
 
 	packet := bytes.Repeat([]byte("A"), 64)
 	
+	var f decoder.Frame
+
 	b.ResetTimer()
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		ef.OnPacket(packet, 64, 0)
+		f.Reset(packet[:64], 0)
+		ef.OnFrame(&f)
 	}
 }
 
-func BenchmarkMacStorm_OnPacket(b *testing.B) {
+func BenchmarkMacStorm_OnFrame(b *testing.B) {
 	cfg := &config.MacStormConfig{
 		Enabled:      true,
 		MaxPPSPerMac: 50000000, 
@@ -291,38 +678,46 @@ func BenchmarkMacStorm_OnPacket(b *testing.B) {
 	}
 	// UPDATED
 	ms := NewMacStorm(cfg, mockNotifier(), "bench")
-	ms.Start(nil, &net.Interface{Name: "bench"})
+	ms.Start(context.Background(), nil, &net.Interface{Name: "bench"})
+	defer ms.Stop()
 	
 	packet := make([]byte, 64)
 	copy(packet[6:12], []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06})
 
+	var f decoder.Frame
+
 	b.ResetTimer()
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		ms.OnPacket(packet, 64, 10)
+		f.Reset(packet[:64], 10)
+		ms.OnFrame(&f)
 	}
 }
 
-func BenchmarkFlapGuard_OnPacket(b *testing.B) {
+func BenchmarkFlapGuard_OnFrame(b *testing.B) {
 	cfg := &config.FlapGuardConfig{Enabled: true, Threshold: 10000, Overrides: make(map[string]config.FlapGuardOverride)}
 	// UPDATED
 	fg := NewFlapGuard(cfg, mockNotifier(), "bench")
-	fg.Start(nil, &net.Interface{Name: "bench"})
+	fg.Start(context.Background(), nil, &net.Interface{Name: "bench"})
+	defer fg.Stop()
 	
 	packet := make([]byte, 64)
 	copy(packet[6:12], []byte{0xAA, 0xBB, 0xCC, 0x00, 0x00, 0x01})
 
+	var f decoder.Frame
+
 	b.ResetTimer()
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
 		vlan := uint16(i % 2)
-		fg.OnPacket(packet, 64, vlan)
+		f.Reset(packet[:64], vlan)
+		fg.OnFrame(&f)
 	}
 }
 
-func BenchmarkActiveProbe_OnPacket(b *testing.B) {
+func BenchmarkActiveProbe_OnFrame(b *testing.B) {
 	cfg := &config.ActiveProbeConfig{
 		Enabled:      true,
 		Ethertype:    0xFFFF,
@@ -347,15 +742,18 @@ func BenchmarkActiveProbe_OnPacket(b *testing.B) {
 	padding := make([]byte, 64-len(packet))
 	packet = append(packet, padding...)
 
+	var f decoder.Frame
+
 	b.ResetTimer()
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		ap.OnPacket(packet, len(packet), 0)
+		f.Reset(packet[:len(packet)], 0)
+		ap.OnFrame(&f)
 	}
 }
 
-func BenchmarkArpWatchdog_OnPacket(b *testing.B) {
+func BenchmarkArpWatchdog_OnFrame(b *testing.B) {
 	cfg := &config.ArpWatchConfig{
 		Enabled:   true,
 		MaxPPS:    100000000, 
@@ -363,16 +761,20 @@ func BenchmarkArpWatchdog_OnPacket(b *testing.B) {
 	}
 	// UPDATED
 	aw := NewArpWatchdog(cfg, mockNotifier(), "bench")
-	aw.Start(nil, &net.Interface{Name: "bench"})
+	aw.Start(context.Background(), nil, &net.Interface{Name: "bench"})
+	defer aw.Stop()
 
 	packet := make([]byte, 64)
 	binary.BigEndian.PutUint16(packet[12:14], 0x0806)
 	binary.BigEndian.PutUint16(packet[20:22], 1)
 
+	var f decoder.Frame
+
 	b.ResetTimer()
 	b.ReportAllocs()
 
 	for i := 0; i < b.N; i++ {
-		aw.OnPacket(packet, 64, 0)
+		f.Reset(packet[:64], 0)
+		aw.OnFrame(&f)
 	}
 }
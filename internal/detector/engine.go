@@ -1,26 +1,74 @@
 package detector
 
 import (
+	"context"
 	"log"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/mdlayher/packet"
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
+	"github.com/soyunomas/loopwarden/internal/forensics"
 	"github.com/soyunomas/loopwarden/internal/notifier"
+	"github.com/soyunomas/loopwarden/internal/telemetry"
 )
 
 type Algorithm interface {
 	Name() string
-	Start(conn *packet.Conn, iface *net.Interface) error
-	OnPacket(data []byte, length int, vlanID uint16)
+	// Start configura el algoritmo y lanza sus tareas de fondo, que terminan al
+	// cancelarse ctx o al llamar a Stop.
+	Start(ctx context.Context, conn *packet.Conn, iface *net.Interface) error
+	// Stop cancela las tareas de fondo y espera a que terminen.
+	Stop()
+	// OnFrame recibe la trama ya decodificada (ver decoder.Frame: no retener el puntero).
+	OnFrame(f *decoder.Frame)
+}
+
+// reconfigurable lo implementan los algoritmos que aceptan una recarga de configuración
+// en caliente (SIGHUP) conservando su estado aprendido.
+type reconfigurable interface {
+	reconfigure(cfg *config.AlgorithmConfig)
+}
+
+// resolvable lo implementan los algoritmos que siguen el ciclo de vida de sus alertas
+// (firing -> resolved).
+type resolvable interface {
+	incidentTracker() *incidents
+}
+
+// forensicAware lo implementan los algoritmos que adjuntan evidencia pcap a sus alertas.
+type forensicAware interface {
+	setRecorder(r *forensics.Recorder)
+}
+
+// alerts cuenta las goroutines de alerta en vuelo. Se lanzan al procesar la trama para no
+// frenar la captura; el replay espera a que terminen antes de imprimir el resumen.
+var alerts sync.WaitGroup
+
+// WaitAlerts espera a que terminen las alertas ya emitidas por los algoritmos.
+func WaitAlerts() { alerts.Wait() }
+
+// goNotify envía ev en segundo plano registrando la goroutine en alerts.
+func goNotify(n *notifier.Notifier, ev notifier.Event) {
+	alerts.Add(1)
+	go func() {
+		defer alerts.Done()
+		n.Notify(ev)
+	}()
 }
 
 type Engine struct {
 	algorithms []Algorithm
+	owned      []Algorithm // Los que arranca este Engine (en un grupo, los compartidos sólo el worker 0)
 	cfg        *config.AlgorithmConfig
 	mu         sync.RWMutex
 	ifaceName  string // Identidad del Engine
+	recorder   *forensics.Recorder
+
+	// frame se reutiliza en cada DispatchPacket (un único hilo de captura por Engine)
+	frame decoder.Frame
 }
 
 // NewEngine propaga ifaceName a todos los constructores
@@ -76,23 +124,178 @@ func NewEngine(cfg *config.AlgorithmConfig, notify *notifier.Notifier, ifaceName
 		e.algorithms = append(e.algorithms, NewMcastPolicer(&cfg.McastPolicer, notify, ifaceName))
 	}
 
+	// 10. StpMonitor
+	if cfg.StpMonitor.Enabled {
+		e.algorithms = append(e.algorithms, NewStpMonitor(&cfg.StpMonitor, notify, ifaceName))
+	}
+
+	e.owned = e.algorithms
+	e.applyHoldDown()
+
 	log.Printf("✅ [Engine:%s] Initialized with %d algorithms", ifaceName, len(e.algorithms))
 	return e
 }
 
-func (e *Engine) StartAll(conn *packet.Conn, iface *net.Interface) {
+// NewEngineGroup crea un Engine por worker de captura (PACKET_FANOUT con hash por MAC origen).
+//
+// Los algoritmos con estado por MAC (EtherFuse, MacStorm, FlapGuard, ArpWatchdog) se
+// instancian por worker: el hash garantiza que una MAC cae siempre en el mismo shard.
+// Los globales (ActiveProbe, DhcpHunter, FlowPanic, RaGuard, McastPolicer, StpMonitor) son
+// una única instancia compartida por todos los workers y sólo la arranca el worker 0.
+func NewEngineGroup(cfg *config.AlgorithmConfig, notify *notifier.Notifier, ifaceName string, workers int) []*Engine {
+	first := NewEngine(cfg, notify, ifaceName)
+	engines := []*Engine{first}
+
+	for w := 1; w < workers; w++ {
+		e := &Engine{
+			cfg:        cfg,
+			ifaceName:  ifaceName,
+			algorithms: make([]Algorithm, 0, len(first.algorithms)),
+		}
+		for _, algo := range first.algorithms {
+			var shard Algorithm
+			switch a := algo.(type) {
+			case *EtherFuse:
+				ef := NewEtherFuse(&cfg.EtherFuse, notify, ifaceName)
+				ef.global = a.global // Tormenta y cooldown se miden por interfaz
+				shard = ef
+			case *MacStorm:
+				shard = NewMacStorm(&cfg.MacStorm, notify, ifaceName)
+			case *FlapGuard:
+				shard = NewFlapGuard(&cfg.FlapGuard, notify, ifaceName)
+			case *ArpWatchdog:
+				shard = NewArpWatchdog(&cfg.ArpWatch, notify, ifaceName)
+			}
+
+			if shard != nil {
+				e.algorithms = append(e.algorithms, shard)
+				e.owned = append(e.owned, shard)
+			} else {
+				e.algorithms = append(e.algorithms, algo)
+			}
+		}
+		e.applyHoldDown()
+		engines = append(engines, e)
+	}
+
+	if workers > 1 {
+		log.Printf("✅ [Engine:%s] %d workers (per-MAC algorithms sharded, global ones shared)", ifaceName, workers)
+	}
+	return engines
+}
+
+// SetRecorder activa el ring forense de la interfaz: todas las tramas despachadas
+// se retienen en memoria y los algoritmos compatibles lo vuelcan a disco al alertar.
+func (e *Engine) SetRecorder(r *forensics.Recorder) {
+	e.recorder = r
+	for _, algo := range e.algorithms {
+		if fa, ok := algo.(forensicAware); ok {
+			fa.setRecorder(r)
+		}
+	}
+}
+
+// applyHoldDown propaga algorithms.hold_down a los seguidores de incidentes.
+func (e *Engine) applyHoldDown() {
+	holdDown := DefaultHoldDown
+	if e.cfg.HoldDown != "" {
+		d, err := time.ParseDuration(e.cfg.HoldDown)
+		if err != nil || d <= 0 {
+			log.Printf("⚠️ [Engine:%s] Invalid hold_down '%s', defaulting to %v", e.ifaceName, e.cfg.HoldDown, DefaultHoldDown)
+		} else {
+			holdDown = d
+		}
+	}
 	for _, algo := range e.algorithms {
-		if err := algo.Start(conn, iface); err != nil {
+		if r, ok := algo.(resolvable); ok {
+			r.incidentTracker().setHoldDown(holdDown)
+		}
+	}
+}
+
+func (e *Engine) StartAll(ctx context.Context, conn *packet.Conn, iface *net.Interface) {
+	for _, algo := range e.owned {
+		if err := algo.Start(ctx, conn, iface); err != nil {
 			log.Printf("❌ [%s] Error starting algorithm %s: %v", e.ifaceName, algo.Name(), err)
 		}
 	}
 }
 
+// StopAll para los algoritmos que arrancó este Engine y espera a todas sus goroutines.
+// Tras StopAll el Engine se puede descartar sin dejar tickers huérfanos.
+func (e *Engine) StopAll() {
+	for _, algo := range e.owned {
+		algo.Stop()
+	}
+}
+
+// Reload aplica una nueva configuración de algoritmos a todos los Engines de una interfaz.
+// El despacho de todos los workers se bloquea a la vez: el cambio es atómico y ninguna
+// trama se procesa con la configuración a medias. Los algoritmos compartidos se
+// reconfiguran una sola vez. El conjunto de algoritmos activos no cambia: activar o
+// desactivar uno requiere relanzar la pila.
+func Reload(engines []*Engine, cfg *config.AlgorithmConfig) {
+	for _, e := range engines {
+		e.mu.Lock()
+	}
+
+	done := make(map[Algorithm]bool)
+	for _, e := range engines {
+		e.cfg = cfg
+		for _, algo := range e.algorithms {
+			if done[algo] {
+				continue
+			}
+			done[algo] = true
+			if r, ok := algo.(reconfigurable); ok {
+				r.reconfigure(cfg)
+			}
+		}
+		e.applyHoldDown()
+	}
+
+	for _, e := range engines {
+		e.mu.Unlock()
+	}
+	log.Printf("♻️ [Engine:%s] Configuration reloaded (%d workers)", engines[0].ifaceName, len(engines))
+}
+
+// RawFrame es una trama pendiente de despachar en los backends por lotes (TPACKET_V3).
+// Data apunta a memoria del ring: sólo es válida durante DispatchBatch.
+type RawFrame struct {
+	Data []byte
+	VLAN uint16 // Tag retirado por el kernel, 0 si no hubo
+}
+
+// DispatchPacket decodifica la trama una sola vez y la reparte a todos los algoritmos.
+// vlanID es el tag retirado por el kernel (auxdata), 0 si la trama llega tal cual.
+// No es reentrante: cada Engine tiene un único lector.
 func (e *Engine) DispatchPacket(data []byte, length int, vlanID uint16) {
 	e.mu.RLock()
+	e.dispatch(data[:length], vlanID)
+	e.mu.RUnlock()
+}
+
+// DispatchBatch despacha un bloque completo del ring tomando el lock una sola vez.
+func (e *Engine) DispatchBatch(batch []RawFrame) {
+	e.mu.RLock()
+	for i := range batch {
+		e.dispatch(batch[i].Data, batch[i].VLAN)
+	}
+	e.mu.RUnlock()
+}
+
+func (e *Engine) dispatch(data []byte, vlanID uint16) {
+	if e.recorder != nil {
+		e.recorder.Record(clock.Now(), data)
+	}
+
+	f := &e.frame
+	f.Reset(data, vlanID)
+	telemetry.TrackPacket(e.ifaceName, f)
+
 	// Precepto #41: Mid-stack inlining optimization
 	for _, algo := range e.algorithms {
-		algo.OnPacket(data, length, vlanID)
+		algo.OnFrame(f)
 	}
-	e.mu.RUnlock()
 }
@@ -0,0 +1,21 @@
+package detector
+
+import (
+	"log"
+
+	"github.com/soyunomas/loopwarden/internal/forensics"
+)
+
+// evidencePath vuelca el ring forense y devuelve la ruta para Event.Evidence ("" si no hay).
+// Cold Path: se llama desde la goroutine de alerta, nunca desde OnFrame.
+func evidencePath(r *forensics.Recorder, reason string) string {
+	if r == nil {
+		return ""
+	}
+	path, err := r.Dump(reason)
+	if err != nil {
+		log.Printf("⚠️ [Forensics] Evidence dump failed (%s): %v", reason, err)
+		return ""
+	}
+	return path
+}
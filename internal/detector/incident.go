@@ -0,0 +1,170 @@
+package detector
+
+import (
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/soyunomas/loopwarden/internal/notifier"
+)
+
+// DefaultHoldDown es el tiempo sin detecciones tras el que una condición se da por resuelta.
+const DefaultHoldDown = 30 * time.Second
+
+// incident es una condición activa (un bucle, una tormenta, un host inundando...).
+type incident struct {
+	template notifier.Event // Última alerta enviada: base del evento "resolved"
+	fired    bool
+	start    time.Time
+	last     time.Time
+	peak     uint64
+	seen     uint64 // Detecciones (con o sin alerta)
+	alerts   uint64 // Alertas realmente enviadas
+}
+
+// incidents sigue las condiciones activas de un algoritmo para cerrar el ciclo de vida
+// de sus alertas: mientras la condición persiste, las repeticiones silenciadas por el
+// cooldown se cuentan; cuando deja de observarse durante hold_down se emite un único
+// evento "resolved" con la duración, el pico y las repeticiones suprimidas.
+//
+// Las claves las elige cada algoritmo: interfaz+MAC para los que van por host
+// (MacStorm, FlapGuard...), sólo el tipo de amenaza para los de interfaz (EtherFuse,
+// McastPolicer, FlowPanic...).
+type incidents struct {
+	notify *notifier.Notifier
+
+	mu       sync.Mutex
+	holdDown time.Duration
+	active   map[string]*incident
+}
+
+func newIncidents(n *notifier.Notifier) *incidents {
+	return &incidents{
+		notify:   n,
+		holdDown: DefaultHoldDown,
+		active:   make(map[string]*incident),
+	}
+}
+
+func (t *incidents) setHoldDown(d time.Duration) {
+	t.mu.Lock()
+	t.holdDown = d
+	t.mu.Unlock()
+}
+
+// get devuelve (o abre) el incidente de la clave. Requiere t.mu.
+func (t *incidents) get(key string, now time.Time) *incident {
+	inc, ok := t.active[key]
+	if !ok {
+		inc = &incident{start: now}
+		t.active[key] = inc
+	}
+	inc.last = now
+	return inc
+}
+
+// seen registra una detección de la condición, haya alerta o la silencie el cooldown.
+func (t *incidents) seen(threatType, subject string, rate uint64) {
+	now := clock.Now()
+	t.mu.Lock()
+	inc := t.get(threatType+"/"+subject, now)
+	inc.seen++
+	if rate > inc.peak {
+		inc.peak = rate
+	}
+	t.mu.Unlock()
+}
+
+// fire registra la alerta que se va a enviar y la completa con el estado del incidente
+// (inicio, pico, repeticiones suprimidas hasta ahora).
+func (t *incidents) fire(subject string, ev notifier.Event) notifier.Event {
+	now := clock.Now()
+	key := ev.ThreatType + "/" + subject
+
+	t.mu.Lock()
+	inc := t.get(key, now)
+	inc.alerts++
+	if ev.Rate > inc.peak {
+		inc.peak = ev.Rate
+	}
+
+	ev.Time = now
+	ev.Status = notifier.StatusFiring
+	ev.Incident = incidentID(ev, subject)
+	ev.StartedAt = inc.start
+	ev.Peak = inc.peak
+	ev.Repeats = inc.suppressed()
+
+	inc.template = ev
+	inc.fired = true
+	t.mu.Unlock()
+
+	return ev
+}
+
+// suppressed son las detecciones que no generaron alerta. Requiere t.mu.
+func (inc *incident) suppressed() uint64 {
+	if inc.seen > inc.alerts {
+		return inc.seen - inc.alerts
+	}
+	return 0
+}
+
+// sweep cierra los incidentes sin detecciones durante hold_down. Se llama cada segundo.
+func (t *incidents) sweep() {
+	now := clock.Now()
+	var resolved []notifier.Event
+
+	t.mu.Lock()
+	for key, inc := range t.active {
+		if now.Sub(inc.last) < t.holdDown {
+			continue
+		}
+		delete(t.active, key)
+		// Sin alerta enviada (todo silenciado por un cooldown previo) no hay nada que cerrar
+		if inc.fired {
+			resolved = append(resolved, inc.resolve(now))
+		}
+	}
+	t.mu.Unlock()
+
+	for _, ev := range resolved {
+		t.notify.Notify(ev)
+	}
+}
+
+// resolve construye el evento de cierre a partir de la última alerta.
+func (inc *incident) resolve(now time.Time) notifier.Event {
+	ev := inc.template
+	ev.Time = now
+	ev.Status = notifier.StatusResolved
+	ev.Title = "✅ RESOLVED: " + stripEmoji(ev.Title)
+	ev.Duration = inc.last.Sub(inc.start).Seconds()
+	ev.Peak = inc.peak
+	ev.Repeats = inc.suppressed()
+	ev.Rate = 0
+	ev.Details = nil
+	ev.Evidence = ""
+	return ev
+}
+
+// incidentID identifica el incidente entre alertas y su resuelto (y entre sensores).
+func incidentID(ev notifier.Event, subject string) string {
+	id := ev.Interface + "/" + ev.Algorithm + "/" + ev.ThreatType
+	if subject != "" {
+		id += "/" + subject
+	}
+	return id
+}
+
+// stripEmoji quita el pictograma inicial del titular ("🚨 LOOP DETECTED!" -> "LOOP DETECTED!").
+func stripEmoji(title string) string {
+	if r, _ := utf8.DecodeRuneInString(title); r < utf8.RuneSelf {
+		return title
+	}
+	if i := strings.IndexByte(title, ' '); i > 0 {
+		return title[i+1:]
+	}
+	return title
+}
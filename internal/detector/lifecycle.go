@@ -0,0 +1,45 @@
+package detector
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// lifecycle agrupa las goroutines de fondo de un algoritmo (tickers de ventana, limpieza,
+// sondas) para poder pararlas. Se embebe en cada algoritmo: aporta Stop() a la interfaz
+// Algorithm y permite destruir o relanzar un Engine sin dejar goroutines huérfanas.
+type lifecycle struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// begin prepara el contexto de las tareas de fondo. Se llama al principio de Start.
+func (l *lifecycle) begin(ctx context.Context) {
+	l.ctx, l.cancel = context.WithCancel(ctx)
+}
+
+// every ejecuta fn cada d (reloj de los algoritmos) hasta Stop o la cancelación del contexto.
+func (l *lifecycle) every(d time.Duration, fn func()) {
+	clock.Every(l.ctx, &l.wg, d, fn)
+}
+
+// spawn lanza una goroutine de fondo que debe terminar al cerrarse ctx.
+func (l *lifecycle) spawn(fn func(ctx context.Context)) {
+	ctx := l.ctx
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		fn(ctx)
+	}()
+}
+
+// Stop cancela las tareas de fondo y espera a que terminen. Es idempotente y
+// seguro aunque Start no se haya llamado.
+func (l *lifecycle) Stop() {
+	if l.cancel != nil {
+		l.cancel()
+	}
+	l.wg.Wait()
+}
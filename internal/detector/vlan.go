@@ -0,0 +1,20 @@
+package detector
+
+import (
+	"github.com/soyunomas/loopwarden/internal/decoder"
+	"github.com/soyunomas/loopwarden/internal/notifier"
+)
+
+// vlanTag son los IDs de VLAN de una trama; la goroutine de alerta se los lleva en
+// lugar del Frame, que se reutiliza.
+type vlanTag struct{ outer, inner uint16 }
+
+func frameVLAN(f *decoder.Frame) vlanTag { return vlanTag{f.Eth.OuterVLAN, f.Eth.InnerVLAN} }
+
+// apply fija la VLAN del evento: el texto que se muestra y los IDs con los que encajan
+// silencios y ventanas de mantenimiento.
+func (v vlanTag) apply(ev notifier.Event) notifier.Event {
+	ev.VLAN = decoder.FormatVLAN(v.outer, v.inner)
+	ev.OuterVLAN, ev.InnerVLAN = v.outer, v.inner
+	return ev
+}
@@ -0,0 +1,288 @@
+package forensics
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/pcap"
+	"github.com/soyunomas/loopwarden/internal/telemetry"
+)
+
+const filePrefix = "loopwarden_"
+
+type record struct {
+	ts  int64 // UnixNano
+	off int
+	len int
+}
+
+// Recorder mantiene en memoria las últimas tramas vistas en una interfaz
+// (acotado por tiempo y por bytes) y las vuelca a pcapng cuando un algoritmo alerta.
+//
+// OPTIMIZACIÓN: Arena circular preasignada. Record() no reserva memoria en régimen
+// estable: copia la trama en la arena y apunta su posición en una cola FIFO.
+type Recorder struct {
+	ifaceName string
+	snapLen   int
+
+	// --- Configuración Efectiva ---
+	dir      string
+	window   time.Duration
+	maxFiles int
+	maxDisk  int64
+	cooldown time.Duration
+
+	mu    sync.Mutex
+	arena []byte
+	head  int      // Próxima posición de escritura en la arena
+	recs  []record // Cola circular de metadatos
+	first int
+	count int
+
+	lastDump     time.Time
+	lastDumpPath string
+
+	diskMu sync.Mutex // Serializa escritura + rotación en disco
+}
+
+func NewRecorder(cfg *config.ForensicsConfig, ifaceName string, snapLen int) (*Recorder, error) {
+	r := &Recorder{
+		ifaceName: ifaceName,
+		snapLen:   snapLen,
+		dir:       cfg.Directory,
+		maxFiles:  cfg.MaxFiles,
+		maxDisk:   int64(cfg.MaxDiskMB) << 20,
+		recs:      make([]record, 1024),
+	}
+
+	window, err := time.ParseDuration(cfg.Window)
+	if err != nil {
+		log.Printf("⚠️ [Forensics:%s] Invalid Window '%s', defaulting to 10s", ifaceName, cfg.Window)
+		window = 10 * time.Second
+	}
+	r.window = window
+
+	cooldown, err := time.ParseDuration(cfg.DumpCooldown)
+	if err != nil {
+		log.Printf("⚠️ [Forensics:%s] Invalid DumpCooldown '%s', defaulting to 30s", ifaceName, cfg.DumpCooldown)
+		cooldown = 30 * time.Second
+	}
+	r.cooldown = cooldown
+
+	// Fallbacks de Seguridad
+	bufferMB := cfg.BufferMB
+	if bufferMB <= 0 { bufferMB = 16 }
+	if r.dir == "" { r.dir = "/var/lib/loopwarden/pcap" }
+	if r.window <= 0 { r.window = 10 * time.Second }
+	if r.maxFiles <= 0 { r.maxFiles = 50 }
+	if r.maxDisk <= 0 { r.maxDisk = 512 << 20 }
+	if r.snapLen <= 0 { r.snapLen = 2048 }
+
+	r.arena = make([]byte, bufferMB<<20)
+
+	if err := os.MkdirAll(r.dir, 0750); err != nil {
+		return nil, fmt.Errorf("forensics dir %s: %w", r.dir, err)
+	}
+
+	log.Printf("✅ [Forensics:%s] Ring active. Window: %v, Memory: %d MB, Disk: %d files / %d MB in %s",
+		ifaceName, r.window, bufferMB, r.maxFiles, r.maxDisk>>20, r.dir)
+	return r, nil
+}
+
+// Record copia la trama en el ring. Hot Path: se llama para cada paquete.
+func (r *Recorder) Record(ts time.Time, data []byte) {
+	n := len(data)
+	if n == 0 || n > len(r.arena) {
+		return
+	}
+	now := ts.UnixNano()
+
+	r.mu.Lock()
+
+	// 1. Expiración por tiempo
+	minTs := now - int64(r.window)
+	for r.count > 0 && r.recs[r.first].ts < minTs {
+		r.pop()
+	}
+
+	// 2. Hueco en la arena (si no cabe al final, damos la vuelta y desalojamos la cola vieja)
+	if r.head+n > len(r.arena) {
+		for r.count > 0 && r.recs[r.first].off >= r.head {
+			r.pop()
+		}
+		r.head = 0
+	}
+	for r.count > 0 && r.recs[r.first].off >= r.head && r.recs[r.first].off < r.head+n {
+		r.pop()
+	}
+
+	copy(r.arena[r.head:], data)
+	r.push(record{ts: now, off: r.head, len: n})
+	r.head += n
+
+	r.mu.Unlock()
+}
+
+func (r *Recorder) pop() {
+	r.first = (r.first + 1) % len(r.recs)
+	r.count--
+}
+
+func (r *Recorder) push(rec record) {
+	if r.count == len(r.recs) {
+		grown := make([]record, len(r.recs)*2)
+		for i := 0; i < r.count; i++ {
+			grown[i] = r.recs[(r.first+i)%len(r.recs)]
+		}
+		r.recs = grown
+		r.first = 0
+	}
+	r.recs[(r.first+r.count)%len(r.recs)] = rec
+	r.count++
+}
+
+// Dump escribe el contenido actual del ring en un pcapng y devuelve su ruta.
+// Varias alertas del mismo incidente (dentro de dump_cooldown) comparten fichero.
+// Debe llamarse fuera del Hot Path (goroutine de alerta).
+func (r *Recorder) Dump(reason string) (string, error) {
+	r.mu.Lock()
+	if r.count == 0 {
+		r.mu.Unlock()
+		return "", fmt.Errorf("ring is empty")
+	}
+
+	// Referencia temporal = última trama (coincide con la captura también en replay)
+	newest := time.Unix(0, r.recs[(r.first+r.count-1)%len(r.recs)].ts)
+	if r.lastDumpPath != "" && newest.Sub(r.lastDump) < r.cooldown {
+		path := r.lastDumpPath
+		r.mu.Unlock()
+		return path, nil
+	}
+
+	// Snapshot bajo lock (sólo memcpy); el disco se toca fuera
+	snapshot := make([]byte, 0, r.usedBytes())
+	recs := make([]record, r.count)
+	for i := 0; i < r.count; i++ {
+		rec := r.recs[(r.first+i)%len(r.recs)]
+		recs[i] = record{ts: rec.ts, off: len(snapshot), len: rec.len}
+		snapshot = append(snapshot, r.arena[rec.off:rec.off+rec.len]...)
+	}
+
+	name := fmt.Sprintf("%s%s_%s_%s.pcapng", filePrefix, sanitize(r.ifaceName),
+		newest.UTC().Format("20060102T150405.000Z"), sanitize(reason))
+	path := filepath.Join(r.dir, name)
+
+	r.lastDump = newest
+	r.lastDumpPath = path
+	r.mu.Unlock()
+
+	r.diskMu.Lock()
+	defer r.diskMu.Unlock()
+
+	if err := r.writeFile(path, snapshot, recs); err != nil {
+		os.Remove(path)
+		r.mu.Lock()
+		r.lastDumpPath = ""
+		r.mu.Unlock()
+		return "", err
+	}
+
+	telemetry.ForensicDumps.WithLabelValues(r.ifaceName).Inc()
+	r.enforceLimits(path)
+	return path, nil
+}
+
+func (r *Recorder) usedBytes() int {
+	total := 0
+	for i := 0; i < r.count; i++ {
+		total += r.recs[(r.first+i)%len(r.recs)].len
+	}
+	return total
+}
+
+func (r *Recorder) writeFile(path string, data []byte, recs []record) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	bw := bufio.NewWriterSize(f, 1<<16)
+	pw, err := pcap.NewWriter(bw, r.ifaceName, r.snapLen)
+	if err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		if err := pw.WritePacket(time.Unix(0, rec.ts), data[rec.off:rec.off+rec.len], rec.len); err != nil {
+			return err
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// enforceLimits borra los volcados más antiguos del directorio (de cualquier interfaz)
+// hasta respetar max_files y max_disk_mb. El fichero recién escrito nunca se borra.
+func (r *Recorder) enforceLimits(keep string) {
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		log.Printf("⚠️ [Forensics:%s] Cannot list %s: %v", r.ifaceName, r.dir, err)
+		return
+	}
+
+	type dumpFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []dumpFile
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), filePrefix) || !strings.HasSuffix(e.Name(), ".pcapng") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, dumpFile{filepath.Join(r.dir, e.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+
+	remaining := len(files)
+	for _, f := range files {
+		if remaining <= r.maxFiles && total <= r.maxDisk {
+			break
+		}
+		if f.path == keep {
+			continue
+		}
+		if err := os.Remove(f.path); err != nil {
+			log.Printf("⚠️ [Forensics:%s] Cannot rotate %s: %v", r.ifaceName, f.path, err)
+			continue
+		}
+		remaining--
+		total -= f.size
+	}
+}
+
+func sanitize(s string) string {
+	return strings.Map(func(c rune) rune {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '.' {
+			return c
+		}
+		return '-'
+	}, s)
+}
@@ -0,0 +1,115 @@
+package forensics
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/pcap"
+)
+
+func newTestRecorder(t *testing.T, dir string) *Recorder {
+	t.Helper()
+	r, err := NewRecorder(&config.ForensicsConfig{
+		Directory:    dir,
+		Window:       "2s",
+		BufferMB:     1,
+		MaxFiles:     2,
+		DumpCooldown: "1s",
+	}, "test0", 2048)
+	if err != nil {
+		t.Fatalf("NewRecorder: %v", err)
+	}
+	return r
+}
+
+func TestRecorder_WindowAndDump(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRecorder(t, dir)
+
+	base := time.Unix(1700000000, 0)
+	// 5 segundos de tráfico a 10 fps: con window=2s sólo deben quedar ~20 tramas
+	for i := 0; i < 50; i++ {
+		frame := bytes.Repeat([]byte{byte(i)}, 64)
+		r.Record(base.Add(time.Duration(i)*100*time.Millisecond), frame)
+	}
+
+	path, err := r.Dump("Test")
+	if err != nil {
+		t.Fatalf("Dump: %v", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open dump: %v", err)
+	}
+	defer f.Close()
+
+	rd, err := pcap.NewReader(f)
+	if err != nil {
+		t.Fatalf("pcap.NewReader: %v", err)
+	}
+
+	var frames int
+	var firstByte byte
+	for {
+		pkt, err := rd.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		if frames == 0 {
+			firstByte = pkt.Data[0]
+		}
+		frames++
+	}
+
+	if frames != 21 {
+		t.Errorf("Esperaba 21 tramas dentro de la ventana de 2s, obtuve %d", frames)
+	}
+	if firstByte != 29 {
+		t.Errorf("La trama más antigua debería ser la #29, es la #%d", firstByte)
+	}
+
+	// Dentro del cooldown se reutiliza el mismo fichero
+	again, err := r.Dump("Other")
+	if err != nil || again != path {
+		t.Errorf("Esperaba reutilizar %s dentro del cooldown, obtuve %s (%v)", path, again, err)
+	}
+}
+
+func TestRecorder_ArenaWrapAndRotation(t *testing.T) {
+	dir := t.TempDir()
+	r := newTestRecorder(t, dir)
+
+	base := time.Unix(1700000000, 0)
+	frame := make([]byte, 1500)
+	// 3 MB en el mismo instante: el límite de 1 MB debe desalojar por espacio, no por tiempo
+	for i := 0; i < 2000; i++ {
+		frame[0] = byte(i)
+		r.Record(base, frame)
+	}
+
+	if used := r.usedBytes(); used > len(r.arena) || used < len(r.arena)-2*1500 {
+		t.Errorf("Uso de arena incoherente: %d de %d bytes", used, len(r.arena))
+	}
+
+	// Tres volcados separados por más que el cooldown: max_files=2 rota el más antiguo
+	for i := 0; i < 3; i++ {
+		r.Record(base.Add(time.Duration(i+1)*5*time.Second), frame)
+		if _, err := r.Dump("Rotate"); err != nil {
+			t.Fatalf("Dump #%d: %v", i, err)
+		}
+	}
+
+	matches, _ := filepath.Glob(filepath.Join(dir, filePrefix+"*.pcapng"))
+	if len(matches) != 2 {
+		t.Errorf("Esperaba 2 ficheros tras la rotación, hay %d", len(matches))
+	}
+}
@@ -0,0 +1,230 @@
+package linkmon
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// State es el estado de una interfaz tal y como lo ve el kernel.
+type State struct {
+	Name    string
+	Index   int
+	Exists  bool
+	Up      bool // IFF_UP (administrativo)
+	Carrier bool // IFF_RUNNING (operstate up: hay portadora)
+}
+
+// Usable indica si se puede capturar en la interfaz.
+func (s State) Usable() bool { return s.Exists && s.Up && s.Carrier }
+
+// Lookup consulta el estado actual sin netlink (arranque y modo degradado).
+func Lookup(name string) State {
+	ifi, err := net.InterfaceByName(name)
+	if err != nil {
+		return State{Name: name}
+	}
+	return State{
+		Name:    name,
+		Index:   ifi.Index,
+		Exists:  true,
+		Up:      ifi.Flags&net.FlagUp != 0,
+		Carrier: ifi.Flags&net.FlagRunning != 0,
+	}
+}
+
+// Monitor escucha RTNLGRP_LINK y reparte los cambios de estado a los suscriptores
+// de cada interfaz. Los RTM_NEWLINK repetidos (estadísticas, MTU...) se filtran:
+// sólo se notifica cuando cambia Exists/Up/Carrier.
+type Monitor struct {
+	file *os.File
+	rc   syscall.RawConn
+
+	mu    sync.Mutex
+	subs  map[string][]chan State
+	last  map[string]State
+	names map[int32]string // RTM_DELLINK puede llegar sin IFLA_IFNAME en kernels antiguos
+}
+
+// Open se suscribe al grupo multicast de enlaces de rtnetlink.
+func Open() (*Monitor, error) {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_ROUTE)
+	if err != nil {
+		return nil, fmt.Errorf("netlink socket: %w", err)
+	}
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: unix.RTMGRP_LINK}); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("netlink bind: %w", err)
+	}
+
+	// os.File sobre un fd no bloqueante: el poller de Go nos permite desbloquear con Close()
+	file := os.NewFile(uintptr(fd), "rtnetlink")
+	rc, err := file.SyscallConn()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &Monitor{
+		file:  file,
+		rc:    rc,
+		subs:  make(map[string][]chan State),
+		last:  make(map[string]State),
+		names: make(map[int32]string),
+	}, nil
+}
+
+// Subscribe devuelve un canal con el último estado de la interfaz. Si el suscriptor no
+// consume a tiempo, los cambios intermedios se pierden pero el más reciente nunca.
+func (m *Monitor) Subscribe(name string) <-chan State {
+	ch := make(chan State, 1)
+	m.mu.Lock()
+	m.subs[name] = append(m.subs[name], ch)
+	if _, ok := m.last[name]; !ok {
+		st := Lookup(name)
+		m.last[name] = st
+		if st.Exists {
+			m.names[int32(st.Index)] = name
+		}
+	}
+	m.mu.Unlock()
+	return ch
+}
+
+// Unsubscribe retira un canal devuelto por Subscribe (interfaz retirada en una recarga).
+func (m *Monitor) Unsubscribe(name string, ch <-chan State) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	subs := m.subs[name]
+	for i, c := range subs {
+		if c == ch {
+			subs = append(subs[:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(subs) == 0 {
+		delete(m.subs, name)
+		delete(m.last, name)
+		return
+	}
+	m.subs[name] = subs
+}
+
+// Run lee eventos hasta que se cancela el contexto.
+func (m *Monitor) Run(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		m.file.Close()
+	}()
+
+	buf := make([]byte, 1<<16)
+	for {
+		var n int
+		var rerr error
+		err := m.rc.Read(func(fd uintptr) bool {
+			n, _, rerr = unix.Recvfrom(int(fd), buf, unix.MSG_DONTWAIT)
+			return rerr != unix.EAGAIN
+		})
+		if err == nil {
+			err = rerr
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			// ENOBUFS: el kernel descartó eventos. Resincronizamos con el estado real.
+			if err == unix.ENOBUFS {
+				m.resync()
+				continue
+			}
+			return fmt.Errorf("netlink read: %w", err)
+		}
+
+		msgs, err := syscall.ParseNetlinkMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		for i := range msgs {
+			m.handle(&msgs[i])
+		}
+	}
+}
+
+func (m *Monitor) handle(msg *syscall.NetlinkMessage) {
+	if msg.Header.Type != unix.RTM_NEWLINK && msg.Header.Type != unix.RTM_DELLINK {
+		return
+	}
+	if len(msg.Data) < unix.SizeofIfInfomsg {
+		return
+	}
+	ifi := (*unix.IfInfomsg)(unsafe.Pointer(&msg.Data[0]))
+
+	name := ""
+	if attrs, err := syscall.ParseNetlinkRouteAttr(msg); err == nil {
+		for _, a := range attrs {
+			if a.Attr.Type == unix.IFLA_IFNAME {
+				name = unix.ByteSliceToString(a.Value)
+			}
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if name == "" {
+		name = m.names[ifi.Index]
+	}
+	if _, watched := m.subs[name]; !watched {
+		return
+	}
+
+	st := State{Name: name, Index: int(ifi.Index)}
+	if msg.Header.Type == unix.RTM_NEWLINK {
+		st.Exists = true
+		st.Up = ifi.Flags&unix.IFF_UP != 0
+		st.Carrier = ifi.Flags&unix.IFF_RUNNING != 0
+		m.names[ifi.Index] = name
+	} else {
+		delete(m.names, ifi.Index)
+	}
+
+	m.publish(st)
+}
+
+// resync compara el estado real de cada interfaz vigilada con el último publicado.
+func (m *Monitor) resync() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range m.subs {
+		st := Lookup(name)
+		if st.Exists {
+			m.names[int32(st.Index)] = name
+		}
+		m.publish(st)
+	}
+}
+
+// publish requiere m.mu.
+func (m *Monitor) publish(st State) {
+	prev := m.last[st.Name]
+	if prev.Exists == st.Exists && prev.Up == st.Up && prev.Carrier == st.Carrier {
+		return
+	}
+	m.last[st.Name] = st
+
+	// Canal de último valor: el estado pendiente sin leer ya no vale, se sustituye.
+	// Sólo publica quien tiene m.mu, así que tras vaciarlo el envío no bloquea.
+	for _, ch := range m.subs[st.Name] {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- st
+	}
+}
@@ -0,0 +1,100 @@
+package linkmon
+
+import (
+	"encoding/binary"
+	"syscall"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// linkMsg construye un mensaje rtnetlink RTM_NEWLINK/RTM_DELLINK como los que envía
+// el kernel: nlmsghdr + ifinfomsg + IFLA_IFNAME opcional.
+func linkMsg(typ uint16, index int32, flags uint32, name string) []byte {
+	var attr []byte
+	if name != "" {
+		value := append([]byte(name), 0)
+		attr = make([]byte, unix.SizeofRtAttr, (unix.SizeofRtAttr+len(value)+3)&^3)
+		binary.NativeEndian.PutUint16(attr[0:], uint16(unix.SizeofRtAttr+len(value)))
+		binary.NativeEndian.PutUint16(attr[2:], unix.IFLA_IFNAME)
+		attr = append(attr, value...)
+		attr = attr[:cap(attr)]
+	}
+
+	b := make([]byte, unix.SizeofNlMsghdr+unix.SizeofIfInfomsg, unix.SizeofNlMsghdr+unix.SizeofIfInfomsg+len(attr))
+	binary.NativeEndian.PutUint32(b[0:], uint32(cap(b)))
+	binary.NativeEndian.PutUint16(b[4:], typ)
+	ifi := b[unix.SizeofNlMsghdr:]
+	ifi[0] = unix.AF_UNSPEC
+	binary.NativeEndian.PutUint32(ifi[4:], uint32(index))
+	binary.NativeEndian.PutUint32(ifi[8:], flags)
+	return append(b, attr...)
+}
+
+func TestMonitor_HandleLinkMessages(t *testing.T) {
+	m := &Monitor{
+		subs:  make(map[string][]chan State),
+		last:  make(map[string]State),
+		names: make(map[int32]string),
+	}
+	const iface = "lwtest0" // No existe: Subscribe parte de Exists=false
+	ch := m.Subscribe(iface)
+
+	feed := func(raw []byte) {
+		msgs, err := syscall.ParseNetlinkMessage(raw)
+		if err != nil || len(msgs) != 1 {
+			t.Fatalf("Mensaje mal construido: %v (%d)", err, len(msgs))
+		}
+		m.handle(&msgs[0])
+	}
+	next := func() (State, bool) {
+		select {
+		case st := <-ch:
+			return st, true
+		default:
+			return State{}, false
+		}
+	}
+
+	feed(linkMsg(unix.RTM_NEWLINK, 42, unix.IFF_UP|unix.IFF_RUNNING, iface))
+	if st, ok := next(); !ok || !st.Usable() || st.Index != 42 || st.Name != iface {
+		t.Errorf("Alta con portadora: %v %+v", ok, st)
+	}
+
+	// Mismo estado (estadísticas, MTU...): no se publica
+	feed(linkMsg(unix.RTM_NEWLINK, 42, unix.IFF_UP|unix.IFF_RUNNING, iface))
+	if st, ok := next(); ok {
+		t.Errorf("RTM_NEWLINK repetido publicado: %+v", st)
+	}
+
+	// Interfaz no vigilada: se ignora
+	feed(linkMsg(unix.RTM_NEWLINK, 7, unix.IFF_UP, "other0"))
+	if st, ok := next(); ok {
+		t.Errorf("Interfaz ajena publicada: %+v", st)
+	}
+
+	feed(linkMsg(unix.RTM_NEWLINK, 42, unix.IFF_UP, iface))
+	if st, ok := next(); !ok || !st.Exists || !st.Up || st.Carrier {
+		t.Errorf("Pérdida de portadora: %v %+v", ok, st)
+	}
+
+	// Ráfaga sin consumir: el suscriptor recibe el último estado, no el primero
+	feed(linkMsg(unix.RTM_NEWLINK, 42, unix.IFF_UP|unix.IFF_RUNNING, iface))
+	feed(linkMsg(unix.RTM_NEWLINK, 42, 0, iface))
+	feed(linkMsg(unix.RTM_NEWLINK, 42, unix.IFF_UP, iface))
+	if st, ok := next(); !ok || !st.Up || st.Carrier {
+		t.Errorf("Tras la ráfaga: %v %+v", ok, st)
+	}
+	if st, ok := next(); ok {
+		t.Errorf("Estado intermedio pendiente: %+v", st)
+	}
+
+	// RTM_DELLINK sin IFLA_IFNAME: el nombre sale del índice
+	feed(linkMsg(unix.RTM_DELLINK, 42, 0, ""))
+	if st, ok := next(); !ok || st.Exists || st.Name != iface {
+		t.Errorf("Baja de la interfaz: %v %+v", ok, st)
+	}
+	if _, ok := m.names[42]; ok {
+		t.Error("El índice debe olvidarse tras RTM_DELLINK")
+	}
+}
@@ -0,0 +1,229 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+// amAlert es el objeto que espera POST /api/v2/alerts de Alertmanager.
+type amAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt,omitzero"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// alertmanagerClient publica los eventos en Alertmanager siguiendo su ciclo de vida:
+// un incidente en curso se reenvía cada resend_interval con endsAt en el futuro
+// (si el sensor muere, Alertmanager lo resuelve solo al vencer endsAt) y el evento
+// "resolved" lo cierra con endsAt = hora de resolución. Silencios, agrupado e
+// inhibición quedan en manos de Alertmanager.
+type alertmanagerClient struct {
+	url          string
+	interval     time.Duration
+	generatorURL string
+	labels       map[string]string
+	user         string
+	pass         string
+	client       *http.Client
+
+	// mu serializa envíos y refrescos: un refresco nunca puede reabrir un incidente
+	// que se acaba de resolver.
+	mu     sync.Mutex
+	active map[string]Event // Incidentes en curso (clave: Event.Incident)
+}
+
+func newAlertmanagerClient(cfg *config.AlertmanagerConfig, client *http.Client) *alertmanagerClient {
+	c := &alertmanagerClient{
+		url:          strings.TrimSuffix(cfg.URL, "/"),
+		generatorURL: cfg.GeneratorURL,
+		labels:       cfg.Labels,
+		user:         cfg.User,
+		pass:         cfg.Pass,
+		client:       client,
+		active:       make(map[string]Event),
+	}
+	if !strings.HasSuffix(c.url, "/api/v2/alerts") {
+		c.url += "/api/v2/alerts"
+	}
+
+	c.interval = time.Minute
+	if cfg.ResendInterval != "" {
+		d, err := time.ParseDuration(cfg.ResendInterval)
+		if err != nil || d <= 0 {
+			log.Printf("⚠️ [Notifier] Invalid Alertmanager resend_interval '%s', defaulting to 1m", cfg.ResendInterval)
+		} else {
+			c.interval = d
+		}
+	}
+
+	log.Printf("📟 [Notifier] Alertmanager -> %s (resend every %v)", c.url, c.interval)
+	return c
+}
+
+// Send publica un evento y actualiza el conjunto de incidentes que se refrescan.
+func (c *alertmanagerClient) Send(ctx context.Context, ev Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.post(ctx, []amAlert{c.alert(ev, time.Now())}); err != nil {
+		return err
+	}
+	if ev.Incident != "" {
+		if ev.Resolved() {
+			delete(c.active, ev.Incident)
+		} else {
+			c.active[ev.Incident] = ev
+		}
+	}
+	return nil
+}
+
+// refresh reenvía los incidentes en curso para que Alertmanager no los dé por resueltos.
+// Un fallo sólo se registra: el siguiente refresco lo vuelve a intentar.
+func (c *alertmanagerClient) refresh() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.active) == 0 {
+		return
+	}
+
+	now := time.Now()
+	alerts := make([]amAlert, 0, len(c.active))
+	for _, ev := range c.active {
+		alerts = append(alerts, c.alert(ev, now))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.post(ctx, alerts); err != nil {
+		log.Printf("⚠️ [Notifier] Alertmanager refresh of %d active alerts failed: %v", len(alerts), err)
+	}
+}
+
+// alert traduce el evento. Las etiquetas identifican la alerta en Alertmanager y deben
+// ser las mismas en cada refresco del incidente: VLAN y MAC sólo son etiqueta cuando
+// forman parte de la clave del incidente (su sujeto). En los incidentes sin sujeto
+// (LoopDetected, GlobalStorm, MulticastStorm, PauseFlood) son las del último evento y
+// van a las anotaciones.
+func (c *alertmanagerClient) alert(ev Event, now time.Time) amAlert {
+	labels := make(map[string]string, len(c.labels)+8)
+	for k, v := range c.labels {
+		labels[k] = v
+	}
+	label := func(name, value string) {
+		if value != "" {
+			labels[name] = value
+		}
+	}
+	label("alertname", ev.ThreatType)
+	label("sensor", ev.Sensor)
+	label("interface", ev.Interface)
+	label("engine", ev.Algorithm)
+	label("threat_type", ev.ThreatType)
+	label("severity", ev.Severity.String())
+
+	a := amAlert{
+		Labels: labels,
+		Annotations: map[string]string{
+			"summary":     ev.Title,
+			"description": FormatText(ev),
+		},
+		StartsAt:     ev.Time,
+		GeneratorURL: c.generatorURL,
+	}
+	annotate := func(name, value string) {
+		if value != "" {
+			a.Annotations[name] = value
+		}
+	}
+	switch subject := incidentSubject(ev); {
+	case subject == "":
+		annotate("vlan", ev.VLAN)
+		annotate("mac", ev.SrcMAC)
+	case subject == ev.SrcMAC:
+		label("mac", ev.SrcMAC)
+		annotate("vlan", ev.VLAN)
+	case subject == ev.VLAN:
+		label("vlan", ev.VLAN)
+		annotate("mac", ev.SrcMAC)
+	default: // Sujeto propio (p. ej. el puente de una RogueBPDU)
+		label("subject", subject)
+		annotate("vlan", ev.VLAN)
+		annotate("mac", ev.SrcMAC)
+	}
+	if ev.Incident != "" {
+		a.Annotations["incident"] = ev.Incident
+	}
+	if !ev.StartedAt.IsZero() {
+		a.StartsAt = ev.StartedAt
+	}
+
+	switch {
+	case ev.Resolved():
+		a.EndsAt = ev.Time
+	case ev.Incident != "":
+		// Mismo margen que Prometheus: sobrevive a tres refrescos perdidos
+		a.EndsAt = now.Add(4 * c.interval)
+	}
+	// Sin incidente (avisos del sistema, enlace): endsAt vacío y Alertmanager
+	// aplica su resolve_timeout.
+	return a
+}
+
+// incidentSubject devuelve el sujeto de la clave interfaz/algoritmo/amenaza/sujeto,
+// "" si el incidente no lo tiene.
+func incidentSubject(ev Event) string {
+	prefix := ev.Interface + "/" + ev.Algorithm + "/" + ev.ThreatType + "/"
+	if !strings.HasPrefix(ev.Incident, prefix) {
+		return ""
+	}
+	return ev.Incident[len(prefix):]
+}
+
+func (c *alertmanagerClient) post(ctx context.Context, alerts []amAlert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.user != "" {
+		req.SetBasicAuth(c.user, c.pass)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// run mantiene vivas en Alertmanager las alertas de incidentes en curso.
+func (c *alertmanagerClient) run(done <-chan struct{}) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.refresh()
+		case <-done:
+			return
+		}
+	}
+}
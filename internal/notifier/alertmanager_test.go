@@ -0,0 +1,142 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+// fakeAlertmanager hace de /api/v2/alerts y guarda cada lote recibido.
+type fakeAlertmanager struct {
+	mu      sync.Mutex
+	batches [][]amAlert
+}
+
+func (f *fakeAlertmanager) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.URL.Path != "/api/v2/alerts" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	var alerts []amAlert
+	if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	f.batches = append(f.batches, alerts)
+	f.mu.Unlock()
+}
+
+func (f *fakeAlertmanager) received() [][]amAlert {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]amAlert(nil), f.batches...)
+}
+
+func TestAlertmanager_Lifecycle(t *testing.T) {
+	am := &fakeAlertmanager{}
+	srv := httptest.NewServer(am)
+	defer srv.Close()
+
+	n := NewNotifier(&config.AlertsConfig{
+		Alertmanager: config.AlertmanagerConfig{
+			Enabled:        true,
+			URL:            srv.URL + "/",
+			ResendInterval: "20ms",
+			Labels:         map[string]string{"site": "madrid"},
+		},
+	}, "sensor-01")
+
+	started := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	firing := sampleEvent()
+	firing.Incident = "eth0/MacStorm/HostFlood/aa:bb:cc:dd:ee:ff"
+	firing.StartedAt = started
+	n.Notify(firing)
+
+	// 1. Alerta inicial + al menos un refresco mientras el incidente sigue abierto
+	deadline := time.Now().Add(2 * time.Second)
+	for len(am.received()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	batches := am.received()
+	if len(batches) < 3 {
+		t.Fatalf("Esperaba la alerta y sus refrescos, recibí %d lotes", len(batches))
+	}
+	a := batches[0][0]
+	for k, want := range map[string]string{
+		"alertname": "HostFlood", "sensor": "sensor-01", "interface": "eth0", "engine": "MacStorm",
+		"threat_type": "HostFlood", "severity": "warning", "mac": "aa:bb:cc:dd:ee:ff", "site": "madrid",
+	} {
+		if a.Labels[k] != want {
+			t.Errorf("Etiqueta %s = %q, esperaba %q", k, a.Labels[k], want)
+		}
+	}
+	if _, ok := a.Labels["vlan"]; ok || a.Annotations["vlan"] != "10" {
+		t.Errorf("La VLAN no forma parte de la clave del incidente: %v / %v", a.Labels, a.Annotations)
+	}
+	if !a.StartsAt.Equal(started) || !a.EndsAt.After(time.Now()) {
+		t.Errorf("Una alerta activa empieza en StartedAt y vence en el futuro: %v -> %v", a.StartsAt, a.EndsAt)
+	}
+	if refreshed := batches[len(batches)-1][0]; refreshed.Labels["mac"] != a.Labels["mac"] || !refreshed.StartsAt.Equal(started) {
+		t.Errorf("El refresco debe reenviar la misma alerta: %+v", refreshed)
+	}
+
+	// 2. Resolved: cierra la alerta y deja de refrescarse
+	resolved := firing
+	resolved.Status = StatusResolved
+	resolved.Time = started.Add(90 * time.Second)
+	n.Notify(resolved)
+	n.Close(5 * time.Second)
+
+	batches = am.received()
+	last := batches[len(batches)-1][0]
+	if !last.EndsAt.Equal(resolved.Time) {
+		t.Errorf("El último envío debe resolver la alerta (endsAt %v), obtuve %v", resolved.Time, last.EndsAt)
+	}
+	if client := n.sinks[ChannelAlertmanager].(*alertmanagerClient); len(client.active) != 0 {
+		t.Errorf("No deben quedar incidentes activos: %d", len(client.active))
+	}
+}
+
+func TestAlertmanager_LabelsFollowIncidentKey(t *testing.T) {
+	c := &alertmanagerClient{}
+	now := time.Now()
+
+	// Incidente sin sujeto: la MAC y la VLAN del último evento cambian entre refrescos
+	loop := sampleEvent()
+	loop.Algorithm, loop.ThreatType = "EtherFuse", "LoopDetected"
+	loop.Incident = "eth0/EtherFuse/LoopDetected"
+	a := c.alert(loop, now)
+	if _, ok := a.Labels["mac"]; ok {
+		t.Errorf("Sin sujeto la MAC no debe ser etiqueta: %v", a.Labels)
+	}
+	if _, ok := a.Labels["vlan"]; ok {
+		t.Errorf("Sin sujeto la VLAN no debe ser etiqueta: %v", a.Labels)
+	}
+	if a.Annotations["mac"] != loop.SrcMAC || a.Annotations["vlan"] != loop.VLAN {
+		t.Errorf("MAC y VLAN deben ir en las anotaciones: %v", a.Annotations)
+	}
+
+	// Sujeto VLAN (StpMonitor): la VLAN identifica la alerta, la MAC no
+	root := sampleEvent()
+	root.Algorithm, root.ThreatType = "StpMonitor", "RootChange"
+	root.Incident = "eth0/StpMonitor/RootChange/" + root.VLAN
+	a = c.alert(root, now)
+	if a.Labels["vlan"] != root.VLAN || a.Labels["mac"] != "" || a.Annotations["mac"] != root.SrcMAC {
+		t.Errorf("Sujeto VLAN: etiquetas %v, anotaciones %v", a.Labels, a.Annotations)
+	}
+
+	// Sujeto propio: otra MAC distinta del emisor
+	rogue := sampleEvent()
+	rogue.Algorithm, rogue.ThreatType = "StpMonitor", "RogueBPDU"
+	rogue.Incident = "eth0/StpMonitor/RogueBPDU/00:11:22:33:44:55"
+	a = c.alert(rogue, now)
+	if a.Labels["subject"] != "00:11:22:33:44:55" || a.Labels["mac"] != "" {
+		t.Errorf("Sujeto propio: etiquetas %v", a.Labels)
+	}
+}
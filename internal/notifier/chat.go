@@ -0,0 +1,171 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Formatos de chat de los webhooks ("format" en [alerts.webhook] y [[alerts.webhooks]]).
+const (
+	FormatNameSlack   = "slack"
+	FormatNameTeams   = "teams"
+	FormatNameDiscord = "discord"
+)
+
+// formatChat renderiza el evento con el formateador de chat indicado.
+func formatChat(format string, ev Event) ([]byte, error) {
+	switch format {
+	case FormatNameSlack:
+		return FormatSlack(ev)
+	case FormatNameTeams:
+		return FormatTeams(ev)
+	case FormatNameDiscord:
+		return FormatDiscord(ev)
+	}
+	return FormatJSON(ev)
+}
+
+// severityColor: rojo crítico, ámbar aviso, azul informativo y verde al resolverse.
+func severityColor(ev Event) int {
+	switch {
+	case ev.Resolved():
+		return 0x2EB67D
+	case ev.Severity == SeverityCritical:
+		return 0xE01E5A
+	case ev.Severity == SeverityWarning:
+		return 0xECB22E
+	default:
+		return 0x36C5F0
+	}
+}
+
+// fieldLabel pasa las claves de fields() a título ("SOURCE MAC" -> "Source MAC").
+func fieldLabel(key string) string {
+	words := strings.Fields(strings.ToLower(key))
+	for i, w := range words {
+		switch w {
+		case "mac", "ip", "vlan", "pcap", "dhcp", "stp":
+			words[i] = strings.ToUpper(w)
+		default:
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// chatContext es la línea secundaria común: "sensor · Algoritmo · severidad · estado".
+func chatContext(ev Event) string {
+	parts := []string{}
+	for _, p := range []string{ev.Sensor, ev.Algorithm, ev.Severity.String(), string(ev.Status)} {
+		if p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " · ")
+}
+
+// FormatSlack construye un mensaje Block Kit: cabecera, campos (interfaz, VLAN, MAC,
+// tasa...) en dos columnas y la línea de contexto. Los bloques van dentro de un
+// attachment para conservar la barra de color de la severidad; "text" es la vista previa
+// de las notificaciones.
+func FormatSlack(ev Event) ([]byte, error) {
+	esc := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+	blocks := []map[string]any{{
+		"type": "header",
+		"text": map[string]any{"type": "plain_text", "text": truncate(ev.Title, 150), "emoji": true},
+	}}
+	var section []map[string]any
+	for _, f := range fields(ev) {
+		section = append(section, map[string]any{
+			"type": "mrkdwn",
+			"text": truncate("*"+fieldLabel(f.Key)+"*\n"+esc.Replace(f.Value), 2000),
+		})
+		if len(section) == 10 { // Límite de Slack por sección
+			blocks = append(blocks, map[string]any{"type": "section", "fields": section})
+			section = nil
+		}
+	}
+	if len(section) > 0 {
+		blocks = append(blocks, map[string]any{"type": "section", "fields": section})
+	}
+	blocks = append(blocks, map[string]any{
+		"type":     "context",
+		"elements": []map[string]any{{"type": "mrkdwn", "text": esc.Replace(chatContext(ev))}},
+	})
+
+	return json.Marshal(map[string]any{
+		"text": FormatSummary(ev),
+		"attachments": []map[string]any{{
+			"color":  fmt.Sprintf("#%06X", severityColor(ev)),
+			"blocks": blocks,
+		}},
+	})
+}
+
+// FormatTeams construye una Adaptive Card (webhooks de Teams / Workflows): titular
+// coloreado por severidad, línea de contexto y un FactSet con los campos del evento.
+func FormatTeams(ev Event) ([]byte, error) {
+	color := "Accent"
+	switch {
+	case ev.Resolved():
+		color = "Good"
+	case ev.Severity == SeverityCritical:
+		color = "Attention"
+	case ev.Severity == SeverityWarning:
+		color = "Warning"
+	}
+
+	var facts []map[string]string
+	for _, f := range fields(ev) {
+		facts = append(facts, map[string]string{"title": fieldLabel(f.Key), "value": f.Value})
+	}
+
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"msteams": map[string]any{"width": "Full"},
+		"body": []map[string]any{
+			{"type": "TextBlock", "text": ev.Title, "weight": "Bolder", "size": "Medium", "color": color, "wrap": true},
+			{"type": "TextBlock", "text": chatContext(ev), "isSubtle": true, "spacing": "None", "wrap": true},
+			{"type": "FactSet", "facts": facts},
+		},
+	}
+	return json.Marshal(map[string]any{
+		"type":    "message",
+		"summary": FormatSummary(ev),
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	})
+}
+
+// FormatDiscord construye un embed con el color de la severidad y un campo por dato.
+func FormatDiscord(ev Event) ([]byte, error) {
+	var embedFields []map[string]any
+	for _, f := range fields(ev) {
+		if len(embedFields) == 25 { // Límite de Discord por embed
+			break
+		}
+		embedFields = append(embedFields, map[string]any{
+			"name":   fieldLabel(f.Key),
+			"value":  truncate(f.Value, 1024),
+			"inline": len(f.Value) <= 40,
+		})
+	}
+
+	embed := map[string]any{
+		"title":  truncate(ev.Title, 256),
+		"color":  severityColor(ev),
+		"fields": embedFields,
+		"footer": map[string]any{"text": chatContext(ev)},
+	}
+	if !ev.Time.IsZero() {
+		embed["timestamp"] = ev.Time.UTC().Format(time.RFC3339)
+	}
+	return json.Marshal(map[string]any{"embeds": []map[string]any{embed}})
+}
@@ -0,0 +1,103 @@
+package notifier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFormatSlack_BlockKitFields(t *testing.T) {
+	ev := sampleEvent()
+	ev.Severity = SeverityCritical
+	body, err := FormatSlack(ev)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg struct {
+		Text        string
+		Attachments []struct {
+			Color  string
+			Blocks []struct {
+				Type   string
+				Text   struct{ Text string }
+				Fields []struct{ Type, Text string }
+			}
+		}
+	}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if len(msg.Attachments) != 1 || msg.Attachments[0].Color != "#E01E5A" {
+		t.Fatalf("Se espera un attachment con el color crítico: %s", body)
+	}
+	blocks := msg.Attachments[0].Blocks
+	if blocks[0].Type != "header" || blocks[0].Text.Text != ev.Title {
+		t.Errorf("Cabecera incorrecta: %+v", blocks[0])
+	}
+	var got []string
+	for _, f := range blocks[1].Fields {
+		got = append(got, f.Text)
+	}
+	for _, want := range []string{"*Interface*\neth0", "*VLAN*\n10", "*Source MAC*\naa:bb:cc:dd:ee:ff", "*Rate*\n4200 pps (Threshold: 2000)"} {
+		if !strings.Contains(strings.Join(got, "|"), want) {
+			t.Errorf("Falta el campo %q en %v", want, got)
+		}
+	}
+	if !strings.HasPrefix(msg.Text, "[CRITICAL]") {
+		t.Errorf("La vista previa debe ser el resumen: %q", msg.Text)
+	}
+}
+
+func TestFormatTeamsAndDiscord(t *testing.T) {
+	ev := sampleEvent()
+	ev.Status = StatusResolved
+
+	body, _ := FormatTeams(ev)
+	var teams struct {
+		Attachments []struct {
+			ContentType string
+			Content     struct {
+				Body []struct {
+					Type  string
+					Color string
+					Facts []struct{ Title, Value string }
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(body, &teams); err != nil || len(teams.Attachments) != 1 {
+		t.Fatalf("Adaptive Card inválida (%v): %s", err, body)
+	}
+	card := teams.Attachments[0].Content.Body
+	if card[0].Color != "Good" || card[2].Type != "FactSet" || card[2].Facts[0].Title != "Interface" {
+		t.Errorf("Card de resolución incorrecta: %s", body)
+	}
+
+	body, _ = FormatDiscord(ev)
+	var discord struct {
+		Embeds []struct {
+			Title  string
+			Color  int
+			Fields []struct {
+				Name, Value string
+				Inline      bool
+			}
+		}
+	}
+	if err := json.Unmarshal(body, &discord); err != nil || len(discord.Embeds) != 1 {
+		t.Fatalf("Embed inválido (%v): %s", err, body)
+	}
+	e := discord.Embeds[0]
+	if e.Color != 0x2EB67D || e.Fields[1].Name != "VLAN" || e.Fields[1].Value != "10" || !e.Fields[1].Inline {
+		t.Errorf("Embed incorrecto: %+v", e)
+	}
+}
+
+func TestTruncate_KeepsRunes(t *testing.T) {
+	s := truncate(strings.Repeat("🌪️", 100), 150)
+	if len(s) > 150 || !utf8.ValidString(s) {
+		t.Errorf("Truncado inválido (%d bytes, UTF-8 válido: %v)", len(s), utf8.ValidString(s))
+	}
+}
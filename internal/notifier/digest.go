@@ -0,0 +1,179 @@
+package notifier
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/telemetry"
+)
+
+const (
+	maxDigestMACs   = 1000 // Por encima sólo se informa "1000+" (memoria acotada en tormentas)
+	digestMACSample = 10   // MACs listadas en el resumen
+)
+
+// digestGroup acumula las alertas retenidas de un mismo algoritmo, interfaz y tipo de
+// amenaza mientras el dampening está activo.
+type digestGroup struct {
+	algorithm  string
+	iface      string
+	threatType string
+	title      string // Último titular recibido
+	severity   Severity
+	count      int
+	first      time.Time
+	last       time.Time
+	peak       uint64
+	threshold  uint64
+	macs       map[string]struct{}
+	macOrder   []string
+	vlans      map[string]struct{}
+}
+
+// suppress retiene un evento durante el dampening: en modo digest lo agrupa para el
+// siguiente resumen; en modo mute sólo se cuenta. Requiere n.mu.
+func (n *Notifier) suppress(ev Event) {
+	n.droppedAlerts++
+	if !n.digestMode {
+		telemetry.AlertsSuppressed.WithLabelValues(ev.Algorithm, "muted").Inc()
+		return
+	}
+	telemetry.AlertsSuppressed.WithLabelValues(ev.Algorithm, "digest").Inc()
+
+	key := ev.Algorithm + "|" + ev.Interface + "|" + ev.ThreatType
+	g, ok := n.digests[key]
+	if !ok {
+		g = &digestGroup{
+			algorithm:  ev.Algorithm,
+			iface:      ev.Interface,
+			threatType: ev.ThreatType,
+			first:      ev.Time,
+			macs:       make(map[string]struct{}),
+			vlans:      make(map[string]struct{}),
+		}
+		n.digests[key] = g
+		n.digestOrder = append(n.digestOrder, key)
+	}
+
+	g.count++
+	g.title = ev.Title
+	g.last = ev.Time
+	if ev.Severity > g.severity { g.severity = ev.Severity }
+	if ev.Rate > g.peak { g.peak = ev.Rate }
+	if ev.Threshold > 0 { g.threshold = ev.Threshold }
+	if ev.SrcMAC != "" && len(g.macs) < maxDigestMACs {
+		if _, seen := g.macs[ev.SrcMAC]; !seen {
+			g.macs[ev.SrcMAC] = struct{}{}
+			g.macOrder = append(g.macOrder, ev.SrcMAC)
+		}
+	}
+	if ev.VLAN != "" {
+		g.vlans[ev.VLAN] = struct{}{}
+	}
+}
+
+// flushDigests convierte los grupos pendientes en eventos resumen y los vacía.
+// Requiere n.mu.
+func (n *Notifier) flushDigests(now time.Time) []Event {
+	n.lastDigest = now
+	var out []Event
+	for _, key := range n.digestOrder {
+		out = append(out, n.digests[key].event(n.sensorName, now))
+	}
+	n.digests = make(map[string]*digestGroup)
+	n.digestOrder = nil
+	return out
+}
+
+// resume cierra el periodo de dampening: últimos resúmenes y aviso de reanudación.
+// Requiere n.mu.
+func (n *Notifier) resume(now time.Time) []Event {
+	out := n.flushDigests(now)
+	title := fmt.Sprintf("⚠️ Resuming alerts. Dropped %d messages.", n.droppedAlerts)
+	if n.digestMode {
+		title = fmt.Sprintf("⚠️ Resuming alerts. %d messages were sent as digests.", n.droppedAlerts)
+	}
+	out = append(out, n.system("AlertsResumed", title, now))
+	n.isMuted = false
+	n.droppedAlerts = 0
+	n.windowStart = now
+	n.alertCount = 0
+	return out
+}
+
+// runDigest emite los resúmenes cada digest_interval y reanuda las alertas
+// individuales al acabar mute_duration, aunque no llegue ningún evento nuevo.
+func (n *Notifier) runDigest() {
+	defer n.wg.Done()
+	tick := n.digestInterval
+	if tick > time.Second { tick = time.Second }
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.done:
+			return
+		case now := <-ticker.C:
+			var out []Event
+			n.mu.Lock()
+			switch {
+			case n.isMuted && !now.Before(n.mutedUntil):
+				out = n.resume(now)
+			case now.Sub(n.lastDigest) >= n.digestInterval:
+				out = n.flushDigests(now)
+			}
+			n.mu.Unlock()
+			for _, ev := range out {
+				n.dispatch(ev)
+			}
+		}
+	}
+}
+
+// event construye el resumen del grupo: mismo algoritmo, interfaz y amenaza (el
+// enrutado y los filtros de los canales se aplican igual) y la gravedad máxima vista.
+func (g *digestGroup) event(sensor string, now time.Time) Event {
+	ev := Event{
+		Time:       now,
+		Sensor:     sensor,
+		Interface:  g.iface,
+		Algorithm:  g.algorithm,
+		ThreatType: g.threatType,
+		Severity:   g.severity,
+		Title:      fmt.Sprintf("📦 DIGEST: %d × %s", g.count, g.title),
+		Rate:       g.peak,
+		Threshold:  g.threshold,
+	}
+	ev = ev.With("SUPPRESSED", fmt.Sprintf("%d alerts", g.count)).
+		With("WINDOW", fmt.Sprintf("%s - %s", g.first.Format("15:04:05"), g.last.Format("15:04:05")))
+
+	if len(g.macs) > 0 {
+		count := fmt.Sprint(len(g.macs))
+		if len(g.macs) >= maxDigestMACs { count += "+" }
+		sample := g.macOrder
+		if len(sample) > digestMACSample { sample = sample[:digestMACSample] }
+		list := strings.Join(sample, ", ")
+		if len(g.macOrder) > len(sample) { list += ", …" }
+		ev = ev.With("DISTINCT MACS", fmt.Sprintf("%s (%s)", count, list))
+	}
+	if len(g.vlans) > 0 {
+		vlans := make([]string, 0, len(g.vlans))
+		for v := range g.vlans {
+			vlans = append(vlans, v)
+		}
+		sort.Slice(vlans, func(i, j int) bool { // Orden numérico: "2" antes que "10"
+			if len(vlans[i]) != len(vlans[j]) {
+				return len(vlans[i]) < len(vlans[j])
+			}
+			return vlans[i] < vlans[j]
+		})
+		ev = ev.With("VLANS", strings.Join(vlans, ", "))
+	}
+	if g.peak > 0 {
+		ev = ev.With("PEAK RATE", fmt.Sprintf("%d pps", g.peak))
+	}
+	return ev
+}
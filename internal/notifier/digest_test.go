@@ -0,0 +1,107 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+func TestDampening_DigestGroupsAndCriticalBypass(t *testing.T) {
+	var mu sync.Mutex
+	var got []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct{ Event Event }
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		got = append(got, payload.Event)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&config.AlertsConfig{
+		Webhook:   config.WebhookConfig{Enabled: true, URL: srv.URL},
+		Dampening: config.DampeningConfig{MaxAlertsPerMinute: 2, MuteDuration: "1m", Mode: "digest", DigestInterval: "1m", BypassSeverity: "critical"},
+	}, "sensor-01")
+
+	// 2 individuales, la 3ª activa el dampening y desde ahí se agrupan
+	for i, mac := range []string{"aa:00:00:00:00:01", "aa:00:00:00:00:02", "aa:00:00:00:00:03", "aa:00:00:00:00:03", "aa:00:00:00:00:04"} {
+		ev := sampleEvent()
+		ev.SrcMAC = mac
+		ev.Rate = uint64(1000 * (i + 1))
+		if i == 4 { ev.VLAN = "2" }
+		n.Notify(ev)
+	}
+	loop := sampleEvent()
+	loop.Algorithm, loop.ThreatType, loop.Severity, loop.Title = "EtherFuse", "HardLoop", SeverityCritical, "🚨 LOOP DETECTED!"
+	n.Notify(loop)
+	n.Close(5 * time.Second) // Close emite el resumen pendiente
+
+	mu.Lock()
+	defer mu.Unlock()
+	var titles []string
+	for _, ev := range got {
+		titles = append(titles, ev.Title)
+	}
+	if len(got) != 5 || got[2].ThreatType != "FloodProtection" || got[3].Algorithm != "EtherFuse" {
+		t.Fatalf("Secuencia inesperada: %q", titles)
+	}
+
+	digest := got[4]
+	if !strings.HasPrefix(digest.Title, "📦 DIGEST: 3 × ") || digest.Algorithm != "MacStorm" || digest.Interface != "eth0" || digest.Rate != 5000 {
+		t.Fatalf("Resumen incorrecto: %+v", digest)
+	}
+	details := map[string]string{}
+	for _, d := range digest.Details {
+		details[d.Key] = d.Value
+	}
+	if details["DISTINCT MACS"] != "2 (aa:00:00:00:00:03, aa:00:00:00:00:04)" || details["VLANS"] != "2, 10" {
+		t.Errorf("Detalles del resumen: %v", details)
+	}
+}
+
+// Sin mode ni bypass_severity se mantiene el dampening clásico: las críticas también
+// cuentan para el límite y quedan silenciadas tras el aviso de FloodProtection.
+func TestDampening_EmptyModeMutesCriticals(t *testing.T) {
+	var mu sync.Mutex
+	var got []Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct{ Event Event }
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		got = append(got, payload.Event)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&config.AlertsConfig{
+		Webhook:   config.WebhookConfig{Enabled: true, URL: srv.URL},
+		Dampening: config.DampeningConfig{MaxAlertsPerMinute: 2, MuteDuration: "1m"},
+	}, "sensor-01")
+	if n.digestMode {
+		t.Fatal("mode vacío activa el modo digest")
+	}
+
+	for _, mac := range []string{"aa:00:00:00:00:01", "aa:00:00:00:00:02", "aa:00:00:00:00:03", "aa:00:00:00:00:04"} {
+		ev := sampleEvent()
+		ev.Algorithm, ev.ThreatType, ev.Severity, ev.Title = "EtherFuse", "HardLoop", SeverityCritical, "🚨 LOOP DETECTED!"
+		ev.SrcMAC = mac
+		n.Notify(ev)
+	}
+	n.Close(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	var threats []string
+	for _, ev := range got {
+		threats = append(threats, ev.ThreatType)
+	}
+	if len(got) != 3 || got[0].ThreatType != "HardLoop" || got[1].ThreatType != "HardLoop" || got[2].ThreatType != "FloodProtection" {
+		t.Fatalf("Esperaba 2 críticas y el aviso de silencio, llegaron %q", threats)
+	}
+}
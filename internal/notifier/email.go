@@ -0,0 +1,281 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+const defaultSubject = `[LoopWarden] [{{upper .Severity}}] {{.Algorithm}}{{with .Interface}} on {{.}}{{end}}: {{.Title}}`
+
+// mailer envía las alertas por SMTP: TLS implícito (465), STARTTLS o texto plano,
+// relay con o sin autenticación, varios destinatarios y cuerpo multipart texto+HTML.
+type mailer struct {
+	host     string
+	addr     string
+	mode     string // "" (STARTTLS si se ofrece) | "starttls" | "implicit" | "none"
+	tlsCfg   *tls.Config
+	tlsErr   error // CA ilegible: cada envío falla (y se reintenta) con este error
+	user     string
+	pass     string
+	helo     string
+	from     string   // Cabecera From tal cual ("LoopWarden <noc@example.com>")
+	fromAddr string   // Sobre (MAIL FROM)
+	to       []string // Cabecera To
+	rcpt     []string // Sobre (RCPT TO)
+	domain   string   // Dominio del Message-ID
+	subject  *template.Template
+}
+
+func newMailer(cfg *config.SmtpConfig) *mailer {
+	m := &mailer{
+		host: cfg.Host,
+		mode: strings.ToLower(cfg.TLS),
+		user: cfg.User,
+		pass: cfg.Pass,
+		from: cfg.From,
+	}
+
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+		if m.mode == "implicit" { port = 465 }
+	}
+	m.addr = net.JoinHostPort(cfg.Host, fmt.Sprint(port))
+
+	m.helo, _ = os.Hostname()
+	if m.helo == "" { m.helo = "localhost" }
+
+	m.fromAddr, m.domain = cfg.From, m.helo
+	if a, err := mail.ParseAddress(cfg.From); err == nil {
+		m.fromAddr = a.Address
+		if i := strings.LastIndexByte(a.Address, '@'); i >= 0 {
+			m.domain = a.Address[i+1:]
+		}
+	}
+
+	// "to" admite la forma clásica (una dirección) o una lista separada por comas
+	var list []string
+	for _, r := range strings.Split(cfg.To, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			list = append(list, r)
+		}
+	}
+	list = append(list, cfg.Recipients...)
+	for _, r := range list {
+		m.to = append(m.to, r)
+		if a, err := mail.ParseAddress(r); err == nil {
+			m.rcpt = append(m.rcpt, a.Address)
+		} else {
+			log.Printf("⚠️ [Notifier] SMTP: invalid recipient '%s': %v", r, err)
+		}
+	}
+
+	m.tlsCfg = &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12, InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		pool := x509.NewCertPool()
+		switch {
+		case err != nil:
+			m.tlsErr = fmt.Errorf("ca_file: %w", err)
+		case !pool.AppendCertsFromPEM(pem):
+			m.tlsErr = fmt.Errorf("ca_file %s: no PEM certificates found", cfg.CAFile)
+		default:
+			m.tlsCfg.RootCAs = pool
+		}
+		if m.tlsErr != nil {
+			log.Printf("❌ [Notifier] SMTP TLS setup failed: %v", m.tlsErr)
+		}
+	}
+
+	subject := cfg.Subject
+	if subject == "" { subject = defaultSubject }
+	tmpl, err := template.New("subject").Funcs(templateFuncs).Parse(subject)
+	if err == nil {
+		err = tmpl.Execute(io.Discard, SystemEvent("TemplateCheck", "check"))
+	}
+	if err != nil {
+		log.Printf("⚠️ [Notifier] SMTP: invalid subject template, using the default: %v", err)
+		tmpl = template.Must(template.New("subject").Funcs(templateFuncs).Parse(defaultSubject))
+	}
+	m.subject = tmpl
+
+	mode := m.mode
+	if mode == "" { mode = "opportunistic STARTTLS" }
+	auth := "unauthenticated relay"
+	if m.user != "" { auth = "auth as " + m.user }
+	log.Printf("✉️ [Notifier] SMTP -> %s (%s, %s, %d recipients)", m.addr, mode, auth, len(m.rcpt))
+	return m
+}
+
+// Send entrega el evento en una sesión SMTP nueva, acotada por ctx.
+func (m *mailer) Send(ctx context.Context, ev Event) (err error) {
+	// Un rechazo 5xx del servidor (RCPT 550, MAIL FROM denegado, AUTH 535) se repetiría
+	// igual en cada reintento; un 4xx (greylisting, buzón lleno) sí se reintenta.
+	defer func() {
+		var smtpErr *textproto.Error
+		if errors.As(err, &smtpErr) && smtpErr.Code >= 500 {
+			err = permanent(err)
+		}
+	}()
+
+	msg, err := m.message(ev, time.Now())
+	if err != nil {
+		return permanent(err)
+	}
+	if len(m.rcpt) == 0 {
+		return permanent(fmt.Errorf("no valid recipients"))
+	}
+
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	if m.mode == "implicit" {
+		if m.tlsErr != nil {
+			return m.tlsErr
+		}
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: m.tlsCfg}).DialContext(ctx, "tcp", m.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", m.addr)
+	}
+	if err != nil {
+		return err
+	}
+	defer bindConn(ctx, conn)()
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if err := c.Hello(m.helo); err != nil {
+		return err
+	}
+	if m.mode == "" || m.mode == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if m.tlsErr != nil {
+				return m.tlsErr
+			}
+			if err := c.StartTLS(m.tlsCfg); err != nil {
+				return fmt.Errorf("STARTTLS: %w", err)
+			}
+		} else if m.mode == "starttls" {
+			return fmt.Errorf("server %s does not offer STARTTLS", m.addr)
+		}
+	}
+	if m.user != "" {
+		// PlainAuth se niega a enviar la contraseña sin TLS (salvo a localhost)
+		if err := c.Auth(smtp.PlainAuth("", m.user, m.pass, m.host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if err := c.Mail(m.fromAddr); err != nil {
+		return err
+	}
+	for _, r := range m.rcpt {
+		if err := c.Rcpt(r); err != nil {
+			return fmt.Errorf("RCPT %s: %w", r, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message construye el correo: cabeceras con Date y Message-ID (los filtros antispam
+// penalizan su ausencia) y cuerpo multipart/alternative texto + HTML.
+func (m *mailer) message(ev Event, now time.Time) ([]byte, error) {
+	var subject strings.Builder
+	if err := m.subject.Execute(&subject, ev); err != nil {
+		return nil, fmt.Errorf("subject template: %w", err)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ kind, content string }{
+		{"text/plain", FormatText(ev)},
+		{"text/html", FormatHTML(ev)},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.kind + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(pw)
+		qp.Write([]byte(part.content))
+		qp.Close()
+	}
+	mw.Close()
+
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	var b bytes.Buffer
+	header := func(k, v string) { fmt.Fprintf(&b, "%s: %s\r\n", k, v) }
+	header("From", m.from)
+	header("To", strings.Join(m.to, ", "))
+	// El asunto lleva emojis: cabecera codificada según RFC 2047
+	header("Subject", mime.QEncoding.Encode("utf-8", subject.String()))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%d.%s@%s>", now.UnixNano(), hex.EncodeToString(id), m.domain))
+	header("MIME-Version", "1.0")
+	header("Auto-Submitted", "auto-generated")
+	if ev.Incident != "" {
+		header("X-LoopWarden-Incident", ev.Incident)
+	}
+	header("Content-Type", "multipart/alternative; boundary="+mw.Boundary())
+	b.WriteString("\r\n")
+	b.Write(body.Bytes())
+	return b.Bytes(), nil
+}
+
+// FormatHTML es la versión HTML del correo: titular con la barra de color de la
+// severidad y una tabla con los campos del evento.
+func FormatHTML(ev Event) string {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html><body style="font-family:Arial,sans-serif;font-size:14px">`)
+	fmt.Fprintf(&b, `<div style="border-left:6px solid #%06X;padding:8px 14px">`, severityColor(ev))
+	fmt.Fprintf(&b, `<h2 style="margin:0 0 4px 0">%s</h2>`, html.EscapeString(ev.Title))
+	fmt.Fprintf(&b, `<p style="margin:0 0 12px 0;color:#666">%s</p>`, html.EscapeString(chatContext(ev)))
+	b.WriteString(`<table style="border-collapse:collapse">`)
+	for _, f := range fields(ev) {
+		fmt.Fprintf(&b, `<tr><th style="text-align:left;padding:3px 16px 3px 0;vertical-align:top">%s</th>`+
+			`<td style="font-family:monospace;padding:3px 0">%s</td></tr>`,
+			html.EscapeString(fieldLabel(f.Key)), html.EscapeString(f.Value))
+	}
+	b.WriteString(`</table></div></body></html>`)
+	return b.String()
+}
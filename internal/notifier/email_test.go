@@ -0,0 +1,226 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+// smtpSession es lo que recibe fakeSMTP en una sesión.
+type smtpSession struct {
+	tls  bool
+	from string
+	rcpt []string
+	data string
+}
+
+// fakeSMTP atiende una sesión SMTP mínima. Con startTLS anuncia STARTTLS y actualiza
+// la conexión al recibirlo. Rechaza los destinatarios nobody@ (550) y busy@ (451).
+func fakeSMTP(ln net.Listener, startTLS *tls.Config) <-chan smtpSession {
+	out := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var s smtpSession
+		_, s.tls = conn.(*tls.Conn)
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+		reply("220 fake ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimSpace(line)
+			switch verb := strings.ToUpper(strings.Fields(cmd + " x")[0]); verb {
+			case "EHLO", "HELO":
+				if startTLS != nil && !s.tls {
+					reply("250-fake")
+					reply("250 STARTTLS")
+				} else {
+					reply("250 fake")
+				}
+			case "STARTTLS":
+				reply("220 go ahead")
+				tc := tls.Server(conn, startTLS)
+				if tc.Handshake() != nil {
+					return
+				}
+				conn, r, s.tls = tc, bufio.NewReader(tc), true
+			case "MAIL":
+				s.from = strings.Trim(cmd[len("MAIL FROM:"):], "<>")
+				reply("250 ok")
+			case "RCPT":
+				rcpt := strings.Trim(cmd[len("RCPT TO:"):], "<>")
+				switch {
+				case strings.HasPrefix(rcpt, "nobody@"):
+					reply("550 no such user")
+				case strings.HasPrefix(rcpt, "busy@"):
+					reply("451 try again later")
+				default:
+					s.rcpt = append(s.rcpt, rcpt)
+					reply("250 ok")
+				}
+			case "DATA":
+				reply("354 end with .")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					b.WriteString(l)
+				}
+				s.data = b.String()
+				reply("250 queued")
+			case "QUIT":
+				reply("221 bye")
+				out <- s
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+	}()
+	return out
+}
+
+func TestMailer_StartTLSRelayMultipart(t *testing.T) {
+	caFile, srvCert := testCA(t, t.TempDir(), "mail")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := fakeSMTP(ln, &tls.Config{Certificates: []tls.Certificate{srvCert}})
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	m := newMailer(&config.SmtpConfig{
+		Host:       "127.0.0.1",
+		Port:       port,
+		TLS:        "starttls",
+		CAFile:     caFile,
+		From:       "LoopWarden <noc@example.com>",
+		To:         "ops@example.com",
+		Recipients: []string{"Guardia <oncall@example.com>"},
+	})
+	ev := sampleEvent()
+	ev.Incident = "eth0/MacStorm/HostFlood/aa:bb:cc:dd:ee:ff"
+	if err := m.Send(context.Background(), ev); err != nil {
+		t.Fatalf("Envío fallido: %v", err)
+	}
+
+	var s smtpSession
+	select {
+	case s = <-got:
+	case <-time.After(5 * time.Second):
+		t.Fatal("El servidor no recibió la sesión")
+	}
+	if !s.tls || s.from != "noc@example.com" || len(s.rcpt) != 2 || s.rcpt[1] != "oncall@example.com" {
+		t.Fatalf("Sobre incorrecto: %+v", s)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(s.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if want := "[LoopWarden] [WARNING] MacStorm on eth0: " + ev.Title; subject != want {
+		t.Errorf("Asunto %q, esperaba %q", subject, want)
+	}
+	if _, err := msg.Header.Date(); err != nil {
+		t.Errorf("Cabecera Date inválida: %v", err)
+	}
+	if id := msg.Header.Get("Message-ID"); !strings.HasSuffix(id, "@example.com>") {
+		t.Errorf("Message-ID inesperado: %q", id)
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type %q", mediaType)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var kinds []string
+	for {
+		p, err := mr.NextRawPart()
+		if err != nil {
+			break
+		}
+		body, _ := io.ReadAll(quotedprintable.NewReader(p))
+		kind, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		kinds = append(kinds, kind)
+		if kind == "text/html" && !strings.Contains(string(body), "<th") {
+			t.Errorf("Parte HTML sin tabla: %s", body)
+		}
+		if kind == "text/plain" && !strings.Contains(string(body), "SOURCE MAC: aa:bb:cc:dd:ee:ff") {
+			t.Errorf("Parte de texto incompleta: %s", body)
+		}
+	}
+	if len(kinds) != 2 || kinds[0] != "text/plain" || kinds[1] != "text/html" {
+		t.Errorf("Partes: %v", kinds)
+	}
+}
+
+func TestMailer_ImplicitTLS(t *testing.T) {
+	caFile, srvCert := testCA(t, t.TempDir(), "smtps")
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{srvCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	got := fakeSMTP(ln, nil)
+
+	port := ln.Addr().(*net.TCPAddr).Port
+	m := newMailer(&config.SmtpConfig{
+		Host: "127.0.0.1", Port: port, TLS: "implicit", CAFile: caFile,
+		From: "noc@example.com", To: "ops@example.com", Subject: "{{.Title}}",
+	})
+	if err := m.Send(context.Background(), sampleEvent()); err != nil {
+		t.Fatalf("Envío fallido: %v", err)
+	}
+	if s := <-got; !s.tls || len(s.rcpt) != 1 {
+		t.Errorf("Sesión inesperada: %+v", s)
+	}
+}
+
+
+// Un rechazo 5xx del servidor no se reintenta desde el spool; un 4xx sí.
+func TestMailer_RejectionIsPermanent(t *testing.T) {
+	for rcpt, retry := range map[string]bool{"nobody@example.com": false, "busy@example.com": true} {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		fakeSMTP(ln, nil)
+		m := newMailer(&config.SmtpConfig{
+			Host: "127.0.0.1", Port: ln.Addr().(*net.TCPAddr).Port,
+			From: "noc@example.com", To: rcpt,
+		})
+		err = m.Send(context.Background(), sampleEvent())
+		ln.Close()
+		if err == nil {
+			t.Fatalf("%s: el servidor rechazó el destinatario y Send no falló", rcpt)
+		}
+		if got := retryable(err); got != retry {
+			t.Errorf("%s: retryable(%v) = %v, esperado %v", rcpt, err, got, retry)
+		}
+	}
+}
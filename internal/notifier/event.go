@@ -0,0 +1,122 @@
+package notifier
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Severity ordena los eventos por gravedad.
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	default:
+		return "info"
+	}
+}
+
+// MarshalText hace que JSON (y TOML) usen el nombre y no el número.
+func (s Severity) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// UnmarshalText acepta "info", "warning" y "critical" (sin distinguir mayúsculas).
+func (s *Severity) UnmarshalText(b []byte) error {
+	switch strings.ToLower(strings.TrimSpace(string(b))) {
+	case "info":
+		*s = SeverityInfo
+	case "warning":
+		*s = SeverityWarning
+	case "critical":
+		*s = SeverityCritical
+	default:
+		return fmt.Errorf("unknown severity '%s' (info|warning|critical)", b)
+	}
+	return nil
+}
+
+// Status es la fase del ciclo de vida de una alerta.
+type Status string
+
+const (
+	StatusFiring   Status = "firing"   // La condición está presente
+	StatusResolved Status = "resolved" // La condición lleva hold_down sin observarse
+)
+
+// Detail es una línea de contexto adicional del evento (PATTERN, ANALYSIS, ACTION...).
+// Se conserva el orden en que el detector las añade.
+type Detail struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Event es una detección (o un evento del sistema) con sus datos estructurados.
+// Los detectores sólo rellenan campos; cada canal decide cómo representarlo
+// (texto plano, JSON o markdown de chat). Los campos vacíos no se muestran.
+type Event struct {
+	Time       time.Time `json:"timestamp"`
+	Sensor     string    `json:"sensor"` // Lo rellena el Notifier
+	Interface  string    `json:"interface,omitempty"`
+	Algorithm  string    `json:"algorithm"`   // "EtherFuse", "MacStorm"... o "System", "LinkMonitor"
+	ThreatType string    `json:"threat_type"` // Misma etiqueta que loopwarden_engine_hits_total
+	Severity   Severity  `json:"severity"`
+	Title      string    `json:"title"` // Titular legible: "🚨 LOOP DETECTED!"
+
+	VLAN      string `json:"vlan,omitempty"`       // Texto: "42", "100/42 (QinQ S/C)", "Native"
+	OuterVLAN uint16 `json:"outer_vlan,omitempty"` // IDs con los que encajan silencios y ventanas
+	InnerVLAN uint16 `json:"inner_vlan,omitempty"` // C-tag en QinQ
+	SrcMAC    string `json:"src_mac,omitempty"`
+	DstMAC    string `json:"dst_mac,omitempty"`
+	SrcIP     string `json:"src_ip,omitempty"`
+	DstIP     string `json:"dst_ip,omitempty"`
+	Rate      uint64 `json:"rate,omitempty"`      // Tasa observada (pps)
+	Threshold uint64 `json:"threshold,omitempty"` // Límite configurado que se ha superado
+
+	Details  []Detail `json:"details,omitempty"`
+	Evidence string   `json:"evidence,omitempty"` // Volcado pcapng (forense)
+
+	// --- Ciclo de vida (lo rellena el seguimiento de incidentes del detector) ---
+	Status    Status    `json:"status"`
+	Incident  string    `json:"incident,omitempty"`           // Clave estable: interfaz/algoritmo/amenaza[/sujeto]
+	StartedAt time.Time `json:"started_at,omitzero"`          // Primera detección del incidente
+	Duration  float64   `json:"duration_seconds,omitempty"`   // Resolved: de la primera a la última detección
+	Peak      uint64    `json:"peak_rate,omitempty"`          // Tasa máxima observada durante el incidente
+	Repeats   uint64    `json:"suppressed_repeats,omitempty"` // Detecciones silenciadas por el cooldown
+}
+
+// Resolved indica si el evento cierra un incidente.
+func (e Event) Resolved() bool { return e.Status == StatusResolved }
+
+// With añade una línea de contexto (encadenable).
+func (e Event) With(key, value string) Event {
+	e.Details = append(e.Details, Detail{Key: key, Value: value})
+	return e
+}
+
+// SystemEvent construye un evento informativo del propio LoopWarden.
+func SystemEvent(threatType, title string) Event {
+	return Event{Algorithm: "System", ThreatType: threatType, Severity: SeverityInfo, Title: title}
+}
+
+// dedupKey identifica el incidente ante los gestores de guardia (PagerDuty, Opsgenie):
+// sensor + interfaz + algoritmo + amenaza + MAC clave. Las repeticiones de una misma
+// tormenta actualizan un único incidente y el "resolved" lo cierra.
+func dedupKey(ev Event) string {
+	if ev.Incident != "" {
+		return ev.Sensor + "/" + ev.Incident
+	}
+	key := ev.Sensor + "/" + ev.Interface + "/" + ev.Algorithm + "/" + ev.ThreatType
+	if ev.SrcMAC != "" {
+		key += "/" + ev.SrcMAC
+	}
+	return key
+}
@@ -0,0 +1,131 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// fields devuelve las líneas del evento en el orden de presentación:
+// campos comunes, contexto del detector y evidencia al final.
+func fields(ev Event) []Detail {
+	var out []Detail
+	add := func(key, value string) {
+		if value != "" {
+			out = append(out, Detail{Key: key, Value: value})
+		}
+	}
+
+	add("INTERFACE", ev.Interface)
+	add("VLAN", ev.VLAN)
+	add("SOURCE MAC", ev.SrcMAC)
+	add("TARGET MAC", ev.DstMAC)
+	add("SOURCE IP", ev.SrcIP)
+	add("TARGET IP", ev.DstIP)
+	if ev.Rate > 0 {
+		rate := fmt.Sprintf("%d pps", ev.Rate)
+		if ev.Threshold > 0 {
+			rate += fmt.Sprintf(" (Threshold: %d)", ev.Threshold)
+		}
+		add("RATE", rate)
+	}
+	if ev.Resolved() {
+		add("DURATION", time.Duration(ev.Duration*float64(time.Second)).Round(time.Second).String())
+		if ev.Peak > 0 {
+			add("PEAK RATE", fmt.Sprintf("%d pps", ev.Peak))
+		}
+		add("SUPPRESSED", fmt.Sprintf("%d repeats", ev.Repeats))
+	} else if !ev.StartedAt.IsZero() && ev.Time.Sub(ev.StartedAt) >= time.Second {
+		// Re-alerta de un incidente que sigue abierto
+		add("ONGOING", fmt.Sprintf("since %s (%d repeats suppressed)", ev.StartedAt.Format("15:04:05"), ev.Repeats))
+	}
+	out = append(out, ev.Details...)
+	add("EVIDENCE", ev.Evidence)
+	return out
+}
+
+// header es la primera línea: "[sensor] [Algoritmo] Titular".
+func header(ev Event) string {
+	var b strings.Builder
+	if ev.Sensor != "" {
+		b.WriteString("[" + ev.Sensor + "] ")
+	}
+	if ev.Algorithm != "" {
+		b.WriteString("[" + ev.Algorithm + "] ")
+	}
+	b.WriteString(ev.Title)
+	return b.String()
+}
+
+// FormatText es la representación multilínea clásica (consola, syslog, email).
+func FormatText(ev Event) string {
+	lines := fields(ev)
+
+	width := 10
+	for _, f := range lines {
+		if len(f.Key)+2 > width {
+			width = len(f.Key) + 2
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(header(ev))
+	for _, f := range lines {
+		fmt.Fprintf(&b, "\n    %-*s%s", width, f.Key+":", f.Value)
+	}
+	return b.String()
+}
+
+// FormatLine es FormatText en una sola línea (Syslog: los colectores parten por saltos de línea).
+func FormatLine(ev Event) string {
+	var b strings.Builder
+	b.WriteString(header(ev))
+	for _, f := range fields(ev) {
+		b.WriteString(" | " + f.Key + ": " + f.Value)
+	}
+	return b.String()
+}
+
+// FormatSummary es una sola línea (asunto de email, vistas previas de chat).
+func FormatSummary(ev Event) string {
+	s := fmt.Sprintf("[%s] %s", strings.ToUpper(ev.Severity.String()), ev.Title)
+	if ev.Interface != "" {
+		s += " on " + ev.Interface
+	}
+	return s
+}
+
+// truncate acota s a max bytes (límites de campo de las APIs) sin partir una runa.
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	cut := max - len("…")
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…"
+}
+
+// FormatJSON serializa el evento completo para integraciones máquina a máquina.
+func FormatJSON(ev Event) ([]byte, error) {
+	return json.Marshal(ev)
+}
+
+// FormatMarkdown usa el markdown de los chats (negrita para etiquetas, código para valores).
+func FormatMarkdown(ev Event) string {
+	var b strings.Builder
+	b.WriteString("*" + escapeMarkdown(header(ev)) + "*")
+	for _, f := range fields(ev) {
+		fmt.Fprintf(&b, "\n*%s:* `%s`", f.Key, strings.ReplaceAll(f.Value, "`", "'"))
+	}
+	return b.String()
+}
+
+// escapeMarkdown protege los caracteres con significado en markdown fuera de los bloques de código.
+func escapeMarkdown(s string) string {
+	r := strings.NewReplacer("_", "\\_", "*", "\\*", "`", "\\`", "[", "\\[")
+	return r.Replace(s)
+}
@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sampleEvent() Event {
+	return Event{
+		Time:       time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC),
+		Sensor:     "sensor-01",
+		Interface:  "eth0",
+		Algorithm:  "MacStorm",
+		ThreatType: "HostFlood",
+		Severity:   SeverityWarning,
+		Title:      "🌪️ HOST FLOODING DETECTED!",
+		VLAN:       "10",
+		SrcMAC:     "aa:bb:cc:dd:ee:ff",
+		Rate:       4200,
+		Threshold:  2000,
+	}.With("PATTERN", "Flooding Broadcast")
+}
+
+func TestFormatText_OrderAndAlignment(t *testing.T) {
+	got := FormatText(sampleEvent())
+	want := "[sensor-01] [MacStorm] 🌪️ HOST FLOODING DETECTED!\n" +
+		"    INTERFACE:  eth0\n" +
+		"    VLAN:       10\n" +
+		"    SOURCE MAC: aa:bb:cc:dd:ee:ff\n" +
+		"    RATE:       4200 pps (Threshold: 2000)\n" +
+		"    PATTERN:    Flooding Broadcast"
+	if got != want {
+		t.Errorf("FormatText:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestFormatJSON_Fields(t *testing.T) {
+	b, err := FormatJSON(sampleEvent())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["severity"] != "warning" || m["threat_type"] != "HostFlood" || m["src_mac"] != "aa:bb:cc:dd:ee:ff" {
+		t.Errorf("Campos JSON inesperados: %s", b)
+	}
+	if _, ok := m["dst_ip"]; ok {
+		t.Errorf("Los campos vacíos deben omitirse: %s", b)
+	}
+
+	var back Event
+	if err := json.Unmarshal(b, &back); err != nil || back.Severity != SeverityWarning || back.Rate != 4200 {
+		t.Errorf("Ida y vuelta JSON fallida: %+v (%v)", back, err)
+	}
+}
+
+func TestFormatMarkdown_Escapes(t *testing.T) {
+	ev := sampleEvent()
+	ev.Title = "LOOP_DETECTED"
+	got := FormatMarkdown(ev)
+	if !strings.HasPrefix(got, "*\\[sensor-01] \\[MacStorm] LOOP\\_DETECTED*") {
+		t.Errorf("Cabecera markdown sin escapar: %q", got)
+	}
+	if !strings.Contains(got, "*RATE:* `4200 pps (Threshold: 2000)`") {
+		t.Errorf("Falta la línea RATE: %q", got)
+	}
+}
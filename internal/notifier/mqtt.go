@@ -0,0 +1,421 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+// Tipos de paquete MQTT (nibble alto del primer byte).
+const (
+	mqttConnect    = 1
+	mqttConnack    = 2
+	mqttPublish    = 3
+	mqttPuback     = 4
+	mqttPubrec     = 5
+	mqttPubrel     = 6
+	mqttPubcomp    = 7
+	mqttPingreq    = 12
+	mqttPingresp   = 13
+	mqttDisconnect = 14
+)
+
+// mqttClient publica los eventos en un broker MQTT 3.1.1 o 5 con una conexión
+// persistente. Al conectar publica "online" (retenido) en <prefix>/status y deja
+// "offline" como Last Will: si el sensor cae, el broker lo anuncia por él.
+type mqttClient struct {
+	addr      string
+	useTLS    bool
+	tlsCfg    *tls.Config
+	tlsErr    error // CA/certificado ilegible: cada envío falla (y se reintenta) con este error
+	version   byte  // 4 = 3.1.1, 5 = 5.0
+	clientID  string
+	user      string
+	pass      string
+	qos       byte
+	retain    bool
+	prefix    string // <topic_prefix>/<sensor>
+	keepAlive time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	r      *bufio.Reader
+	nextID uint16
+}
+
+func newMQTTClient(cfg *config.MqttConfig, sensor string) *mqttClient {
+	c := &mqttClient{
+		version:   4,
+		clientID:  cfg.ClientID,
+		user:      cfg.User,
+		pass:      cfg.Pass,
+		qos:       byte(cfg.QoS),
+		retain:    cfg.Retain,
+		keepAlive: 30 * time.Second,
+	}
+	if cfg.Version == "5" { c.version = 5 }
+	if c.qos > 2 { c.qos = 1 }
+	if c.clientID == "" { c.clientID = "loopwarden-" + mqttLevel(sensor, "sensor") }
+	if d, err := time.ParseDuration(cfg.KeepAlive); err == nil && d >= time.Second {
+		c.keepAlive = d
+	}
+
+	prefix := strings.Trim(cfg.TopicPrefix, "/")
+	if prefix == "" { prefix = "loopwarden" }
+	c.prefix = prefix + "/" + mqttLevel(sensor, "sensor")
+
+	u, err := url.Parse(cfg.Broker)
+	if err != nil || u.Host == "" {
+		c.addr = cfg.Broker // El error aparecerá al conectar
+	} else {
+		c.addr = u.Host
+		c.useTLS = u.Scheme == "tls" || u.Scheme == "ssl" || u.Scheme == "mqtts"
+		if u.Port() == "" {
+			port := "1883"
+			if c.useTLS { port = "8883" }
+			c.addr = net.JoinHostPort(u.Hostname(), port)
+		}
+	}
+	if c.useTLS {
+		c.tlsCfg, c.tlsErr = clientTLSConfig(c.addr, cfg.ServerName, cfg.CAFile, cfg.CertFile, cfg.KeyFile)
+		if c.tlsErr != nil {
+			log.Printf("❌ [Notifier] MQTT TLS setup failed: %v", c.tlsErr)
+		}
+	}
+
+	scheme := "tcp"
+	if c.useTLS { scheme = "tls" }
+	ver := "3.1.1"
+	if c.version == 5 { ver = "5" }
+	log.Printf("🛰️ [Notifier] MQTT %s -> %s://%s (QoS %d, topics %s/<iface>/<algorithm>)", ver, scheme, c.addr, c.qos, c.prefix)
+	return c
+}
+
+// mqttLevel limpia un nivel de topic: sin separadores ni comodines.
+func mqttLevel(s, def string) string {
+	s = strings.NewReplacer("/", "_", "+", "_", "#", "_").Replace(strings.TrimSpace(s))
+	if s == "" {
+		return def
+	}
+	return s
+}
+
+func (c *mqttClient) statusTopic() string { return c.prefix + "/status" }
+
+// topic: <prefix>/<interfaz>/<algoritmo>; los eventos sin interfaz van a "global".
+func (c *mqttClient) topic(ev Event) string {
+	return c.prefix + "/" + mqttLevel(ev.Interface, "global") + "/" + mqttLevel(ev.Algorithm, "unknown")
+}
+
+// Send publica el evento; si la conexión estaba rota se reconecta y reintenta una vez.
+func (c *mqttClient) Send(ctx context.Context, ev Event) error {
+	payload, err := FormatJSON(ev)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if err = c.connect(ctx); err != nil {
+				return err
+			}
+		}
+		stop := bindConn(ctx, c.conn)
+		err = c.publish(c.topic(ev), payload, c.retain)
+		stop()
+		if err == nil {
+			return nil
+		}
+		c.drop()
+	}
+	return err
+}
+
+// connect abre la sesión y anuncia "online", todo dentro del plazo de ctx. Requiere c.mu.
+func (c *mqttClient) connect(ctx context.Context) error {
+	dialer := &net.Dialer{KeepAlive: 30 * time.Second}
+	var conn net.Conn
+	var err error
+	if c.useTLS {
+		if c.tlsErr != nil {
+			return c.tlsErr
+		}
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: c.tlsCfg}).DialContext(ctx, "tcp", c.addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		return err
+	}
+	c.conn, c.r = conn, bufio.NewReader(conn)
+	defer bindConn(ctx, conn)()
+
+	if err := c.write(mqttConnect<<4, c.connectPacket()); err != nil {
+		c.drop()
+		return err
+	}
+	hdr, body, err := readMQTTPacket(c.r)
+	if err == nil && (hdr>>4 != mqttConnack || len(body) < 2) {
+		err = fmt.Errorf("unexpected packet 0x%02X instead of CONNACK", hdr)
+	}
+	if err == nil && body[1] != 0 {
+		err = fmt.Errorf("connection refused: %s", mqttConnackReason(c.version, body[1]))
+	}
+	if err == nil {
+		err = c.publish(c.statusTopic(), []byte("online"), true)
+	}
+	if err != nil {
+		c.drop()
+		return fmt.Errorf("mqtt %s: %w", c.addr, err)
+	}
+	log.Printf("🛰️ [Notifier] MQTT connected to %s as %s", c.addr, c.clientID)
+	return nil
+}
+
+func (c *mqttClient) connectPacket() []byte {
+	flags := byte(0x02) | 0x04 | c.qos<<3 | 0x20 // Clean session + Will (QoS, retenido)
+	if c.user != "" {
+		flags |= 0x80
+		if c.pass != "" { flags |= 0x40 }
+	}
+	ka := uint16(c.keepAlive / time.Second)
+
+	p := mqttString(nil, "MQTT")
+	p = append(p, c.version, flags, byte(ka>>8), byte(ka))
+	if c.version == 5 {
+		p = append(p, 0) // Sin propiedades
+	}
+	p = mqttString(p, c.clientID)
+	if c.version == 5 {
+		p = append(p, 0) // Propiedades del Will
+	}
+	p = mqttString(p, c.statusTopic())
+	p = mqttString(p, "offline")
+	if c.user != "" {
+		p = mqttString(p, c.user)
+		if c.pass != "" { p = mqttString(p, c.pass) }
+	}
+	return p
+}
+
+// publish envía un PUBLISH y espera la confirmación que exija la QoS. Requiere c.mu
+// y una conexión ya ligada al plazo del llamante (bindConn).
+func (c *mqttClient) publish(topic string, payload []byte, retain bool) error {
+	body := mqttString(nil, topic)
+	var id uint16
+	if c.qos > 0 {
+		c.nextID++
+		if c.nextID == 0 { c.nextID = 1 }
+		id = c.nextID
+		body = binary.BigEndian.AppendUint16(body, id)
+	}
+	if c.version == 5 {
+		body = append(body, 0)
+	}
+	body = append(body, payload...)
+
+	hdr := byte(mqttPublish<<4) | c.qos<<1
+	if retain { hdr |= 0x01 }
+	if err := c.write(hdr, body); err != nil {
+		return err
+	}
+	switch c.qos {
+	case 1:
+		return c.await(mqttPuback, id)
+	case 2:
+		if err := c.await(mqttPubrec, id); err != nil {
+			return err
+		}
+		if err := c.write(mqttPubrel<<4|0x02, binary.BigEndian.AppendUint16(nil, id)); err != nil {
+			return err
+		}
+		return c.await(mqttPubcomp, id)
+	}
+	return nil
+}
+
+// await lee hasta recibir el paquete indicado (PUBACK/PUBREC/PUBCOMP de id, o PINGRESP).
+// En MQTT 5 un reason code >= 0x80 es un rechazo del broker.
+func (c *mqttClient) await(typ byte, id uint16) error {
+	for {
+		hdr, body, err := readMQTTPacket(c.r)
+		if err != nil {
+			return err
+		}
+		switch {
+		case hdr>>4 == mqttDisconnect:
+			return errors.New("disconnected by broker")
+		case hdr>>4 != typ:
+			continue
+		case len(body) >= 2 && binary.BigEndian.Uint16(body) != id:
+			continue
+		case len(body) >= 3 && body[2] >= 0x80:
+			return fmt.Errorf("publish rejected by broker (reason 0x%02X)", body[2])
+		}
+		return nil
+	}
+}
+
+// ping mantiene viva la sesión: sin tráfico durante 1.5 × keep_alive el broker la
+// cerraría y publicaría el Will ("offline"). Requiere c.mu, como publish.
+func (c *mqttClient) ping() error {
+	if err := c.write(mqttPingreq<<4, nil); err != nil {
+		return err
+	}
+	return c.await(mqttPingresp, 0)
+}
+
+func (c *mqttClient) write(hdr byte, body []byte) error {
+	pkt := []byte{hdr}
+	for n := len(body); ; {
+		b := byte(n % 128)
+		if n /= 128; n > 0 {
+			b |= 0x80
+		}
+		pkt = append(pkt, b)
+		if n == 0 {
+			break
+		}
+	}
+	_, err := c.conn.Write(append(pkt, body...))
+	return err
+}
+
+// drop cierra la conexión sin DISCONNECT (el broker publicará el Will). Requiere c.mu.
+func (c *mqttClient) drop() {
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn, c.r = nil, nil
+	}
+}
+
+// close anuncia "offline" y se desconecta limpiamente (sin Will).
+func (c *mqttClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	defer bindConn(ctx, c.conn)()
+	if err := c.publish(c.statusTopic(), []byte("offline"), true); err != nil {
+		log.Printf("⚠️ [Notifier] MQTT: cannot publish offline status: %v", err)
+	}
+	c.write(mqttDisconnect<<4, nil)
+	c.drop()
+}
+
+// run conecta al arrancar (el estado "online" no espera a la primera alerta),
+// mantiene la sesión con PINGREQ y reconecta si se pierde.
+func (c *mqttClient) run(done <-chan struct{}) {
+	ticker := time.NewTicker(c.keepAlive / 2)
+	defer ticker.Stop()
+
+	up := true // Fuerza el aviso del primer fallo
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		c.mu.Lock()
+		var err error
+		if c.conn == nil {
+			err = c.connect(ctx)
+		} else {
+			stop := bindConn(ctx, c.conn)
+			if err = c.ping(); err != nil {
+				c.drop()
+			}
+			stop()
+		}
+		c.mu.Unlock()
+		cancel()
+		if err != nil && up {
+			log.Printf("⚠️ [Notifier] MQTT broker unavailable, retrying every %v: %v", c.keepAlive/2, err)
+		}
+		up = err == nil
+
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// readMQTTPacket lee un paquete: primer byte (tipo y flags) y cuerpo.
+func readMQTTPacket(r *bufio.Reader) (byte, []byte, error) {
+	hdr, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	n, mult := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(b&0x7F) * mult
+		if b&0x80 == 0 {
+			break
+		}
+		mult *= 128
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return hdr, body, nil
+}
+
+// mqttString añade una cadena con su longitud (UTF-8 / binary data de la especificación).
+func mqttString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+func mqttConnackReason(version, code byte) string {
+	if version == 5 {
+		switch code {
+		case 0x84:
+			return "unsupported protocol version"
+		case 0x85:
+			return "client identifier not valid"
+		case 0x86:
+			return "bad user name or password"
+		case 0x87:
+			return "not authorized"
+		case 0x88:
+			return "server unavailable"
+		}
+	} else {
+		switch code {
+		case 1:
+			return "unacceptable protocol version"
+		case 2:
+			return "identifier rejected"
+		case 3:
+			return "server unavailable"
+		case 4:
+			return "bad user name or password"
+		case 5:
+			return "not authorized"
+		}
+	}
+	return fmt.Sprintf("code 0x%02X", code)
+}
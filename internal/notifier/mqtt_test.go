@@ -0,0 +1,182 @@
+package notifier
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+// mqttSession es lo que recibe fakeBroker de un cliente.
+type mqttSession struct {
+	version              byte
+	user, pass           string
+	willTopic, willValue string
+	publishes            []mqttMessage
+	disconnected         bool
+}
+
+type mqttMessage struct {
+	topic   string
+	payload string
+	qos     byte
+	retain  bool
+}
+
+// fakeBroker atiende una sesión MQTT 3.1.1/5 mínima: CONNACK, confirmaciones de
+// QoS 1/2 y PINGRESP. Devuelve la sesión al recibir DISCONNECT.
+func fakeBroker(ln net.Listener) <-chan mqttSession {
+	out := make(chan mqttSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		var s mqttSession
+		str := func(b []byte) (string, []byte) {
+			n := binary.BigEndian.Uint16(b)
+			return string(b[2 : 2+n]), b[2+n:]
+		}
+		reply := func(hdr byte, body ...byte) { conn.Write(append([]byte{hdr, byte(len(body))}, body...)) }
+
+		for {
+			hdr, body, err := readMQTTPacket(r)
+			if err != nil {
+				return
+			}
+			switch hdr >> 4 {
+			case mqttConnect:
+				_, body = str(body)
+				s.version, body = body[0], body[1:]
+				flags := body[0]
+				body = body[3:]
+				if s.version == 5 {
+					body = body[1+body[0]:]
+				}
+				_, body = str(body) // client id
+				if s.version == 5 {
+					body = body[1+body[0]:]
+				}
+				s.willTopic, body = str(body)
+				s.willValue, body = str(body)
+				if flags&0x80 != 0 {
+					s.user, body = str(body)
+				}
+				if flags&0x40 != 0 {
+					s.pass, _ = str(body)
+				}
+				if s.version == 5 {
+					reply(mqttConnack<<4, 0, 0, 0)
+				} else {
+					reply(mqttConnack<<4, 0, 0)
+				}
+			case mqttPublish:
+				m := mqttMessage{qos: hdr >> 1 & 0x03, retain: hdr&0x01 != 0}
+				m.topic, body = str(body)
+				var id []byte
+				if m.qos > 0 {
+					id, body = body[:2], body[2:]
+				}
+				if s.version == 5 {
+					body = body[1+body[0]:]
+				}
+				m.payload = string(body)
+				s.publishes = append(s.publishes, m)
+				switch m.qos {
+				case 1:
+					reply(mqttPuback<<4, id...)
+				case 2:
+					reply(mqttPubrec<<4, id...)
+				}
+			case mqttPubrel:
+				reply(mqttPubcomp<<4, body[:2]...)
+			case mqttPingreq:
+				reply(mqttPingresp << 4)
+			case mqttDisconnect:
+				s.disconnected = true
+				out <- s
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func TestMQTT_PublishesEventsAndStatus(t *testing.T) {
+	caFile, srvCert := testCA(t, t.TempDir(), "broker")
+	for _, tc := range []struct {
+		name    string
+		version string
+		qos     int
+		tls     bool
+	}{
+		{"3.1.1 QoS1", "3.1.1", 1, false},
+		{"5 QoS2 TLS", "5", 2, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var ln net.Listener
+			var err error
+			scheme := "tcp"
+			if tc.tls {
+				scheme = "tls"
+				ln, err = tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{srvCert}})
+			} else {
+				ln, err = net.Listen("tcp", "127.0.0.1:0")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+			got := fakeBroker(ln)
+
+			n := NewNotifier(&config.AlertsConfig{Mqtt: config.MqttConfig{
+				Enabled: true,
+				Broker:  scheme + "://" + ln.Addr().String(),
+				Version: tc.version,
+				User:    "sensor",
+				Pass:    "secret",
+				QoS:     tc.qos,
+				CAFile:  caFile,
+			}}, "sensor-01")
+			n.Notify(sampleEvent())
+			n.Close(5 * time.Second)
+
+			var s mqttSession
+			select {
+			case s = <-got:
+			case <-time.After(5 * time.Second):
+				t.Fatal("El broker no recibió la sesión")
+			}
+
+			wantVersion := byte(4)
+			if tc.version == "5" { wantVersion = 5 }
+			if s.version != wantVersion || s.user != "sensor" || s.pass != "secret" {
+				t.Errorf("CONNECT incorrecto: %+v", s)
+			}
+			if s.willTopic != "loopwarden/sensor-01/status" || s.willValue != "offline" {
+				t.Errorf("Last Will: %q = %q", s.willTopic, s.willValue)
+			}
+			if len(s.publishes) != 3 {
+				t.Fatalf("Esperaba online, evento y offline: %+v", s.publishes)
+			}
+			online, event, offline := s.publishes[0], s.publishes[1], s.publishes[2]
+			if online.payload != "online" || !online.retain || offline.payload != "offline" || !offline.retain {
+				t.Errorf("Estado retenido incorrecto: %+v / %+v", online, offline)
+			}
+			var ev Event
+			if err := json.Unmarshal([]byte(event.payload), &ev); err != nil || ev.SrcMAC != "aa:bb:cc:dd:ee:ff" {
+				t.Errorf("Payload JSON inválido (%v): %s", err, event.payload)
+			}
+			if event.topic != "loopwarden/sensor-01/eth0/MacStorm" || event.qos != byte(tc.qos) || event.retain {
+				t.Errorf("Publicación del evento: %+v", event)
+			}
+		})
+	}
+}
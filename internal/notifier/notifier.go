@@ -2,48 +2,73 @@ package notifier
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"net"
 	"net/http"
-	"net/smtp"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/telemetry"
 )
 
-const alertBufferSize = 100
-
 type Notifier struct {
-	cfg        *config.AlertsConfig
-	sensorName string
-	alertChan  chan string
-	client     *http.Client
+	cfg          *config.AlertsConfig
+	sensorName   string
+	client       *http.Client
+	router       *router
+	silences     *silencer
+
+	// --- Entrega: un sink, una cola (spool) y un repartidor por canal activo ---
+	sinks        map[string]Sink
+	spools       map[string]*spool
+	maxAge       time.Duration
+	retryInitial time.Duration
+	retryMax     time.Duration
+	done         chan struct{}
+	stop         context.CancelFunc // Interrumpe los envíos en curso al cerrar
+	ctx          context.Context
+	wg           sync.WaitGroup
 
 	// --- Configuración Efectiva (Dampening) ---
 	maxAlertsPerMin int
 	muteDuration    time.Duration
+	digestMode      bool          // false: modo "mute" clásico
+	digestInterval  time.Duration
+	bypass          bool          // false: todas las alertas cuentan y se retienen (clásico)
+	bypassSeverity  Severity      // Desde esta gravedad no se aplica dampening
 
 	mu            sync.Mutex
 	alertCount    int
 	windowStart   time.Time
-	isMuted       bool
+	isMuted       bool // Dampening activo (mute o digest)
 	mutedUntil    time.Time
 	droppedAlerts int
+	digests       map[string]*digestGroup
+	digestOrder   []string
+	lastDigest    time.Time
+	notified      map[string]bool // Incidentes con algún aviso no silenciado: su cierre se envía
 }
 
 func NewNotifier(cfg *config.AlertsConfig, sensorName string) *Notifier {
 	n := &Notifier{
 		cfg:        cfg,
 		sensorName: sensorName,
-		alertChan:  make(chan string, alertBufferSize),
-		client: &http.Client{
-			Timeout: 5 * time.Second,
-		},
+		// Sin Timeout global: cada envío lleva el timeout de su canal en el contexto
+		client:      &http.Client{},
 		windowStart: time.Now(),
+		digests:     make(map[string]*digestGroup),
+		notified:    make(map[string]bool),
+		router:      newRouter(cfg),
+		sinks:       make(map[string]Sink),
+		spools:      make(map[string]*spool),
+		done:        make(chan struct{}),
 	}
+	n.ctx, n.stop = context.WithCancel(context.Background())
 
 	// 1. Cargar Configuración de Dampening
 	n.maxAlertsPerMin = cfg.Dampening.MaxAlertsPerMinute
@@ -64,35 +89,140 @@ func NewNotifier(cfg *config.AlertsConfig, sensorName string) *Notifier {
 		n.muteDuration = 60 * time.Second
 	}
 
-	log.Printf("🔔 [Notifier] Initialized. Dampening: Max %d alerts/min, Silence for %v", n.maxAlertsPerMin, n.muteDuration)
+	n.digestMode = strings.EqualFold(cfg.Dampening.Mode, "digest") // "" = mute clásico
+	n.digestInterval = time.Minute
+	if cfg.Dampening.DigestInterval != "" {
+		if d, err := time.ParseDuration(cfg.Dampening.DigestInterval); err == nil && d > 0 {
+			n.digestInterval = d
+		} else {
+			log.Printf("⚠️ [Notifier] Invalid DigestInterval '%s', defaulting to 1m", cfg.Dampening.DigestInterval)
+		}
+	}
+	// Sin bypass_severity ninguna alerta salta el dampening: en un bucle real cada MAC
+	// genera su alerta crítica y son justo las que inundan los canales
+	bypassNote := ""
+	if cfg.Dampening.BypassSeverity != "" {
+		if err := n.bypassSeverity.UnmarshalText([]byte(cfg.Dampening.BypassSeverity)); err != nil {
+			log.Printf("⚠️ [Notifier] Invalid bypass_severity: %v, no alerts bypass dampening", err)
+		} else {
+			n.bypass = true
+			bypassNote = fmt.Sprintf(" (%s alerts bypass)", n.bypassSeverity)
+		}
+	}
+
+	if n.digestMode {
+		log.Printf("🔔 [Notifier] Initialized. Dampening: Max %d alerts/min, then digests every %v for %v%s",
+			n.maxAlertsPerMin, n.digestInterval, n.muteDuration, bypassNote)
+		n.wg.Add(1)
+		go n.runDigest()
+	} else {
+		log.Printf("🔔 [Notifier] Initialized. Dampening: Max %d alerts/min, Silence for %v%s",
+			n.maxAlertsPerMin, n.muteDuration, bypassNote)
+	}
+
+	// 3. Spool y reintentos
+	sp := cfg.Spool
+	n.maxAge = spoolDuration("max_age", sp.MaxAge, 24*time.Hour)
+	n.retryInitial = spoolDuration("retry_initial", sp.RetryInitial, 2*time.Second)
+	n.retryMax = spoolDuration("retry_max", sp.RetryMax, 5*time.Minute)
+	maxSizeMB := sp.MaxSizeMB
+	if maxSizeMB <= 0 { maxSizeMB = 10 }
+	if n.retryMax < n.retryInitial { n.retryMax = n.retryInitial }
 
-	go n.worker()
+	n.silences = newSilencer(cfg)
+
+	for _, kind := range sinkKinds {
+		for _, ch := range kind.Channels(cfg) {
+			sink := kind.Build(n, ch)
+			n.sinks[ch] = sink
+			if r, ok := sink.(sinkRunner); ok {
+				n.wg.Add(1)
+				go func() {
+					defer n.wg.Done()
+					r.run(n.done)
+				}()
+			}
+			s := openSpool(ch, sp.Directory, int64(maxSizeMB)<<20)
+			n.spools[ch] = s
+			n.wg.Add(1)
+			go n.deliver(s, sink, sinkTimeout(cfg, ch, kind.Timeout))
+		}
+	}
+	if len(n.spools) > 0 {
+		where := sp.Directory
+		if where == "" { where = "memory only" }
+		log.Printf("📮 [Notifier] Spool: %s, max %d MB/channel, max age %v, retry %v..%v",
+			where, maxSizeMB, n.maxAge, n.retryInitial, n.retryMax)
+	}
 	return n
 }
 
-func (n *Notifier) Alert(msg string) {
-	// Precepto #8: String Concatenation.
-	taggedMsg := fmt.Sprintf("[%s] %s", n.sensorName, msg)
+// spoolDuration interpreta una duración de [alerts.spool] con su default.
+func spoolDuration(key, value string, def time.Duration) time.Duration {
+	if value == "" {
+		return def
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		log.Printf("⚠️ [Notifier] Invalid spool %s '%s', defaulting to %v", key, value, def)
+		return def
+	}
+	return d
+}
+
+// Notify encola un evento para los canales que le asigne [alerts.routing] (aplicando dampening).
+// Sensor y Time se completan aquí si el detector no los ha fijado.
+func (n *Notifier) Notify(ev Event) {
+	ev.Sensor = n.sensorName
 
 	n.mu.Lock()
 	now := time.Now()
+	if ev.Time.IsZero() {
+		ev.Time = now
+	}
+
+	// Silencios y ventanas de mantenimiento: decisión explícita del operador, se
+	// aplican a cualquier severidad. El evento queda en el log y en las métricas.
+	// El cierre de un incidente ya notificado nunca se silencia: dejaría abierto el
+	// incidente en PagerDuty/Alertmanager si la condición desaparece durante el silencio.
+	closesNotified := ev.Resolved() && n.notified[ev.Incident]
+	if ev.Resolved() {
+		delete(n.notified, ev.Incident)
+	}
+	if by := n.silences.match(ev, now); by != "" && !closesNotified {
+		n.mu.Unlock()
+		telemetry.AlertsSuppressed.WithLabelValues(ev.Algorithm, "silenced").Inc()
+		log.Printf("🔕 [Notifier] Silenced by %s\n%s", by, FormatText(ev))
+		return
+	}
+
+	if ev.Incident != "" && !ev.Resolved() {
+		n.notified[ev.Incident] = true
+	}
+
+	// Los cierres de incidente nunca se retienen (un "resolved" perdido deja abierto el
+	// incidente en PagerDuty/Alertmanager), ni las alertas desde bypass_severity si se ha
+	// configurado.
+	if (n.bypass && ev.Severity >= n.bypassSeverity) || ev.Resolved() {
+		n.mu.Unlock()
+		n.dispatch(ev)
+		return
+	}
 
 	if n.isMuted {
 		if now.Before(n.mutedUntil) {
-			n.droppedAlerts++
+			n.suppress(ev)
 			n.mu.Unlock()
 			return
 		}
 		// Fin del silencio
-		n.isMuted = false
-		summary := fmt.Sprintf("⚠️ [System] Resuming alerts. Dropped %d messages.", n.droppedAlerts)
-		n.droppedAlerts = 0
-		n.windowStart = now
-		n.alertCount = 0
+		out := n.resume(now)
 		n.mu.Unlock()
 
-		n.dispatch(fmt.Sprintf("[%s] %s", n.sensorName, summary))
-		n.dispatch(taggedMsg)
+		for _, e := range out {
+			n.dispatch(e)
+		}
+		n.dispatch(ev)
 		return
 	}
 
@@ -109,88 +239,234 @@ func (n *Notifier) Alert(msg string) {
 		n.isMuted = true
 		n.mutedUntil = now.Add(n.muteDuration) // Usamos variable de instancia
 		
-		warning := fmt.Sprintf("[%s] ⛔ [System] FLOOD PROTECTION. Silencing for %v...", n.sensorName, n.muteDuration)
+		title := fmt.Sprintf("⛔ FLOOD PROTECTION. Silencing for %v...", n.muteDuration)
+		if n.digestMode {
+			title = fmt.Sprintf("📦 FLOOD PROTECTION. Grouping alerts into digests every %v for %v...", n.digestInterval, n.muteDuration)
+		}
+		warning := n.system("FloodProtection", title, now)
+		warning.Severity = SeverityWarning
+		n.lastDigest = now
+		n.suppress(ev)
 		n.mu.Unlock()
 		n.dispatch(warning)
 		return
 	}
 	n.mu.Unlock()
 
-	n.dispatch(taggedMsg)
+	n.dispatch(ev)
+}
+
+// SetMaintenance aplica las ventanas de mantenimiento de una configuración recargada.
+func (n *Notifier) SetMaintenance(windows []config.MaintenanceWindow) {
+	n.silences.setMaintenance(windows)
+}
+
+// system construye los avisos internos del propio Notifier.
+func (n *Notifier) system(threatType, title string, now time.Time) Event {
+	ev := SystemEvent(threatType, title)
+	ev.Sensor = n.sensorName
+	ev.Time = now
+	return ev
 }
 
-func (n *Notifier) dispatch(msg string) {
-	log.Println(msg)
-	select {
-	case n.alertChan <- msg:
-	default:
-		// Drop silencioso si el canal interno está lleno (Backpressure extremo)
+// dispatch registra el evento y lo encola en el spool de cada canal destino.
+// Nunca descarta: si un canal no responde, su cola crece (hasta max_size_mb).
+func (n *Notifier) dispatch(ev Event) {
+	if ev.Status == "" {
+		ev.Status = StatusFiring
+	}
+	log.Println(FormatText(ev))
+	for _, ch := range n.router.channelsFor(ev) {
+		if s, ok := n.spools[ch]; ok {
+			s.push(ev)
+		}
 	}
 }
 
-func (n *Notifier) worker() {
-	for msg := range n.alertChan {
-		if n.cfg.Webhook.Enabled {
-			n.sendWebhook(msg)
+// deliver vacía el spool de un canal en orden. Si el envío falla, el evento se queda
+// en cabeza y se reintenta con backoff exponencial (retry_initial .. retry_max).
+// Cada intento tiene como mucho timeout: un canal colgado sólo retrasa su propia cola.
+func (n *Notifier) deliver(s *spool, sink Sink, timeout time.Duration) {
+	defer n.wg.Done()
+	backoff := n.retryInitial
+
+	for {
+		e, ok := s.peek()
+		if !ok {
+			select {
+			case <-s.wake:
+				continue
+			case <-n.done:
+				return
+			}
 		}
-		if n.cfg.SyslogServer != "" {
-			n.sendSyslog(msg)
+
+		if age := time.Since(e.queued); age > n.maxAge {
+			log.Printf("⚠️ [Notifier] %s: dropping undelivered alert queued at %s (older than %v)",
+				s.channel, e.queued.Format(time.RFC3339), n.maxAge)
+			telemetry.AlertSpoolDropped.WithLabelValues(s.channel, "expired").Inc()
+			s.remove(e.seq)
+			continue
 		}
-		if n.cfg.Smtp.Enabled {
-			n.sendEmail(msg)
+
+		ctx, cancel := context.WithTimeout(n.ctx, timeout)
+		start := time.Now()
+		err := sink.Send(ctx, e.ev)
+		cancel()
+		if n.ctx.Err() != nil {
+			return // Apagado: el evento sigue en el spool
 		}
-		if n.cfg.Telegram.Enabled {
-			n.sendTelegram(msg)
+		telemetry.AlertDeliverySeconds.WithLabelValues(s.channel).Observe(time.Since(start).Seconds())
+
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				err = fmt.Errorf("timed out after %v: %w", timeout, err)
+			}
+			telemetry.AlertDeliveries.WithLabelValues(s.channel, "failed").Inc()
+			if !retryable(err) {
+				// Reintentar no lo arreglará y bloquearía la cola del canal hasta max_age
+				log.Printf("⚠️ [Notifier] %s rejected the alert, dropping it: %v", s.channel, err)
+				telemetry.AlertSpoolDropped.WithLabelValues(s.channel, "rejected").Inc()
+				s.remove(e.seq)
+				continue
+			}
+			log.Printf("⚠️ [Notifier] %s delivery failed (%d queued, retry in %v): %v", s.channel, s.len(), backoff, err)
+
+			retry := time.NewTimer(backoff)
+			select {
+			case <-retry.C:
+			case <-n.done:
+				retry.Stop()
+				return
+			}
+			backoff *= 2
+			if backoff > n.retryMax {
+				backoff = n.retryMax
+			}
+			continue
 		}
+
+		telemetry.AlertDeliveries.WithLabelValues(s.channel, "sent").Inc()
+		s.remove(e.seq)
+		backoff = n.retryInitial
 	}
 }
 
-func (n *Notifier) sendWebhook(msg string) {
-	payload := map[string]string{"text": msg}
-	jsonBody, _ := json.Marshal(payload)
-	resp, err := n.client.Post(n.cfg.Webhook.URL, "application/json", bytes.NewBuffer(jsonBody))
-	if err != nil {
-		log.Printf("⚠️ [Notifier] Webhook failed: %v", err)
-		return
+// Close espera (como mucho timeout) a que se vacíen las colas y para los repartidores.
+// Lo que no se haya podido entregar queda en el spool de disco para el siguiente arranque.
+func (n *Notifier) Close(timeout time.Duration) {
+	n.mu.Lock()
+	out := n.flushDigests(time.Now())
+	n.mu.Unlock()
+	for _, ev := range out {
+		n.dispatch(ev)
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) && n.pending() > 0 {
+		time.Sleep(100 * time.Millisecond)
+	}
+	close(n.done)
+	n.stop()
+	n.wg.Wait()
+	for _, sink := range n.sinks {
+		if c, ok := sink.(sinkCloser); ok {
+			c.close()
+		}
 	}
-	resp.Body.Close()
 }
 
-func (n *Notifier) sendTelegram(msg string) {
-	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", n.cfg.Telegram.Token)
-	payload := map[string]string{
-		"chat_id": n.cfg.Telegram.ChatID,
-		"text":    msg,
+// pending cuenta las alertas sin entregar de todos los canales.
+func (n *Notifier) pending() int {
+	total := 0
+	for _, s := range n.spools {
+		total += s.len()
 	}
+	return total
+}
+
+// sendWebhook mantiene "text" (compatible con Slack/Mattermost) y añade el evento estructurado.
+// Con format = "slack" | "teams" | "discord" se envía el mensaje nativo de ese chat.
+func (n *Notifier) sendWebhook(ctx context.Context, ev Event) error {
+	if n.cfg.Webhook.Format != "" {
+		body, err := formatChat(n.cfg.Webhook.Format, ev)
+		if err != nil {
+			return permanent(err)
+		}
+		return n.postJSON(ctx, n.cfg.Webhook.URL, body)
+	}
+	payload := struct {
+		Text  string `json:"text"`
+		Event Event  `json:"event"`
+	}{FormatText(ev), ev}
 	jsonBody, _ := json.Marshal(payload)
-	resp, err := n.client.Post(url, "application/json", bytes.NewBuffer(jsonBody))
+	return n.postJSON(ctx, n.cfg.Webhook.URL, jsonBody)
+}
+
+// postJSON hace el POST y trata cualquier respuesta no 2xx como fallo.
+func (n *Notifier) postJSON(ctx context.Context, url string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
 	if err != nil {
-		log.Printf("⚠️ [Notifier] Telegram failed: %v", err)
-		return
+		return permanent(err)
 	}
-	resp.Body.Close()
+	req.Header.Set("Content-Type", "application/json")
+	return n.do(req)
 }
 
-func (n *Notifier) sendSyslog(msg string) {
-	conn, err := net.DialTimeout("udp", n.cfg.SyslogServer, 2*time.Second)
+// do ejecuta la petición de un canal HTTP (con el contexto del envío): cualquier
+// respuesta no 2xx es un *HTTPError.
+func (n *Notifier) do(req *http.Request) error {
+	resp, err := n.client.Do(req)
 	if err != nil {
-		log.Printf("⚠️ [Notifier] Syslog failed: %v", err)
-		return
+		return err
 	}
-	defer conn.Close()
-	timestamp := time.Now().Format(time.RFC3339)
-	fmt.Fprintf(conn, "<132>%s LoopWarden: %s", timestamp, msg)
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
 }
 
-func (n *Notifier) sendEmail(msg string) {
-	auth := smtp.PlainAuth("", n.cfg.Smtp.User, n.cfg.Smtp.Pass, n.cfg.Smtp.Host)
-	addr := fmt.Sprintf("%s:%d", n.cfg.Smtp.Host, n.cfg.Smtp.Port)
-	subject := "Subject: [LoopWarden] Network Alert\n"
-	mime := "MIME-version: 1.0;\nContent-Type: text/plain; charset=\"UTF-8\";\n\n"
-	body := []byte(subject + mime + msg)
+// HTTPError es la respuesta no 2xx de un canal HTTP.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
 
-	err := smtp.SendMail(addr, auth, n.cfg.Smtp.From, []string{n.cfg.Smtp.To}, body)
-	if err != nil {
-		log.Printf("⚠️ [Notifier] SMTP failed: %v", err)
+func (e *HTTPError) Error() string { return "HTTP " + e.Status }
+
+// permanentError marca un fallo que ningún reintento arreglará (plantilla rota,
+// URL inválida...).
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+func permanent(err error) error { return permanentError{err} }
+
+// retryable decide si un envío fallido se reintenta desde el spool: errores de red y
+// timeouts, HTTP 5xx, 408 y 429. Un 4xx o un error marcado con permanent (mensaje mal
+// construido, rechazo SMTP 5xx) se repetirían igual en cada intento.
+func retryable(err error) bool {
+	var perm permanentError
+	if errors.As(err, &perm) {
+		return false
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		code := httpErr.StatusCode
+		return code >= 500 || code == http.StatusRequestTimeout || code == http.StatusTooManyRequests
 	}
+	return true
+}
+
+func (n *Notifier) sendTelegram(ctx context.Context, ev Event) error {
+	url := fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage", n.cfg.Telegram.Token)
+	payload := map[string]string{
+		"chat_id":    n.cfg.Telegram.ChatID,
+		"text":       FormatMarkdown(ev),
+		"parse_mode": "Markdown",
+	}
+	jsonBody, _ := json.Marshal(payload)
+	return n.postJSON(ctx, url, jsonBody)
 }
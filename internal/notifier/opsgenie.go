@@ -0,0 +1,76 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const opsgenieURL = "https://api.opsgenie.com"
+
+// ogAlert es el cuerpo de POST /v2/alerts. Con el mismo alias abierto, Opsgenie no crea
+// una alerta nueva: incrementa el contador de la existente.
+type ogAlert struct {
+	Message     string            `json:"message"`
+	Alias       string            `json:"alias"`
+	Description string            `json:"description,omitempty"`
+	Source      string            `json:"source,omitempty"`
+	Entity      string            `json:"entity,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Details     map[string]string `json:"details,omitempty"`
+	Priority    string            `json:"priority"`
+}
+
+// sendOpsgenie crea la alerta (alias = dedup key) o la cierra con el evento "resolved".
+func (n *Notifier) sendOpsgenie(ctx context.Context, ev Event) error {
+	base := strings.TrimSuffix(n.cfg.Opsgenie.URL, "/")
+	if base == "" { base = opsgenieURL }
+	alias := truncate(dedupKey(ev), 512)
+
+	var target string
+	var body any
+	if ev.Resolved() {
+		target = base + "/v2/alerts/" + url.PathEscape(alias) + "/close?identifierType=alias"
+		body = map[string]string{"source": ev.Sensor, "note": FormatLine(ev)}
+	} else {
+		message := truncate(FormatSummary(ev), 130)
+		details := make(map[string]string)
+		for _, f := range fields(ev) {
+			details[sdParamName(f.Key)] = f.Value
+		}
+		target = base + "/v2/alerts"
+		body = ogAlert{
+			Message:     message,
+			Alias:       alias,
+			Description: FormatText(ev),
+			Source:      ev.Sensor,
+			Entity:      ev.Interface,
+			Tags:        append([]string{ev.Algorithm, ev.ThreatType}, n.cfg.Opsgenie.Tags...),
+			Details:     details,
+			Priority:    opsgeniePriority(ev.Severity),
+		}
+	}
+
+	jsonBody, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(jsonBody))
+	if err != nil {
+		return permanent(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "GenieKey "+n.cfg.Opsgenie.APIKey)
+	return n.do(req)
+}
+
+func opsgeniePriority(s Severity) string {
+	switch s {
+	case SeverityCritical:
+		return "P1"
+	case SeverityWarning:
+		return "P3"
+	default:
+		return "P5"
+	}
+}
@@ -0,0 +1,70 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+func TestOpsgenie_CreateAndCloseByAlias(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	var created []ogAlert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "GenieKey s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.RequestURI())
+		if r.URL.Path == "/v2/alerts" {
+			var a ogAlert
+			json.NewDecoder(r.Body).Decode(&a)
+			created = append(created, a)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&config.AlertsConfig{
+		Opsgenie: config.OpsgenieConfig{Enabled: true, APIKey: "s3cr3t", URL: srv.URL, Tags: []string{"network"}},
+	}, "sensor-01")
+
+	loop := sampleEvent()
+	loop.Severity = SeverityCritical
+	loop.Incident = "eth0/EtherFuse/LoopDetected"
+	n.Notify(loop)
+	resolved := loop
+	resolved.Status = StatusResolved
+	n.Notify(resolved)
+	// Límites de la API: message 130 y alias 512 bytes, sin partir runas
+	long := sampleEvent()
+	long.Title = "x" + strings.Repeat("€", 200)
+	long.Incident = "eth0/MacStorm/HostFlood/" + strings.Repeat("€", 200)
+	n.Notify(long)
+	n.Close(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"/v2/alerts", "/v2/alerts/sensor-01%2Feth0%2FEtherFuse%2FLoopDetected/close?identifierType=alias", "/v2/alerts"}
+	if len(paths) != 3 || paths[0] != want[0] || paths[1] != want[1] || paths[2] != want[2] {
+		t.Fatalf("Peticiones: %v, esperaba %v", paths, want)
+	}
+	if c := created[0]; c.Alias != "sensor-01/eth0/EtherFuse/LoopDetected" || c.Priority != "P1" || c.Entity != "eth0" {
+		t.Errorf("Alerta creada incorrecta: %+v", c)
+	}
+	if created[0].Details["vlan"] != "10" || created[0].Details["pattern"] != "Flooding Broadcast" {
+		t.Errorf("Detalles incompletos: %v", created[0].Details)
+	}
+	if m, a := created[1].Message, created[1].Alias; len(m) > 130 || !utf8.ValidString(m) || len(a) > 512 || !utf8.ValidString(a) {
+		t.Errorf("Campos fuera de límite: message %d bytes, alias %d bytes", len(m), len(a))
+	}
+}
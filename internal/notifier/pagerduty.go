@@ -0,0 +1,58 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+const pagerDutyURL = "https://events.pagerduty.com/v2/enqueue"
+
+// pdEvent es el cuerpo de la Events API v2. Payload sólo viaja en los "trigger".
+type pdEvent struct {
+	RoutingKey  string     `json:"routing_key"`
+	EventAction string     `json:"event_action"` // "trigger" | "resolve"
+	DedupKey    string     `json:"dedup_key"`
+	Payload     *pdPayload `json:"payload,omitempty"`
+}
+
+type pdPayload struct {
+	Summary       string `json:"summary"`
+	Source        string `json:"source"`
+	Severity      string `json:"severity"` // "critical" | "error" | "warning" | "info"
+	Timestamp     string `json:"timestamp"`
+	Component     string `json:"component,omitempty"`
+	Group         string `json:"group,omitempty"`
+	Class         string `json:"class,omitempty"`
+	CustomDetails Event  `json:"custom_details"`
+}
+
+// sendPagerDuty abre (o actualiza, por dedup_key) el incidente y lo resuelve con el
+// evento "resolved". PagerDuty agrupa los trigger repetidos en el mismo incidente.
+func (n *Notifier) sendPagerDuty(ctx context.Context, ev Event) error {
+	url := n.cfg.PagerDuty.URL
+	if url == "" { url = pagerDutyURL }
+
+	pe := pdEvent{
+		RoutingKey:  n.cfg.PagerDuty.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    truncate(dedupKey(ev), 255),
+	}
+	if ev.Resolved() {
+		pe.EventAction = "resolve"
+	} else {
+		pe.Payload = &pdPayload{
+			Summary:       truncate(FormatSummary(ev), 1024),
+			Source:        ev.Sensor,
+			Severity:      ev.Severity.String(),
+			Timestamp:     ev.Time.UTC().Format(time.RFC3339),
+			Component:     ev.Interface,
+			Group:         ev.Algorithm,
+			Class:         ev.ThreatType,
+			CustomDetails: ev,
+		}
+	}
+
+	jsonBody, _ := json.Marshal(pe)
+	return n.postJSON(ctx, url, jsonBody)
+}
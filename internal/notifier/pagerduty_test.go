@@ -0,0 +1,70 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+func TestPagerDuty_TriggerDedupAndResolve(t *testing.T) {
+	var mu sync.Mutex
+	var got []pdEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var pe pdEvent
+		json.NewDecoder(r.Body).Decode(&pe)
+		mu.Lock()
+		got = append(got, pe)
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&config.AlertsConfig{
+		PagerDuty: config.PagerDutyConfig{Enabled: true, RoutingKey: "R0UT1NG", URL: srv.URL},
+	}, "sensor-01")
+
+	// La misma tormenta repetida cada cooldown, y después su cierre
+	storm := sampleEvent()
+	storm.Severity = SeverityCritical
+	storm.Incident = "eth0/MacStorm/HostFlood/aa:bb:cc:dd:ee:ff"
+	n.Notify(storm)
+	n.Notify(storm)
+	resolved := storm
+	resolved.Status = StatusResolved
+	n.Notify(resolved)
+	// Límites de la Events API: dedup_key 255 y summary 1024 bytes, sin partir runas
+	long := sampleEvent()
+	long.Title = "x" + strings.Repeat("€", 400)
+	long.Incident = "eth0/MacStorm/HostFlood/" + strings.Repeat("€", 100)
+	n.Notify(long)
+	n.Close(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 4 {
+		t.Fatalf("Esperaba 4 eventos, recibí %d", len(got))
+	}
+	key := "sensor-01/eth0/MacStorm/HostFlood/aa:bb:cc:dd:ee:ff"
+	for i, action := range []string{"trigger", "trigger", "resolve"} {
+		if got[i].EventAction != action || got[i].DedupKey != key || got[i].RoutingKey != "R0UT1NG" {
+			t.Errorf("Evento %d: %s %q (esperaba %s %q)", i, got[i].EventAction, got[i].DedupKey, action, key)
+		}
+	}
+	p := got[0].Payload
+	if p == nil || p.Severity != "critical" || p.Source != "sensor-01" || p.Component != "eth0" || p.Class != "HostFlood" {
+		t.Errorf("Payload del trigger incorrecto: %+v", p)
+	}
+	if got[2].Payload != nil {
+		t.Error("El resolve no lleva payload")
+	}
+	if k, sum := got[3].DedupKey, got[3].Payload.Summary; len(k) > 255 || !utf8.ValidString(k) || len(sum) > 1024 || !utf8.ValidString(sum) {
+		t.Errorf("Campos fuera de límite: dedup_key %d bytes, summary %d bytes", len(k), len(sum))
+	}
+}
@@ -0,0 +1,123 @@
+package notifier
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+// Nombres de canal usados en [alerts.routing].
+const (
+	ChannelWebhook  = "webhook"
+	ChannelSyslog   = "syslog"
+	ChannelSmtp     = "smtp"
+	ChannelTelegram = "telegram"
+
+	ChannelAlertmanager = "alertmanager"
+	ChannelPagerDuty    = "pagerduty"
+	ChannelOpsgenie     = "opsgenie"
+	ChannelMQTT         = "mqtt"
+)
+
+// route es una regla de [alerts.routing] ya compilada (listas -> sets).
+type route struct {
+	minSeverity Severity
+	algorithms  map[string]bool
+	threatTypes map[string]bool
+	interfaces  map[string]bool
+	channels    []string
+}
+
+// router elige los canales de cada evento: primera regla que encaja o el catch-all.
+type router struct {
+	rules    []route
+	defaults []string
+}
+
+func newRouter(cfg *config.AlertsConfig) *router {
+	// Los canales, como los criterios, no distinguen mayúsculas: se traducen al nombre
+	// del canal activo ("Webhook:OPS" -> "webhook:ops"). Un canal enrutado pero
+	// desactivado no envía nada: mejor avisar al arrancar.
+	active := make(map[string]string)
+	for _, ch := range enabledChannels(cfg) {
+		active[strings.ToLower(ch)] = ch
+	}
+	resolve := func(where string, list []string) []string {
+		var out []string
+		for _, ch := range list {
+			name, ok := active[strings.ToLower(strings.TrimSpace(ch))]
+			if !ok {
+				log.Printf("⚠️ [Notifier] %s routes to channel '%s' which is not enabled", where, ch)
+				continue
+			}
+			out = append(out, name)
+		}
+		return out
+	}
+
+	r := &router{defaults: resolve("Routing default", cfg.Routing.Default)}
+	// Sin catch-all explícito: todos los canales activos (comportamiento clásico)
+	if len(cfg.Routing.Default) == 0 {
+		r.defaults = enabledChannels(cfg)
+	}
+
+	for i, rule := range cfg.Routing.Rules {
+		rt := route{
+			algorithms:  toSet(rule.Algorithms),
+			threatTypes: toSet(rule.ThreatTypes),
+			interfaces:  toSet(rule.Interfaces),
+			channels:    resolve(fmt.Sprintf("Routing rule %d", i), rule.Channels),
+		}
+		if rule.MinSeverity != "" {
+			if err := rt.minSeverity.UnmarshalText([]byte(rule.MinSeverity)); err != nil {
+				log.Printf("⚠️ [Notifier] Routing rule %d: %v, matching any severity", i, err)
+			}
+		}
+		r.rules = append(r.rules, rt)
+	}
+
+	if len(r.rules) > 0 {
+		log.Printf("🔀 [Notifier] Routing: %d rules, default -> %v", len(r.rules), r.defaults)
+	}
+	return r
+}
+
+// channelsFor devuelve los canales destino del evento.
+func (r *router) channelsFor(ev Event) []string {
+	for _, rt := range r.rules {
+		if rt.matches(ev) {
+			return rt.channels
+		}
+	}
+	return r.defaults
+}
+
+func (rt *route) matches(ev Event) bool {
+	if ev.Severity < rt.minSeverity {
+		return false
+	}
+	if len(rt.algorithms) > 0 && !rt.algorithms[strings.ToLower(ev.Algorithm)] {
+		return false
+	}
+	if len(rt.threatTypes) > 0 && !rt.threatTypes[strings.ToLower(ev.ThreatType)] {
+		return false
+	}
+	if len(rt.interfaces) > 0 && !rt.interfaces[strings.ToLower(ev.Interface)] {
+		return false
+	}
+	return true
+}
+
+// toSet normaliza a minúsculas: los nombres de la config no distinguen mayúsculas.
+func toSet(list []string) map[string]bool {
+	if len(list) == 0 {
+		return nil
+	}
+	m := make(map[string]bool, len(list))
+	for _, s := range list {
+		m[strings.ToLower(strings.TrimSpace(s))] = true
+	}
+	return m
+}
@@ -0,0 +1,81 @@
+package notifier
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+func TestRouter_FirstMatchAndDefault(t *testing.T) {
+	cfg := &config.AlertsConfig{
+		SyslogServer: "127.0.0.1:514",
+		Webhook:      config.WebhookConfig{Enabled: true},
+		Telegram:     config.TelegramConfig{Enabled: true},
+		Routing: config.RoutingConfig{
+			Default: []string{"syslog", "webhook"},
+			Rules: []config.RouteRule{
+				{MinSeverity: "critical", Algorithms: []string{"EtherFuse"}, Channels: []string{"telegram", "syslog"}},
+				{Algorithms: []string{"ActiveProbe"}, ThreatTypes: []string{"HardLoop"}, Channels: []string{"telegram"}},
+				{Algorithms: []string{"MacStorm", "arpwatchdog"}, Channels: []string{"syslog"}},
+				{Interfaces: []string{"lab0"}},
+			},
+		},
+	}
+	r := newRouter(cfg)
+
+	cases := []struct {
+		name string
+		ev   Event
+		want []string
+	}{
+		{"EtherFuse crítico", Event{Algorithm: "EtherFuse", Severity: SeverityCritical}, []string{"telegram", "syslog"}},
+		{"EtherFuse warning cae al default", Event{Algorithm: "EtherFuse", Severity: SeverityWarning}, []string{"syslog", "webhook"}},
+		{"HardLoop", Event{Algorithm: "ActiveProbe", ThreatType: "HardLoop", Severity: SeverityCritical}, []string{"telegram"}},
+		{"CrossDomain no es HardLoop", Event{Algorithm: "ActiveProbe", ThreatType: "CrossDomainLoop"}, []string{"syslog", "webhook"}},
+		{"MacStorm sólo syslog", Event{Algorithm: "MacStorm", Severity: SeverityWarning}, []string{"syslog"}},
+		{"Sin distinguir mayúsculas", Event{Algorithm: "ArpWatchdog"}, []string{"syslog"}},
+		{"Regla sin canales descarta", Event{Algorithm: "DhcpHunter", Interface: "lab0"}, nil},
+	}
+	for _, c := range cases {
+		if got := r.channelsFor(c.ev); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: canales %v, esperado %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRouter_NoRulesUsesEnabledChannels(t *testing.T) {
+	cfg := &config.AlertsConfig{
+		SyslogServer: "127.0.0.1:514",
+		Smtp:         config.SmtpConfig{Enabled: true},
+	}
+	got := newRouter(cfg).channelsFor(Event{Algorithm: "MacStorm"})
+	if want := []string{"syslog", "smtp"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sin routing deben usarse todos los canales activos: %v", got)
+	}
+}
+
+func TestRouter_ChannelNamesIgnoreCase(t *testing.T) {
+	cfg := &config.AlertsConfig{
+		SyslogServer: "127.0.0.1:514",
+		Webhooks:     []config.WebhookTarget{{Name: "NetOps", Enabled: true, URL: "https://example.com/hook"}},
+		Spool:        config.SpoolConfig{Timeouts: map[string]string{"Webhook:netops": "3s"}},
+		Routing: config.RoutingConfig{
+			Default: []string{"SYSLOG"},
+			Rules: []config.RouteRule{
+				{Algorithms: []string{"EtherFuse"}, Channels: []string{" webhook:netops", "Telegram"}},
+			},
+		},
+	}
+	r := newRouter(cfg)
+	if got := r.channelsFor(Event{Algorithm: "EtherFuse"}); !reflect.DeepEqual(got, []string{"webhook:NetOps"}) {
+		t.Errorf("La regla debe resolver al canal activo (y omitir el desactivado): %v", got)
+	}
+	if got := r.channelsFor(Event{Algorithm: "MacStorm"}); !reflect.DeepEqual(got, []string{"syslog"}) {
+		t.Errorf("Default: %v", got)
+	}
+	if got := sinkTimeout(cfg, "webhook:NetOps", time.Second); got != 3*time.Second {
+		t.Errorf("Timeout por canal sin distinguir mayúsculas: %v", got)
+	}
+}
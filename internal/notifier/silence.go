@@ -0,0 +1,353 @@
+package notifier
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+// Silence es un silencio creado en caliente (API local / "loopwarden silence").
+// Los criterios vacíos encajan con cualquier valor; al menos uno es obligatorio.
+type Silence struct {
+	ID         string    `json:"id"`
+	Interfaces []string  `json:"interfaces,omitempty"`
+	Algorithms []string  `json:"algorithms,omitempty"`
+	MACs       []string  `json:"macs,omitempty"` // Origen o destino del evento
+	VLANs      []string  `json:"vlans,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedBy  string    `json:"created_by,omitempty"`
+	StartsAt   time.Time `json:"starts_at"`
+	EndsAt     time.Time `json:"ends_at"`
+
+	scope scope
+}
+
+// MaintenanceStatus describe una ventana de [[alerts.maintenance]] en el listado.
+type MaintenanceStatus struct {
+	Name   string    `json:"name"`
+	Active bool      `json:"active"`
+	Until  time.Time `json:"until,omitzero"` // Activa: fin de la ocurrencia en curso
+	Next   time.Time `json:"next,omitzero"`  // Inactiva: próximo inicio
+}
+
+// scope son los criterios de un silencio o ventana ya compilados (listas -> sets).
+type scope struct {
+	interfaces map[string]bool
+	algorithms map[string]bool
+	macs       map[string]bool
+	vlans      map[string]bool // Tal cual, en minúsculas: "42", "100/42", "native"
+	vlanIDs    map[uint16]bool // Las entradas numéricas
+}
+
+func newScope(interfaces, algorithms, macs, vlans []string) scope {
+	// Misma representación que los eventos: "aa:bb:cc:dd:ee:ff"
+	var normalized []string
+	for _, m := range macs {
+		if hw, err := net.ParseMAC(strings.TrimSpace(m)); err == nil {
+			m = hw.String()
+		}
+		normalized = append(normalized, m)
+	}
+	ids := make(map[uint16]bool)
+	for _, v := range vlans {
+		if id, err := strconv.ParseUint(strings.TrimSpace(v), 10, 12); err == nil {
+			ids[uint16(id)] = true
+		}
+	}
+	return scope{
+		interfaces: toSet(interfaces),
+		algorithms: toSet(algorithms),
+		macs:       toSet(normalized),
+		vlans:      toSet(vlans),
+		vlanIDs:    ids,
+	}
+}
+
+func (s scope) empty() bool {
+	return len(s.interfaces) == 0 && len(s.algorithms) == 0 && len(s.macs) == 0 && len(s.vlans) == 0
+}
+
+func (s scope) matches(ev Event) bool {
+	in := func(set map[string]bool, values ...string) bool {
+		if len(set) == 0 {
+			return true
+		}
+		for _, v := range values {
+			if v != "" && set[strings.ToLower(v)] {
+				return true
+			}
+		}
+		return false
+	}
+	return in(s.interfaces, ev.Interface) && in(s.algorithms, ev.Algorithm) &&
+		in(s.macs, ev.SrcMAC, ev.DstMAC) && s.matchesVLAN(ev)
+}
+
+// matchesVLAN compara por ID: "42" encaja con la etiqueta externa o la interna (una
+// trama QinQ 100/42 encaja con "100" y con "42"), "100/42" con ese par exacto y
+// "native" con las tramas sin etiquetar.
+func (s scope) matchesVLAN(ev Event) bool {
+	if len(s.vlans) == 0 {
+		return true
+	}
+	if (ev.OuterVLAN != 0 && s.vlanIDs[ev.OuterVLAN]) || (ev.InnerVLAN != 0 && s.vlanIDs[ev.InnerVLAN]) {
+		return true
+	}
+	if ev.InnerVLAN != 0 && s.vlans[fmt.Sprintf("%d/%d", ev.OuterVLAN, ev.InnerVLAN)] {
+		return true
+	}
+	return ev.VLAN != "" && s.vlans[strings.ToLower(ev.VLAN)]
+}
+
+// maintenanceWindow es una ventana recurrente ya compilada.
+type maintenanceWindow struct {
+	name         string
+	days         map[time.Weekday]bool // Vacío = todos los días
+	hour, minute int
+	length       time.Duration
+	loc          *time.Location
+	scope        scope
+}
+
+func newMaintenanceWindow(w config.MaintenanceWindow) (maintenanceWindow, error) {
+	mw := maintenanceWindow{name: w.Name, days: make(map[time.Weekday]bool), loc: time.Local}
+	for _, d := range w.Days {
+		day, ok := config.ParseWeekday(d)
+		if !ok {
+			return mw, fmt.Errorf("unknown day '%s'", d)
+		}
+		mw.days[day] = true
+	}
+	start, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return mw, fmt.Errorf("invalid start '%s'", w.Start)
+	}
+	mw.hour, mw.minute = start.Hour(), start.Minute()
+	if mw.length, err = time.ParseDuration(w.Duration); err != nil || mw.length <= 0 {
+		return mw, fmt.Errorf("invalid duration '%s'", w.Duration)
+	}
+	if w.Timezone != "" {
+		if mw.loc, err = time.LoadLocation(w.Timezone); err != nil {
+			return mw, err
+		}
+	}
+	mw.scope = newScope(w.Interfaces, w.Algorithms, w.MACs, w.VLANs)
+	return mw, nil
+}
+
+// startOn es el inicio de la ocurrencia del día indicado (si ese día tiene ventana).
+func (w maintenanceWindow) startOn(day time.Time) (time.Time, bool) {
+	y, m, d := day.Date()
+	start := time.Date(y, m, d, w.hour, w.minute, 0, 0, w.loc)
+	return start, len(w.days) == 0 || w.days[start.Weekday()]
+}
+
+// current devuelve el fin de la ocurrencia activa en now. Una ventana puede haber
+// empezado días atrás (duraciones largas o que cruzan la medianoche).
+func (w maintenanceWindow) current(now time.Time) (time.Time, bool) {
+	now = now.In(w.loc)
+	for back := 0; back <= int(w.length/(24*time.Hour))+1; back++ {
+		start, ok := w.startOn(now.AddDate(0, 0, -back))
+		if ok && !now.Before(start) && now.Before(start.Add(w.length)) {
+			return start.Add(w.length), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// next devuelve el próximo inicio posterior a now.
+func (w maintenanceWindow) next(now time.Time) time.Time {
+	now = now.In(w.loc)
+	for fwd := 0; fwd <= 7; fwd++ {
+		if start, ok := w.startOn(now.AddDate(0, 0, fwd)); ok && start.After(now) {
+			return start
+		}
+	}
+	return time.Time{}
+}
+
+// silencer decide si un evento está silenciado (silencio en caliente o ventana de
+// mantenimiento) y persiste los silencios en disco.
+type silencer struct {
+	file string // "" = sólo memoria
+
+	mu       sync.Mutex
+	silences []*Silence
+	windows  []maintenanceWindow
+}
+
+func newSilencer(cfg *config.AlertsConfig) *silencer {
+	s := &silencer{file: cfg.Silences.File}
+	s.setMaintenance(cfg.Maintenance)
+	if s.file != "" {
+		s.load()
+	}
+	return s
+}
+
+// setMaintenance sustituye las ventanas de mantenimiento (arranque y SIGHUP).
+func (s *silencer) setMaintenance(list []config.MaintenanceWindow) {
+	var windows []maintenanceWindow
+	for _, w := range list {
+		mw, err := newMaintenanceWindow(w)
+		if err != nil {
+			log.Printf("⚠️ [Notifier] Maintenance window '%s' ignored: %v", w.Name, err)
+			continue
+		}
+		windows = append(windows, mw)
+	}
+	s.mu.Lock()
+	s.windows = windows
+	s.mu.Unlock()
+	if len(windows) > 0 {
+		log.Printf("🔕 [Notifier] %d maintenance windows configured", len(windows))
+	}
+}
+
+// match devuelve qué silencia el evento ("" = nada).
+func (s *silencer) match(ev Event, now time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sil := range s.silences {
+		if !now.Before(sil.StartsAt) && now.Before(sil.EndsAt) && sil.scope.matches(ev) {
+			return "silence " + sil.ID
+		}
+	}
+	for _, w := range s.windows {
+		if _, ok := w.current(now); ok && w.scope.matches(ev) {
+			return fmt.Sprintf("maintenance '%s'", w.name)
+		}
+	}
+	return ""
+}
+
+// add valida y registra un silencio nuevo.
+func (s *silencer) add(sil Silence, now time.Time) (Silence, error) {
+	for _, m := range sil.MACs {
+		if _, err := net.ParseMAC(strings.TrimSpace(m)); err != nil {
+			return sil, fmt.Errorf("invalid MAC '%s'", m)
+		}
+	}
+	sil.scope = newScope(sil.Interfaces, sil.Algorithms, sil.MACs, sil.VLANs)
+	if sil.scope.empty() {
+		return sil, errors.New("at least one of interfaces, algorithms, macs or vlans is required")
+	}
+	if sil.StartsAt.IsZero() { sil.StartsAt = now }
+	if !sil.EndsAt.After(now) || !sil.EndsAt.After(sil.StartsAt) {
+		return sil, errors.New("ends_at must be in the future and after starts_at")
+	}
+	id := make([]byte, 4)
+	rand.Read(id)
+	sil.ID = hex.EncodeToString(id)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prune(now)
+	s.silences = append(s.silences, &sil)
+	s.save()
+	log.Printf("🔕 [Notifier] Silence %s created until %s (%s)", sil.ID, sil.EndsAt.Format(time.RFC3339), sil.Comment)
+	return sil, nil
+}
+
+// expire retira un silencio antes de tiempo.
+func (s *silencer) expire(id string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, sil := range s.silences {
+		if sil.ID == id {
+			s.silences = append(s.silences[:i], s.silences[i+1:]...)
+			s.prune(now)
+			s.save()
+			log.Printf("🔔 [Notifier] Silence %s expired", id)
+			return true
+		}
+	}
+	return false
+}
+
+// list devuelve los silencios vigentes o futuros y el estado de las ventanas.
+func (s *silencer) list(now time.Time) ([]Silence, []MaintenanceStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.prune(now) {
+		s.save()
+	}
+	silences := make([]Silence, 0, len(s.silences))
+	for _, sil := range s.silences {
+		silences = append(silences, *sil)
+	}
+	windows := make([]MaintenanceStatus, 0, len(s.windows))
+	for _, w := range s.windows {
+		st := MaintenanceStatus{Name: w.name}
+		if until, ok := w.current(now); ok {
+			st.Active, st.Until = true, until
+		} else {
+			st.Next = w.next(now)
+		}
+		windows = append(windows, st)
+	}
+	return silences, windows
+}
+
+// prune descarta los silencios caducados. Requiere s.mu.
+func (s *silencer) prune(now time.Time) bool {
+	kept := s.silences[:0]
+	for _, sil := range s.silences {
+		if now.Before(sil.EndsAt) {
+			kept = append(kept, sil)
+		}
+	}
+	changed := len(kept) != len(s.silences)
+	s.silences = kept
+	return changed
+}
+
+// save persiste los silencios. Requiere s.mu.
+func (s *silencer) save() {
+	if s.file == "" {
+		return
+	}
+	data, _ := json.MarshalIndent(s.silences, "", "  ")
+	err := os.MkdirAll(filepath.Dir(s.file), 0750)
+	if err == nil {
+		err = writeFileAtomic(s.file, data)
+	}
+	if err != nil {
+		log.Printf("⚠️ [Notifier] Cannot persist silences in %s: %v", s.file, err)
+	}
+}
+
+func (s *silencer) load() {
+	data, err := os.ReadFile(s.file)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	var list []*Silence
+	if err == nil {
+		err = json.Unmarshal(data, &list)
+	}
+	if err != nil {
+		log.Printf("⚠️ [Notifier] Cannot load silences from %s: %v", s.file, err)
+		return
+	}
+	for _, sil := range list {
+		sil.scope = newScope(sil.Interfaces, sil.Algorithms, sil.MACs, sil.VLANs)
+	}
+	s.silences = list
+	s.prune(time.Now())
+	if len(s.silences) > 0 {
+		log.Printf("🔕 [Notifier] %d active silences restored from %s", len(s.silences), s.file)
+	}
+}
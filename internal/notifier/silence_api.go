@@ -0,0 +1,106 @@
+package notifier
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// SilenceRequest es el cuerpo de POST /api/v1/silences. La duración se puede dar
+// relativa ("2h") o como fin absoluto (ends_at).
+type SilenceRequest struct {
+	Interfaces []string  `json:"interfaces,omitempty"`
+	Algorithms []string  `json:"algorithms,omitempty"`
+	MACs       []string  `json:"macs,omitempty"`
+	VLANs      []string  `json:"vlans,omitempty"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedBy  string    `json:"created_by,omitempty"`
+	Duration   string    `json:"duration,omitempty"`
+	StartsAt   time.Time `json:"starts_at,omitzero"`
+	EndsAt     time.Time `json:"ends_at,omitzero"`
+}
+
+// SilenceList es la respuesta de GET /api/v1/silences.
+type SilenceList struct {
+	Silences    []Silence           `json:"silences"`
+	Maintenance []MaintenanceStatus `json:"maintenance"`
+}
+
+// SilenceAPI devuelve la API local de silencios:
+//
+//	GET    /api/v1/silences        silencios vigentes y estado de las ventanas
+//	POST   /api/v1/silences        crea un silencio (SilenceRequest)
+//	DELETE /api/v1/silences/{id}   lo expira
+//
+// Con [alerts.silences] token, todas las peticiones exigen "Authorization: Bearer <token>".
+func (n *Notifier) SilenceAPI() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/silences", func(w http.ResponseWriter, r *http.Request) {
+		silences, windows := n.silences.list(time.Now())
+		writeJSON(w, http.StatusOK, SilenceList{Silences: silences, Maintenance: windows})
+	})
+	mux.HandleFunc("POST /api/v1/silences", func(w http.ResponseWriter, r *http.Request) {
+		var req SilenceRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		now := time.Now()
+		sil := Silence{
+			Interfaces: req.Interfaces,
+			Algorithms: req.Algorithms,
+			MACs:       req.MACs,
+			VLANs:      req.VLANs,
+			Comment:    req.Comment,
+			CreatedBy:  req.CreatedBy,
+			StartsAt:   req.StartsAt,
+			EndsAt:     req.EndsAt,
+		}
+		if req.Duration != "" {
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 {
+				writeError(w, http.StatusBadRequest, "invalid duration '"+req.Duration+"'")
+				return
+			}
+			from := now
+			if !sil.StartsAt.IsZero() { from = sil.StartsAt }
+			sil.EndsAt = from.Add(d)
+		}
+		sil, err := n.silences.add(sil, now)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusCreated, sil)
+	})
+	mux.HandleFunc("DELETE /api/v1/silences/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !n.silences.expire(r.PathValue("id"), time.Now()) {
+			writeError(w, http.StatusNotFound, "silence not found")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	token := n.cfg.Silences.Token
+	if token == "" {
+		return mux
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,195 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+func TestSilenceAPI_SilencesAndPersists(t *testing.T) {
+	var mu sync.Mutex
+	var got []Event
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct{ Event Event }
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		got = append(got, payload.Event)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	cfg := &config.AlertsConfig{
+		Webhook:  config.WebhookConfig{Enabled: true, URL: hook.URL},
+		Silences: config.SilencesConfig{File: filepath.Join(t.TempDir(), "silences.json"), Token: "s3cret"},
+	}
+	n := NewNotifier(cfg, "sensor-01")
+	api := httptest.NewServer(n.SilenceAPI())
+	defer api.Close()
+
+	call := func(method, path, body string) *http.Response {
+		req, _ := http.NewRequest(method, api.URL+path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer s3cret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	if resp, _ := http.Get(api.URL + "/api/v1/silences"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Sin token: %s", resp.Status)
+	}
+	if resp := call("POST", "/api/v1/silences", `{"duration": "1h"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Un silencio sin criterios debe rechazarse: %s", resp.Status)
+	}
+	resp := call("POST", "/api/v1/silences", `{"algorithms": ["MacStorm"], "macs": ["AA-BB-CC-DD-EE-FF"], "duration": "1h", "comment": "switch work"}`)
+	var sil Silence
+	json.NewDecoder(resp.Body).Decode(&sil)
+	if resp.StatusCode != http.StatusCreated || sil.ID == "" || time.Until(sil.EndsAt) < 59*time.Minute {
+		t.Fatalf("Alta fallida: %s %+v", resp.Status, sil)
+	}
+
+	silenced := sampleEvent() // MacStorm, SrcMAC aa:bb:cc:dd:ee:ff
+	other := sampleEvent()
+	other.SrcMAC = "aa:00:00:00:00:01"
+	n.Notify(silenced)
+	n.Notify(other)
+	n.Close(5 * time.Second)
+	mu.Lock()
+	if len(got) != 1 || got[0].SrcMAC != other.SrcMAC {
+		t.Errorf("Sólo debía entregarse la alerta no silenciada: %+v", got)
+	}
+	mu.Unlock()
+
+	// Un proceso nuevo recupera el silencio del fichero
+	n2 := NewNotifier(cfg, "sensor-01")
+	defer n2.Close(time.Second)
+	api2 := httptest.NewServer(n2.SilenceAPI())
+	defer api2.Close()
+	api = api2
+	var list SilenceList
+	json.NewDecoder(call("GET", "/api/v1/silences", "").Body).Decode(&list)
+	if len(list.Silences) != 1 || list.Silences[0].ID != sil.ID || list.Silences[0].Comment != "switch work" {
+		t.Fatalf("Silencio no persistido: %+v", list)
+	}
+	if n2.silences.match(silenced, time.Now()) == "" {
+		t.Error("El silencio recuperado debe seguir aplicándose")
+	}
+
+	if resp := call("DELETE", "/api/v1/silences/"+sil.ID, ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expirar: %s", resp.Status)
+	}
+	if n2.silences.match(silenced, time.Now()) != "" {
+		t.Error("Un silencio expirado no debe aplicarse")
+	}
+}
+
+func TestMaintenanceWindow_CrossesMidnight(t *testing.T) {
+	w, err := newMaintenanceWindow(config.MaintenanceWindow{
+		Name: "weekend", Days: []string{"sat"}, Start: "23:00", Duration: "3h",
+		Timezone: "UTC", Interfaces: []string{"eth0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		ts, _ := time.Parse(time.DateTime, s)
+		return ts
+	}
+	for _, c := range []struct {
+		now    string
+		active bool
+	}{
+		{"2026-01-03 22:59:00", false}, // Sábado
+		{"2026-01-03 23:30:00", true},
+		{"2026-01-04 01:59:00", true}, // Domingo, misma ventana
+		{"2026-01-04 02:00:00", false},
+		{"2026-01-04 23:30:00", false}, // El domingo no hay ventana
+	} {
+		if _, active := w.current(at(c.now)); active != c.active {
+			t.Errorf("%s: activa=%v, esperaba %v", c.now, active, c.active)
+		}
+	}
+	if next := w.next(at("2026-01-04 02:00:00")); !next.Equal(at("2026-01-10 23:00:00")) {
+		t.Errorf("Próxima ventana: %v", next)
+	}
+}
+
+func TestScope_MatchesVLANByID(t *testing.T) {
+	qinq := Event{VLAN: "100/42 (QinQ S/C)", OuterVLAN: 100, InnerVLAN: 42}
+	tagged := Event{VLAN: "42", OuterVLAN: 42}
+	native := Event{VLAN: "Native"}
+
+	cases := []struct {
+		vlans []string
+		ev    Event
+		want  bool
+	}{
+		{[]string{"42"}, qinq, true},  // C-tag
+		{[]string{"100"}, qinq, true}, // S-tag
+		{[]string{"100/42"}, qinq, true},
+		{[]string{"7"}, qinq, false},
+		{[]string{"42/100"}, qinq, false},
+		{[]string{"42"}, tagged, true},
+		{[]string{" 42 "}, tagged, true},
+		{[]string{"100/42"}, tagged, false},
+		{[]string{"native"}, native, true},
+		{[]string{"42"}, native, false},
+		{nil, native, true},
+	}
+	for _, c := range cases {
+		if got := newScope(nil, nil, nil, c.vlans).matchesVLAN(c.ev); got != c.want {
+			t.Errorf("vlans %q con %q: %v, esperaba %v", c.vlans, c.ev.VLAN, got, c.want)
+		}
+	}
+}
+
+func TestSilence_ResolvedOfNotifiedIncidentPassesThrough(t *testing.T) {
+	var mu sync.Mutex
+	var got []Event
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct{ Event Event }
+		json.NewDecoder(r.Body).Decode(&payload)
+		mu.Lock()
+		got = append(got, payload.Event)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	n := NewNotifier(&config.AlertsConfig{Webhook: config.WebhookConfig{Enabled: true, URL: hook.URL}}, "sensor-01")
+
+	// 1. El incidente se notifica; después el operador silencia la interfaz y la
+	// condición desaparece durante el silencio: el cierre debe llegar igualmente
+	open := sampleEvent()
+	open.Incident = "eth0/MacStorm/HostFlood/aa:bb:cc:dd:ee:ff"
+	n.Notify(open)
+	if _, err := n.silences.add(Silence{Interfaces: []string{"eth0"}, EndsAt: time.Now().Add(time.Hour)}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	repeat := open
+	n.Notify(repeat) // Repetición durante el silencio: retenida
+	closed := open
+	closed.Status = StatusResolved
+	n.Notify(closed)
+
+	// 2. Incidente que empieza y acaba dentro del silencio: no se envía nada
+	quiet := sampleEvent()
+	quiet.Incident = "eth0/MacStorm/HostFlood/aa:00:00:00:00:01"
+	n.Notify(quiet)
+	quiet.Status = StatusResolved
+	n.Notify(quiet)
+	n.Close(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 || got[0].Resolved() || !got[1].Resolved() || got[1].Incident != open.Incident {
+		t.Errorf("Esperaba el aviso y su cierre, recibí %+v", got)
+	}
+}
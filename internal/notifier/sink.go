@@ -0,0 +1,177 @@
+package notifier
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+// Sink es un canal de salida. Cada sink activo tiene su propia cola (spool) y su
+// propio repartidor: un SMTP lento o un webhook colgado no retrasa a los demás.
+// Send debe respetar ctx: el repartidor lo cancela al vencer el timeout del canal
+// o al cerrar el Notifier, y el evento se queda en la cola para el siguiente intento.
+type Sink interface {
+	Send(ctx context.Context, ev Event) error
+}
+
+// SinkFunc adapta una función a Sink.
+type SinkFunc func(ctx context.Context, ev Event) error
+
+func (f SinkFunc) Send(ctx context.Context, ev Event) error { return f(ctx, ev) }
+
+// Capacidades opcionales de un Sink.
+type (
+	// sinkRunner: tarea de fondo mientras viva el Notifier (keep-alive, reenvíos).
+	sinkRunner interface{ run(done <-chan struct{}) }
+	// sinkCloser: cierre ordenado una vez vaciadas las colas.
+	sinkCloser interface{ close() }
+)
+
+// SinkFactory describe un tipo de canal: qué canales de ese tipo activa la
+// configuración ("<tipo>" o "<tipo>:<nombre>"), cómo se construye cada uno y su
+// timeout de envío por defecto ([alerts.spool] timeouts lo sustituye por canal).
+type SinkFactory struct {
+	Channels func(cfg *config.AlertsConfig) []string
+	Build    func(n *Notifier, channel string) Sink
+	Timeout  time.Duration
+
+	// Named indica si "<tipo>:<nombre>" está definido en la configuración (activo o
+	// no). nil = el tipo sólo tiene el canal "<tipo>".
+	Named func(cfg *config.AlertsConfig, name string) bool
+}
+
+// sinkKinds es el registro, en el orden del catch-all por defecto.
+var sinkKinds []SinkFactory
+
+// Register añade un tipo de canal. La validación de la configuración acepta su nombre
+// en [alerts.routing] y [alerts.spool] timeouts. Se llama desde init, antes de
+// NewNotifier; un tipo registrado dos veces es un error de programación.
+func Register(kind string, factory SinkFactory) {
+	config.RegisterChannel(kind, factory.Named)
+	sinkKinds = append(sinkKinds, factory)
+}
+
+func init() {
+	Register(ChannelWebhook, SinkFactory{
+		Channels: func(c *config.AlertsConfig) []string {
+			var out []string
+			if c.Webhook.Enabled {
+				out = append(out, ChannelWebhook)
+			}
+			for _, w := range c.Webhooks {
+				if w.Enabled {
+					out = append(out, ChannelWebhookPrefix+w.Name)
+				}
+			}
+			return out
+		},
+		Build: func(n *Notifier, ch string) Sink {
+			if ch == ChannelWebhook {
+				return SinkFunc(n.sendWebhook)
+			}
+			for i := range n.cfg.Webhooks {
+				if w := &n.cfg.Webhooks[i]; ChannelWebhookPrefix+w.Name == ch {
+					return n.targetSink(newWebhookTarget(w))
+				}
+			}
+			return nil
+		},
+		Timeout: 10 * time.Second,
+		Named: func(c *config.AlertsConfig, name string) bool {
+			for _, w := range c.Webhooks {
+				if strings.EqualFold(w.Name, name) {
+					return true
+				}
+			}
+			return false
+		},
+	})
+	Register(ChannelSyslog, SinkFactory{
+		Channels: enabledIf(ChannelSyslog, func(c *config.AlertsConfig) bool { return c.SyslogServer != "" || c.Syslog.Server != "" }),
+		Build:    func(n *Notifier, _ string) Sink { return newSyslogClient(n.cfg) },
+		Timeout:  10 * time.Second,
+	})
+	Register(ChannelSmtp, SinkFactory{
+		Channels: enabledIf(ChannelSmtp, func(c *config.AlertsConfig) bool { return c.Smtp.Enabled }),
+		Build:    func(n *Notifier, _ string) Sink { return newMailer(&n.cfg.Smtp) },
+		Timeout:  60 * time.Second,
+	})
+	Register(ChannelTelegram, SinkFactory{
+		Channels: enabledIf(ChannelTelegram, func(c *config.AlertsConfig) bool { return c.Telegram.Enabled }),
+		Build:    func(n *Notifier, _ string) Sink { return SinkFunc(n.sendTelegram) },
+		Timeout:  10 * time.Second,
+	})
+	Register(ChannelAlertmanager, SinkFactory{
+		Channels: enabledIf(ChannelAlertmanager, func(c *config.AlertsConfig) bool { return c.Alertmanager.Enabled }),
+		Build:    func(n *Notifier, _ string) Sink { return newAlertmanagerClient(&n.cfg.Alertmanager, n.client) },
+		Timeout:  10 * time.Second,
+	})
+	Register(ChannelPagerDuty, SinkFactory{
+		Channels: enabledIf(ChannelPagerDuty, func(c *config.AlertsConfig) bool { return c.PagerDuty.Enabled }),
+		Build:    func(n *Notifier, _ string) Sink { return SinkFunc(n.sendPagerDuty) },
+		Timeout:  10 * time.Second,
+	})
+	Register(ChannelOpsgenie, SinkFactory{
+		Channels: enabledIf(ChannelOpsgenie, func(c *config.AlertsConfig) bool { return c.Opsgenie.Enabled }),
+		Build:    func(n *Notifier, _ string) Sink { return SinkFunc(n.sendOpsgenie) },
+		Timeout:  10 * time.Second,
+	})
+	Register(ChannelMQTT, SinkFactory{
+		Channels: enabledIf(ChannelMQTT, func(c *config.AlertsConfig) bool { return c.Mqtt.Enabled }),
+		Build:    func(n *Notifier, _ string) Sink { return newMQTTClient(&n.cfg.Mqtt, n.sensorName) },
+		Timeout:  15 * time.Second,
+	})
+}
+
+// enabledIf es el caso común: un tipo con un único canal, activo según la config.
+func enabledIf(channel string, on func(*config.AlertsConfig) bool) func(*config.AlertsConfig) []string {
+	return func(cfg *config.AlertsConfig) []string {
+		if on(cfg) {
+			return []string{channel}
+		}
+		return nil
+	}
+}
+
+// enabledChannels lista los canales configurados y activos.
+func enabledChannels(cfg *config.AlertsConfig) []string {
+	var out []string
+	for _, k := range sinkKinds {
+		out = append(out, k.Channels(cfg)...)
+	}
+	return out
+}
+
+// sinkTimeout: timeout del canal en [alerts.spool] timeouts o el de su tipo.
+func sinkTimeout(cfg *config.AlertsConfig, channel string, def time.Duration) time.Duration {
+	lookup := func(ch string) (string, bool) {
+		for k, v := range cfg.Spool.Timeouts {
+			if strings.EqualFold(strings.TrimSpace(k), ch) {
+				return v, true
+			}
+		}
+		return "", false
+	}
+	value, ok := lookup(channel)
+	if !ok && strings.HasPrefix(channel, ChannelWebhookPrefix) {
+		value, ok = lookup(ChannelWebhook) // Default para todos los webhooks con nombre
+	}
+	if !ok {
+		return def
+	}
+	return spoolDuration("timeouts."+channel, value, def)
+}
+
+// bindConn aplica el plazo de ctx a una conexión y la interrumpe si ctx se cancela
+// antes (apagado). Devuelve la función que la desliga al terminar la operación.
+func bindConn(ctx context.Context, conn net.Conn) (stop func() bool) {
+	if d, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(d)
+	} else {
+		conn.SetDeadline(time.Time{})
+	}
+	return context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+}
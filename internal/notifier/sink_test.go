@@ -0,0 +1,104 @@
+package notifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+func TestSinks_HungChannelTimesOutWithoutBlockingOthers(t *testing.T) {
+	release := make(chan struct{})
+	var hungCalls atomic.Int32
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hungCalls.Add(1)
+		select { // Nunca responde a tiempo
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer hung.Close()
+	defer close(release)
+
+	delivered := make(chan struct{}, 1)
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		delivered <- struct{}{}
+	}))
+	defer fast.Close()
+
+	n := NewNotifier(&config.AlertsConfig{
+		Webhook:  config.WebhookConfig{Enabled: true, URL: hung.URL},
+		Webhooks: []config.WebhookTarget{{Name: "fast", Enabled: true, URL: fast.URL}},
+		Spool: config.SpoolConfig{RetryInitial: "5ms", RetryMax: "10ms",
+			Timeouts: map[string]string{ChannelWebhook: "50ms", "webhook:fast": "1s"}},
+	}, "test")
+	if got := sinkTimeout(n.cfg, "webhook:other", time.Second); got != 50*time.Millisecond {
+		t.Errorf("Un webhook con nombre sin clave propia hereda la de 'webhook': %v", got)
+	}
+
+	n.Notify(sampleEvent())
+	select {
+	case <-delivered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("El canal sano no debe esperar al colgado")
+	}
+	time.Sleep(300 * time.Millisecond)
+	if calls := hungCalls.Load(); calls < 2 {
+		t.Errorf("El intento colgado debe cortarse por timeout y reintentarse: %d llamadas", calls)
+	}
+	if n.spools[ChannelWebhook].len() != 1 {
+		t.Error("El evento sin entregar debe seguir en la cola")
+	}
+	n.Close(0)
+}
+
+var (
+	registerNull sync.Once
+	nullSent     atomic.Int32
+)
+
+func TestRegister_ValidatesChannelNames(t *testing.T) {
+	registerNull.Do(func() {
+		Register("null", SinkFactory{
+			// Sólo activo si la ruta lo pide: el registro es global al paquete
+			Channels: enabledIf("null", func(c *config.AlertsConfig) bool { return slices.Contains(c.Routing.Default, "null") }),
+			Build: func(*Notifier, string) Sink {
+				return SinkFunc(func(context.Context, Event) error { nullSent.Add(1); return nil })
+			},
+			Timeout: time.Second,
+		})
+	})
+	nullSent.Store(0)
+
+	cfg := &config.Config{}
+	cfg.Alerts.Webhooks = []config.WebhookTarget{{Name: "ops", URL: "https://example.com/hook"}}
+	cfg.Alerts.Routing.Default = []string{"null", "webhook:ops", "webhook:missing", "bogus", "null:x"}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Canales desconocidos aceptados")
+	}
+	for _, want := range []string{"unknown webhook 'missing'", "unknown channel 'bogus'", "unknown channel 'null:x'"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Falta %q en: %v", want, err)
+		}
+	}
+	for _, bad := range []string{"'null'", "'ops'"} {
+		if strings.Contains(err.Error(), bad) {
+			t.Errorf("Canal registrado rechazado (%s): %v", bad, err)
+		}
+	}
+
+	n := NewNotifier(&cfg.Alerts, "test")
+	n.Notify(sampleEvent())
+	n.Close(5 * time.Second)
+	if nullSent.Load() != 1 {
+		t.Errorf("El sink registrado debe recibir el evento: %d envíos", nullSent.Load())
+	}
+}
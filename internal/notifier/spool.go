@@ -0,0 +1,222 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/telemetry"
+)
+
+// spoolEntry es un evento pendiente de entregar en un canal.
+type spoolEntry struct {
+	seq    uint64
+	ev     Event
+	size   int64
+	queued time.Time // Encolado (no ev.Time: en replay el evento lleva la hora de la captura)
+}
+
+// spool es la cola FIFO de un canal. Con directorio, cada evento se persiste como
+// <dir>/<canal>/<seq>.json (escritura tmp + rename) y la cola sobrevive a un reinicio;
+// sin directorio sólo vive en memoria. La cola está acotada por bytes: al llenarse
+// se descartan los eventos más antiguos.
+type spool struct {
+	channel  string
+	dir      string // "" = sólo memoria
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries []spoolEntry
+	bytes   int64
+	nextSeq uint64
+	wake    chan struct{} // Avisa al repartidor de que hay eventos nuevos
+}
+
+func openSpool(channel, baseDir string, maxBytes int64) *spool {
+	s := &spool{
+		channel:  channel,
+		maxBytes: maxBytes,
+		nextSeq:  1,
+		wake:     make(chan struct{}, 1),
+	}
+	if baseDir != "" {
+		dir := filepath.Join(baseDir, channel)
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			log.Printf("⚠️ [Notifier] Spool dir %s unavailable, queueing %s in memory: %v", dir, channel, err)
+		} else {
+			s.dir = dir
+			s.load()
+		}
+	}
+	s.updateMetrics()
+	if len(s.entries) > 0 {
+		log.Printf("📬 [Notifier] %s spool: %d pending alerts recovered from %s", channel, len(s.entries), s.dir)
+	}
+	return s
+}
+
+// load recupera los eventos que quedaron en disco en una ejecución anterior.
+func (s *spool) load() {
+	names, err := os.ReadDir(s.dir)
+	if err != nil {
+		log.Printf("⚠️ [Notifier] Cannot list spool %s: %v", s.dir, err)
+		return
+	}
+	for _, e := range names {
+		name := e.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			os.Remove(filepath.Join(s.dir, name)) // Escritura interrumpida
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, name))
+		var ev Event
+		if err == nil {
+			err = json.Unmarshal(data, &ev)
+		}
+		queued := time.Now()
+		if info, statErr := e.Info(); statErr == nil {
+			queued = info.ModTime()
+		}
+		if err != nil {
+			log.Printf("⚠️ [Notifier] Discarding unreadable spool entry %s: %v", name, err)
+			os.Remove(filepath.Join(s.dir, name))
+			telemetry.AlertSpoolDropped.WithLabelValues(s.channel, "corrupt").Inc()
+			continue
+		}
+		s.entries = append(s.entries, spoolEntry{seq: seq, ev: ev, size: int64(len(data)), queued: queued})
+		s.bytes += int64(len(data))
+		if seq >= s.nextSeq {
+			s.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(s.entries, func(i, j int) bool { return s.entries[i].seq < s.entries[j].seq })
+	s.trim()
+}
+
+func (s *spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d.json", seq))
+}
+
+// push encola el evento (y lo persiste) al final de la cola.
+func (s *spool) push(ev Event) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("⚠️ [Notifier] Cannot encode event for %s: %v", s.channel, err)
+		return
+	}
+
+	s.mu.Lock()
+	seq := s.nextSeq
+	s.nextSeq++
+	if s.dir != "" {
+		if err := writeFileAtomic(s.path(seq), data); err != nil {
+			// Se entrega igualmente, pero no sobrevivirá a un reinicio
+			log.Printf("⚠️ [Notifier] Cannot persist alert in %s spool: %v", s.channel, err)
+		}
+	}
+	s.entries = append(s.entries, spoolEntry{seq: seq, ev: ev, size: int64(len(data)), queued: time.Now()})
+	s.bytes += int64(len(data))
+	s.trim()
+	s.updateMetrics()
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// peek devuelve el evento más antiguo sin sacarlo de la cola.
+func (s *spool) peek() (spoolEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.entries) == 0 {
+		return spoolEntry{}, false
+	}
+	return s.entries[0], true
+}
+
+// remove saca el evento seq de la cola (entregado o caducado).
+func (s *spool) remove(seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, e := range s.entries {
+		if e.seq == seq {
+			s.drop(i)
+			break
+		}
+	}
+	s.updateMetrics()
+}
+
+// len devuelve los eventos pendientes.
+func (s *spool) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// trim descarta los más antiguos mientras se supere max_size (se conserva siempre el último).
+func (s *spool) trim() {
+	dropped := 0
+	for s.bytes > s.maxBytes && len(s.entries) > 1 {
+		s.drop(0)
+		dropped++
+	}
+	if dropped > 0 {
+		telemetry.AlertSpoolDropped.WithLabelValues(s.channel, "overflow").Add(float64(dropped))
+		log.Printf("⚠️ [Notifier] %s spool full, dropped %d oldest alerts", s.channel, dropped)
+	}
+}
+
+// drop elimina la entrada i de memoria y de disco. Requiere s.mu.
+func (s *spool) drop(i int) {
+	e := s.entries[i]
+	if s.dir != "" {
+		if err := os.Remove(s.path(e.seq)); err != nil && !os.IsNotExist(err) {
+			log.Printf("⚠️ [Notifier] Cannot remove spool entry %d: %v", e.seq, err)
+		}
+	}
+	s.bytes -= e.size
+	s.entries = append(s.entries[:i], s.entries[i+1:]...)
+}
+
+func (s *spool) updateMetrics() {
+	telemetry.AlertSpoolDepth.WithLabelValues(s.channel).Set(float64(len(s.entries)))
+	telemetry.AlertSpoolBytes.WithLabelValues(s.channel).Set(float64(s.bytes))
+}
+
+// writeFileAtomic escribe en un temporal y renombra: nunca queda un JSON a medias.
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
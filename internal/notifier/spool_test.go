@@ -0,0 +1,157 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+func TestSpool_SurvivesRestartInOrder(t *testing.T) {
+	dir := t.TempDir()
+
+	s := openSpool("webhook", dir, 1<<20)
+	for _, title := range []string{"A", "B", "C"} {
+		s.push(Event{Title: title, Time: time.Now()})
+	}
+	first, _ := s.peek()
+	s.remove(first.seq) // "A" entregado antes del reinicio
+
+	// Reinicio del proceso: la cola se reconstruye desde disco
+	s2 := openSpool("webhook", dir, 1<<20)
+	if s2.len() != 2 {
+		t.Fatalf("Esperados 2 eventos recuperados, hay %d", s2.len())
+	}
+	s2.push(Event{Title: "D", Time: time.Now()})
+
+	var got []string
+	for {
+		e, ok := s2.peek()
+		if !ok {
+			break
+		}
+		got = append(got, e.ev.Title)
+		s2.remove(e.seq)
+	}
+	if len(got) != 3 || got[0] != "B" || got[1] != "C" || got[2] != "D" {
+		t.Errorf("Orden tras reinicio: %v", got)
+	}
+}
+
+func TestSpool_OverflowDropsOldest(t *testing.T) {
+	s := openSpool("syslog", "", 600)
+	for i := 0; i < 10; i++ {
+		s.push(Event{Title: "Overflow", Time: time.Now()})
+	}
+	if s.bytes > 600 || s.len() == 0 || s.len() == 10 {
+		t.Errorf("La cola debe acotarse a max_size: %d eventos, %d bytes", s.len(), s.bytes)
+	}
+	last := s.entries[len(s.entries)-1].seq
+	if last != 10 {
+		t.Errorf("Se debe conservar el evento más reciente, último seq = %d", last)
+	}
+}
+
+func TestNotifier_RetriesUntilDelivered(t *testing.T) {
+	var mu sync.Mutex
+	var calls int
+	var delivered []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls <= 3 { // Uplink caído durante los primeros intentos
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload struct{ Event Event }
+		json.NewDecoder(r.Body).Decode(&payload)
+		delivered = append(delivered, payload.Event.Title)
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&config.AlertsConfig{
+		Webhook: config.WebhookConfig{Enabled: true, URL: srv.URL},
+		Spool:   config.SpoolConfig{Directory: t.TempDir(), RetryInitial: "5ms", RetryMax: "20ms"},
+	}, "test")
+	for _, title := range []string{"1", "2", "3"} {
+		n.Notify(Event{Algorithm: "Test", Title: title})
+	}
+	n.Close(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(delivered) != 3 || delivered[0] != "1" || delivered[1] != "2" || delivered[2] != "3" {
+		t.Errorf("Entrega tras reintentos: %v (llamadas: %d)", delivered, calls)
+	}
+}
+
+func TestNotifier_DropsRejectedWithoutBlockingQueue(t *testing.T) {
+	var mu sync.Mutex
+	var delivered []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload struct{ Event Event }
+		json.NewDecoder(r.Body).Decode(&payload)
+		switch payload.Event.Title {
+		case "bad": // El receptor nunca aceptará este evento
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		case "busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		mu.Lock()
+		delivered = append(delivered, payload.Event.Title)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&config.AlertsConfig{
+		Webhook: config.WebhookConfig{Enabled: true, URL: srv.URL},
+		Spool:   config.SpoolConfig{RetryInitial: "5ms", RetryMax: "20ms"},
+	}, "test")
+	n.Notify(Event{Algorithm: "Test", Title: "bad"})
+	n.Notify(Event{Algorithm: "Test", Title: "ok"})
+	time.Sleep(200 * time.Millisecond)
+
+	mu.Lock()
+	if len(delivered) != 1 || delivered[0] != "ok" {
+		t.Errorf("Un 400 debe descartarse sin bloquear la cola: %v", delivered)
+	}
+	mu.Unlock()
+
+	n.Notify(Event{Algorithm: "Test", Title: "busy"})
+	time.Sleep(100 * time.Millisecond)
+	if n.spools[ChannelWebhook].len() != 1 {
+		t.Error("Un 429 debe seguir en la cola para reintentarse")
+	}
+	n.Close(0)
+}
+
+func TestRetryable(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("dial tcp: connection refused"), true},
+		{&HTTPError{StatusCode: 503}, true},
+		{&HTTPError{StatusCode: 408}, true},
+		{&HTTPError{StatusCode: 429}, true},
+		{fmt.Errorf("wrapped: %w", &HTTPError{StatusCode: 500}), true},
+		{&HTTPError{StatusCode: 400}, false},
+		{&HTTPError{StatusCode: 401}, false},
+		{&HTTPError{StatusCode: 404}, false},
+		{permanent(errors.New("template: bad")), false},
+	}
+	for _, c := range cases {
+		if got := retryable(c.err); got != c.want {
+			t.Errorf("retryable(%v) = %v, esperado %v", c.err, got, c.want)
+		}
+	}
+}
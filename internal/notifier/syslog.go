@@ -0,0 +1,289 @@
+package notifier
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+// sdID identifica el bloque de structured data de LoopWarden (RFC 5424 §6.3.2).
+// 32473 es el número de empresa reservado por IANA para ejemplos y documentación.
+const sdID = "loopwarden@32473"
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5, "lpr": 6, "news": 7,
+	"uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11, "ntp": 12, "security": 13, "console": 14, "solaris-cron": 15,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19, "local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// syslogClient mantiene una conexión persistente con el colector y la rehace al fallar.
+// UDP: un datagrama por mensaje. TCP/TLS: octet-counting (RFC 6587 / RFC 5425).
+type syslogClient struct {
+	addr     string
+	protocol string // "udp" | "tcp" | "tls"
+	facility int
+	hostname string
+	appName  string
+	procID   string
+	tlsCfg   *tls.Config
+	tlsErr   error // CA/certificado ilegible: cada envío falla (y se reintenta) con este error
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newSyslogClient(cfg *config.AlertsConfig) *syslogClient {
+	sc := cfg.Syslog
+	c := &syslogClient{
+		addr:     sc.Server,
+		protocol: strings.ToLower(sc.Protocol),
+		hostname: sc.Hostname,
+		appName:  sc.AppName,
+		procID:   fmt.Sprint(os.Getpid()),
+	}
+
+	// Compatibilidad: syslog_server de [alerts] sigue funcionando (UDP)
+	if c.addr == "" { c.addr = cfg.SyslogServer }
+	if c.protocol == "" { c.protocol = "udp" }
+	if c.appName == "" { c.appName = "loopwarden" }
+	if c.hostname == "" {
+		c.hostname, _ = os.Hostname()
+	}
+	c.hostname = sdToken(c.hostname, 255)
+	c.appName = sdToken(c.appName, 48)
+
+	facility := strings.ToLower(sc.Facility)
+	if facility == "" { facility = "local0" }
+	code, ok := syslogFacilities[facility]
+	if !ok {
+		log.Printf("⚠️ [Notifier] Unknown syslog facility '%s', defaulting to local0", sc.Facility)
+		code = syslogFacilities["local0"]
+	}
+	c.facility = code
+
+	if c.protocol == "tls" {
+		c.tlsCfg, c.tlsErr = clientTLSConfig(c.addr, sc.ServerName, sc.CAFile, sc.CertFile, sc.KeyFile)
+		if c.tlsErr != nil {
+			log.Printf("❌ [Notifier] Syslog TLS setup failed: %v", c.tlsErr)
+		}
+	}
+
+	log.Printf("📜 [Notifier] Syslog RFC 5424 -> %s://%s (facility %s, host %s)", c.protocol, c.addr, facility, c.hostname)
+	return c
+}
+
+// clientTLSConfig (Syslog, MQTT): con caFile sólo se confía en esa CA (pinning); con
+// certFile/keyFile el sensor se autentica ante el servidor (mTLS).
+func clientTLSConfig(addr, serverName, caFile, certFile, keyFile string) (*tls.Config, error) {
+	tc := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: serverName}
+	if tc.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, fmt.Errorf("address %s: %w", addr, err)
+		}
+		tc.ServerName = host
+	}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file %s: no PEM certificates found", caFile)
+		}
+		tc.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %w", err)
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	return tc, nil
+}
+
+// Send escribe el evento; si la conexión estaba rota se reconecta y reintenta una vez.
+func (c *syslogClient) Send(ctx context.Context, ev Event) error {
+	msg := c.format(ev)
+	if c.protocol != "udp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if c.conn == nil {
+			if c.conn, err = c.dial(ctx); err != nil {
+				return err
+			}
+		}
+		stop := bindConn(ctx, c.conn)
+		_, err = c.conn.Write([]byte(msg))
+		stop()
+		if err == nil {
+			c.conn.SetDeadline(time.Time{})
+			return nil
+		}
+		c.conn.Close()
+		c.conn = nil
+	}
+	return err
+}
+
+func (c *syslogClient) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{KeepAlive: 30 * time.Second}
+	switch c.protocol {
+	case "tcp":
+		return dialer.DialContext(ctx, "tcp", c.addr)
+	case "tls":
+		if c.tlsErr != nil {
+			return nil, c.tlsErr
+		}
+		conn, err := (&tls.Dialer{NetDialer: dialer, Config: c.tlsCfg}).DialContext(ctx, "tcp", c.addr)
+		if err != nil {
+			return nil, err // Evita devolver un *tls.Conn nil dentro de net.Conn
+		}
+		return conn, nil
+	default:
+		return dialer.DialContext(ctx, "udp", c.addr)
+	}
+}
+
+func (c *syslogClient) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.conn != nil {
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+// format construye el mensaje RFC 5424:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [loopwarden@32473 ...] BOM MSG
+func (c *syslogClient) format(ev Event) string {
+	sev := syslogSeverity(ev.Severity)
+	if ev.Resolved() {
+		sev = 5 // notice: el incidente se ha cerrado
+	}
+	pri := c.facility*8 + sev
+	msgID := sdToken(ev.ThreatType, 32)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ", pri,
+		ev.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), c.hostname, c.appName, c.procID, msgID)
+
+	b.WriteString("[" + sdID)
+	param := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, " %s=\"%s\"", sdParamName(name), sdEscape(value))
+		}
+	}
+	param("sensor", ev.Sensor)
+	param("interface", ev.Interface)
+	param("algorithm", ev.Algorithm)
+	param("threat_type", ev.ThreatType)
+	param("severity", ev.Severity.String())
+	param("vlan", ev.VLAN)
+	param("src_mac", ev.SrcMAC)
+	param("dst_mac", ev.DstMAC)
+	param("src_ip", ev.SrcIP)
+	param("dst_ip", ev.DstIP)
+	if ev.Rate > 0 {
+		param("rate", fmt.Sprint(ev.Rate))
+	}
+	if ev.Threshold > 0 {
+		param("threshold", fmt.Sprint(ev.Threshold))
+	}
+	for _, d := range ev.Details {
+		param(d.Key, d.Value)
+	}
+	param("evidence", ev.Evidence)
+	param("status", string(ev.Status))
+	param("incident", ev.Incident)
+	if !ev.StartedAt.IsZero() {
+		param("started_at", ev.StartedAt.UTC().Format(time.RFC3339))
+	}
+	if ev.Resolved() {
+		param("duration_seconds", fmt.Sprintf("%.0f", ev.Duration))
+	}
+	if ev.Peak > 0 {
+		param("peak_rate", fmt.Sprint(ev.Peak))
+	}
+	if ev.Repeats > 0 {
+		param("suppressed_repeats", fmt.Sprint(ev.Repeats))
+	}
+	b.WriteString("] ")
+
+	b.WriteString("\ufeff" + FormatLine(ev))
+	return b.String()
+}
+
+// syslogSeverity traduce la severidad del evento a la de syslog (RFC 5424 §6.2.1).
+func syslogSeverity(s Severity) int {
+	switch s {
+	case SeverityCritical:
+		return 2 // crit
+	case SeverityWarning:
+		return 4 // warning
+	default:
+		return 6 // informational
+	}
+}
+
+// sdToken limpia un campo de cabecera: sólo ASCII imprimible sin espacios ("-" si vacío).
+func sdToken(s string, max int) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 32 && r < 127 {
+			b.WriteRune(r)
+		}
+	}
+	out := b.String()
+	if len(out) > max {
+		out = out[:max]
+	}
+	if out == "" {
+		return "-"
+	}
+	return out
+}
+
+// sdParamName normaliza las claves de Details ("TARGET TYPE" -> "target_type"). Una
+// clave sin ningún carácter ASCII válido pasa a "param": PARAM-NAME no puede ir vacío.
+func sdParamName(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(key) {
+		switch {
+		case r == ' ' || r == '-':
+			b.WriteByte('_')
+		case r > 32 && r < 127 && r != '=' && r != ']' && r != '"':
+			b.WriteRune(r)
+		}
+	}
+	name := b.String()
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	if name == "" {
+		return "param"
+	}
+	return name
+}
+
+// sdEscape escapa '"', '\' y ']' dentro de PARAM-VALUE.
+func sdEscape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(v)
+}
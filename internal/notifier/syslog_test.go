@@ -0,0 +1,199 @@
+package notifier
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+func TestSyslog_RFC5424Format(t *testing.T) {
+	c := newSyslogClient(&config.AlertsConfig{
+		Syslog: config.SyslogConfig{Server: "127.0.0.1:514", Facility: "local3", Hostname: "sensor host"},
+	})
+	ev := sampleEvent()
+	ev.Severity = SeverityCritical
+	ev = ev.With("TARGET TYPE", `Bridge "STP" [x]`).With("ÁÑ", "sin nombre ASCII")
+
+	msg := c.format(ev)
+
+	// local3 (19) * 8 + crit (2) = 154
+	prefix := "<154>1 2026-01-01T12:00:00.000000Z sensorhost loopwarden " + c.procID + " HostFlood [loopwarden@32473 "
+	if !strings.HasPrefix(msg, prefix) {
+		t.Fatalf("Cabecera RFC 5424 incorrecta:\n%s", msg)
+	}
+	for _, want := range []string{
+		`sensor="sensor-01"`, `interface="eth0"`, `severity="critical"`, `vlan="10"`,
+		`rate="4200"`, `threshold="2000"`, `pattern="Flooding Broadcast"`,
+		`target_type="Bridge \"STP\" [x\]"`, `param="sin nombre ASCII"`,
+		"] \ufeff[sensor-01] [MacStorm]",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("Falta %q en:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "\n") {
+		t.Errorf("El mensaje syslog debe ser de una sola línea")
+	}
+}
+
+// readFrame lee un mensaje con octet-counting ("LEN SP MSG").
+func readFrame(t *testing.T, r *bufio.Reader) string {
+	t.Helper()
+	lenStr, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatalf("Lectura de trama: %v", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(lenStr))
+	if err != nil {
+		t.Fatalf("Longitud inválida %q", lenStr)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("Mensaje truncado: %v", err)
+	}
+	return string(buf)
+}
+
+func TestSyslog_TCPFramingAndReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	c := newSyslogClient(&config.AlertsConfig{
+		Syslog: config.SyslogConfig{Server: ln.Addr().String(), Protocol: "tcp"},
+	})
+	defer c.close()
+
+	// 1. Dos mensajes por la misma conexión
+	if err := c.Send(context.Background(), Event{Title: "one", ThreatType: "A"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Send(context.Background(), Event{Title: "two", ThreatType: "B"}); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(conn)
+	if m := readFrame(t, r); !strings.HasSuffix(m, "one") {
+		t.Errorf("Primer mensaje: %q", m)
+	}
+	if m := readFrame(t, r); !strings.HasSuffix(m, "two") {
+		t.Errorf("Segundo mensaje: %q", m)
+	}
+
+	// 2. El colector cierra: el cliente debe reconectar
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if c2, err := ln.Accept(); err == nil {
+			accepted <- c2
+		}
+	}()
+	for time.Now().Before(deadline) {
+		c.Send(context.Background(), Event{Title: "three"})
+		select {
+		case c2 := <-accepted:
+			defer c2.Close()
+			if m := readFrame(t, bufio.NewReader(c2)); !strings.HasSuffix(m, "three") {
+				t.Errorf("Mensaje tras reconexión: %q", m)
+			}
+			return
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+	t.Fatal("El cliente no reconectó tras el cierre del colector")
+}
+
+// testCA genera una CA y un certificado de servidor para 127.0.0.1.
+func testCA(t *testing.T, dir, name string) (caFile string, serverCert tls.Certificate) {
+	t.Helper()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, _ := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	srvKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	srvTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "syslog"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	srvDER, _ := x509.CreateCertificate(rand.Reader, srvTmpl, caCert, &srvKey.PublicKey, caKey)
+
+	caFile = filepath.Join(dir, name+".pem")
+	os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0600)
+	return caFile, tls.Certificate{Certificate: [][]byte{srvDER}, PrivateKey: srvKey}
+}
+
+func TestSyslog_TLSPinnedCA(t *testing.T) {
+	dir := t.TempDir()
+	caFile, srvCert := testCA(t, dir, "pinned")
+	otherCA, _ := testCA(t, dir, "other")
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{srvCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				bufio.NewReader(conn).ReadString('\n') // Fuerza el handshake y drena
+			}()
+		}
+	}()
+
+	good := newSyslogClient(&config.AlertsConfig{
+		Syslog: config.SyslogConfig{Server: ln.Addr().String(), Protocol: "tls", CAFile: caFile},
+	})
+	defer good.close()
+	if err := good.Send(context.Background(), Event{Title: "pinned"}); err != nil {
+		t.Errorf("La CA fijada debe aceptar al colector: %v", err)
+	}
+
+	bad := newSyslogClient(&config.AlertsConfig{
+		Syslog: config.SyslogConfig{Server: ln.Addr().String(), Protocol: "tls", CAFile: otherCA},
+	})
+	defer bad.close()
+	if err := bad.Send(context.Background(), Event{Title: "rogue"}); err == nil {
+		t.Error("Un colector firmado por otra CA debe rechazarse")
+	}
+}
@@ -0,0 +1,141 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+// ChannelWebhookPrefix: los webhooks con nombre se enrutan como "webhook:<name>".
+const ChannelWebhookPrefix = "webhook:"
+
+// Cabeceras de autenticación por defecto. La firma cubre "<timestamp>.<cuerpo>" para
+// que el receptor pueda rechazar peticiones repetidas fuera de su ventana.
+const (
+	defaultSignatureHeader = "X-LoopWarden-Signature"
+	timestampHeader        = "X-LoopWarden-Timestamp"
+)
+
+// templateFuncs se exponen a las plantillas (webhooks y asunto del email): {{json .Title}}
+// produce un string JSON escapado, {{text .}} el bloque de texto de los logs, {{summary .}}
+// el titular, {{upper .Severity}} la severidad en mayúsculas...
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"text":     FormatText,
+	"line":     FormatLine,
+	"summary":  FormatSummary,
+	"markdown": FormatMarkdown,
+	"upper":    func(v any) string { return strings.ToUpper(fmt.Sprint(v)) },
+}
+
+// webhookTarget es un [[alerts.webhooks]] ya compilado.
+type webhookTarget struct {
+	name        string
+	url         string
+	method      string
+	contentType string
+	headers     map[string]string
+	tmpl        *template.Template // nil = format (o evento en JSON)
+	format      string
+	secret      []byte
+	sigHeader   string
+}
+
+func newWebhookTarget(cfg *config.WebhookTarget) *webhookTarget {
+	w := &webhookTarget{
+		name:        cfg.Name,
+		url:         cfg.URL,
+		method:      strings.ToUpper(cfg.Method),
+		contentType: cfg.ContentType,
+		headers:     cfg.Headers,
+		format:      cfg.Format,
+		secret:      []byte(cfg.Secret),
+		sigHeader:   cfg.SignatureHeader,
+	}
+	if w.method == "" { w.method = http.MethodPost }
+	if w.contentType == "" { w.contentType = "application/json" }
+	if w.sigHeader == "" { w.sigHeader = defaultSignatureHeader }
+
+	if cfg.Template != "" {
+		// Validate ya revisó la sintaxis; aquí se detectan funciones y campos inexistentes
+		// (un error al renderizar bloquearía la cola del canal en cada reintento).
+		tmpl, err := template.New(cfg.Name).Funcs(templateFuncs).Parse(cfg.Template)
+		if err == nil {
+			err = tmpl.Execute(io.Discard, SystemEvent("TemplateCheck", "check"))
+		}
+		if err != nil {
+			log.Printf("⚠️ [Notifier] Webhook '%s': invalid template, sending the JSON event instead: %v", cfg.Name, err)
+		} else {
+			w.tmpl = tmpl
+		}
+	}
+
+	signed := "unsigned"
+	if len(w.secret) > 0 {
+		signed = "HMAC-SHA256 in " + w.sigHeader
+	}
+	log.Printf("🪝 [Notifier] Webhook '%s' -> %s %s (%s)", w.name, w.method, w.url, signed)
+	return w
+}
+
+// render construye el cuerpo de la petición.
+func (w *webhookTarget) render(ev Event) ([]byte, error) {
+	if w.tmpl == nil {
+		return formatChat(w.format, ev)
+	}
+	var b bytes.Buffer
+	if err := w.tmpl.Execute(&b, ev); err != nil {
+		return nil, fmt.Errorf("template: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// sign devuelve "sha256=<hex>" = HMAC-SHA256(secret, "<timestamp>.<cuerpo>").
+func (w *webhookTarget) sign(timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, w.secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// targetSink entrega los eventos a un webhook con nombre.
+func (n *Notifier) targetSink(w *webhookTarget) Sink {
+	return SinkFunc(func(ctx context.Context, ev Event) error {
+		body, err := w.render(ev)
+		if err != nil {
+			return permanent(err)
+		}
+		req, err := http.NewRequestWithContext(ctx, w.method, w.url, bytes.NewReader(body))
+		if err != nil {
+			return permanent(err)
+		}
+		req.Header.Set("Content-Type", w.contentType)
+		for k, v := range w.headers {
+			req.Header.Set(k, v)
+		}
+		if len(w.secret) > 0 {
+			// Firmado en cada intento: un reintento desde el spool lleva su propio timestamp
+			ts := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set(timestampHeader, ts)
+			req.Header.Set(w.sigHeader, w.sign(ts, body))
+		}
+		return n.do(req)
+	})
+}
@@ -0,0 +1,97 @@
+package notifier
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+)
+
+func TestWebhookTarget_TemplateAndSignature(t *testing.T) {
+	const secret = "shared-secret"
+	var mu sync.Mutex
+	var got []*http.Request
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, r)
+		bodies = append(bodies, body)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	n := NewNotifier(&config.AlertsConfig{
+		Webhooks: []config.WebhookTarget{
+			{
+				Name:     "siem",
+				Enabled:  true,
+				URL:      srv.URL + "/ingest",
+				Method:   "put",
+				Headers:  map[string]string{"X-Tenant": "noc"},
+				Template: `{"title": {{json .Title}}, "iface": {{json .Interface}}, "rate": {{.Rate}}}`,
+				Secret:   secret,
+			},
+			{Name: "raw", Enabled: true, URL: srv.URL + "/raw"},
+			{Name: "off", URL: srv.URL + "/off"},
+		},
+		Routing: config.RoutingConfig{Default: []string{"webhook:siem", "webhook:raw"}},
+	}, "sensor-01")
+
+	ev := sampleEvent()
+	ev.Title = `Loop "quoted"`
+	n.Notify(ev)
+	n.Close(5 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 {
+		t.Fatalf("Esperaba 2 peticiones (siem y raw), recibí %d", len(got))
+	}
+	for i, r := range got {
+		switch r.URL.Path {
+		case "/ingest":
+			if r.Method != http.MethodPut || r.Header.Get("X-Tenant") != "noc" {
+				t.Errorf("Método/cabeceras: %s %v", r.Method, r.Header)
+			}
+			var body struct {
+				Title string
+				Iface string
+				Rate  uint64
+			}
+			if err := json.Unmarshal(bodies[i], &body); err != nil || body.Title != ev.Title || body.Iface != "eth0" || body.Rate != 4200 {
+				t.Errorf("Plantilla mal renderizada (%v): %s", err, bodies[i])
+			}
+
+			ts := r.Header.Get("X-LoopWarden-Timestamp")
+			if sec, err := strconv.ParseInt(ts, 10, 64); err != nil || time.Since(time.Unix(sec, 0)) > time.Minute {
+				t.Errorf("Timestamp de firma inválido: %q", ts)
+			}
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte(ts + "."))
+			mac.Write(bodies[i])
+			if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-LoopWarden-Signature") != want {
+				t.Errorf("Firma HMAC incorrecta: %q", r.Header.Get("X-LoopWarden-Signature"))
+			}
+		case "/raw":
+			var decoded Event
+			if err := json.Unmarshal(bodies[i], &decoded); err != nil || decoded.Title != ev.Title {
+				t.Errorf("Sin plantilla se envía el evento JSON: %s", bodies[i])
+			}
+			if r.Header.Get("X-LoopWarden-Signature") != "" {
+				t.Error("Sin secret no se firma")
+			}
+		default:
+			t.Errorf("Petición inesperada a %s", r.URL.Path)
+		}
+	}
+}
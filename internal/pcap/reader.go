@@ -0,0 +1,373 @@
+package pcap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"time"
+)
+
+// LinkTypeEthernet es el único tipo de enlace que entienden los algoritmos.
+const LinkTypeEthernet = 1
+
+const (
+	// Magic numbers del formato clásico (libpcap)
+	magicMicros = 0xa1b2c3d4
+	magicNanos  = 0xa1b23c4d
+
+	// Tipos de bloque pcapng
+	blockSectionHeader  = 0x0A0D0D0A
+	blockInterfaceDesc  = 0x00000001
+	blockPacketObsolete = 0x00000002
+	blockSimplePacket   = 0x00000003
+	blockEnhancedPacket = 0x00000006
+
+	byteOrderMagic = 0x1A2B3C4D
+
+	optEndOfOpt = 0
+	optTsResol  = 9
+
+	// Protección contra ficheros corruptos: ningún bloque legítimo se acerca a esto.
+	maxBlockSize = 64 << 20
+)
+
+var ErrUnsupportedFormat = errors.New("pcap: unknown file format (expected pcap or pcapng)")
+
+// Packet es una trama leída del fichero.
+// Data sólo es válido hasta la siguiente llamada a Next (buffer reutilizado).
+type Packet struct {
+	Timestamp time.Time
+	Data      []byte
+	OrigLen   int
+}
+
+type ngInterface struct {
+	linkType uint16
+	snapLen  uint32
+	// Resolución del timestamp: 10^-tsExp (tsPow2=false) o 2^-tsExp (tsPow2=true)
+	tsExp  uint8
+	tsPow2 bool
+}
+
+// Reader lee capturas en formato pcap clásico o pcapng.
+// El formato se detecta automáticamente a partir de los primeros 4 bytes.
+type Reader struct {
+	r   *bufio.Reader
+	buf []byte
+
+	ng    bool
+	order binary.ByteOrder
+
+	// pcap clásico
+	nanos    bool
+	linkType uint32
+	snapLen  uint32
+
+	// pcapng
+	ifaces []ngInterface
+	lastTs time.Time
+}
+
+func NewReader(r io.Reader) (*Reader, error) {
+	pr := &Reader{
+		r:   bufio.NewReaderSize(r, 1<<16),
+		buf: make([]byte, 0, 1<<16),
+	}
+
+	head, err := pr.r.Peek(4)
+	if err != nil {
+		return nil, fmt.Errorf("pcap: reading header: %w", err)
+	}
+
+	if binary.LittleEndian.Uint32(head) == blockSectionHeader {
+		pr.ng = true
+		return pr, nil
+	}
+
+	if err := pr.readClassicHeader(); err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// LinkType devuelve el tipo de enlace del fichero clásico.
+// En pcapng cada interfaz declara el suyo y las no-Ethernet se descartan en Next.
+func (pr *Reader) LinkType() int {
+	if pr.ng {
+		return LinkTypeEthernet
+	}
+	return int(pr.linkType)
+}
+
+func (pr *Reader) readClassicHeader() error {
+	hdr := make([]byte, 24)
+	if _, err := io.ReadFull(pr.r, hdr); err != nil {
+		return fmt.Errorf("pcap: reading global header: %w", err)
+	}
+
+	switch {
+	case binary.LittleEndian.Uint32(hdr) == magicMicros:
+		pr.order = binary.LittleEndian
+	case binary.BigEndian.Uint32(hdr) == magicMicros:
+		pr.order = binary.BigEndian
+	case binary.LittleEndian.Uint32(hdr) == magicNanos:
+		pr.order, pr.nanos = binary.LittleEndian, true
+	case binary.BigEndian.Uint32(hdr) == magicNanos:
+		pr.order, pr.nanos = binary.BigEndian, true
+	default:
+		return ErrUnsupportedFormat
+	}
+
+	pr.snapLen = pr.order.Uint32(hdr[16:20])
+	pr.linkType = pr.order.Uint32(hdr[20:24]) & 0x0FFFFFFF // Bits altos: FCS info
+	return nil
+}
+
+// Next devuelve la siguiente trama Ethernet del fichero o io.EOF al terminar.
+func (pr *Reader) Next() (Packet, error) {
+	if pr.ng {
+		return pr.nextNG()
+	}
+	return pr.nextClassic()
+}
+
+func (pr *Reader) nextClassic() (Packet, error) {
+	var hdr [16]byte
+	if _, err := io.ReadFull(pr.r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return Packet{}, fmt.Errorf("pcap: truncated record header: %w", err)
+		}
+		return Packet{}, err
+	}
+
+	sec := pr.order.Uint32(hdr[0:4])
+	frac := pr.order.Uint32(hdr[4:8])
+	capLen := pr.order.Uint32(hdr[8:12])
+	origLen := pr.order.Uint32(hdr[12:16])
+
+	if capLen > maxBlockSize {
+		return Packet{}, fmt.Errorf("pcap: record too large (%d bytes)", capLen)
+	}
+
+	data, err := pr.read(int(capLen))
+	if err != nil {
+		return Packet{}, fmt.Errorf("pcap: truncated record: %w", err)
+	}
+
+	nsec := int64(frac)
+	if !pr.nanos {
+		nsec *= 1000
+	}
+
+	return Packet{
+		Timestamp: time.Unix(int64(sec), nsec),
+		Data:      data,
+		OrigLen:   int(origLen),
+	}, nil
+}
+
+func (pr *Reader) nextNG() (Packet, error) {
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(pr.r, hdr[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return Packet{}, fmt.Errorf("pcapng: truncated block header: %w", err)
+			}
+			return Packet{}, err
+		}
+
+		blockType := binary.LittleEndian.Uint32(hdr[0:4])
+
+		// La SHB define el orden de bytes de toda la sección: hay que mirar el BOM antes que la longitud.
+		if blockType == blockSectionHeader {
+			bom, err := pr.r.Peek(4)
+			if err != nil {
+				return Packet{}, fmt.Errorf("pcapng: truncated section header: %w", err)
+			}
+			switch {
+			case binary.LittleEndian.Uint32(bom) == byteOrderMagic:
+				pr.order = binary.LittleEndian
+			case binary.BigEndian.Uint32(bom) == byteOrderMagic:
+				pr.order = binary.BigEndian
+			default:
+				return Packet{}, errors.New("pcapng: invalid byte-order magic")
+			}
+			// Nueva sección: los IDs de interfaz empiezan de cero
+			pr.ifaces = pr.ifaces[:0]
+		}
+
+		if pr.order == nil {
+			return Packet{}, errors.New("pcapng: block found before section header")
+		}
+
+		totalLen := pr.order.Uint32(hdr[4:8])
+		if totalLen < 12 || totalLen%4 != 0 || totalLen > maxBlockSize {
+			return Packet{}, fmt.Errorf("pcapng: invalid block length %d", totalLen)
+		}
+
+		// Cuerpo + longitud final repetida
+		body, err := pr.read(int(totalLen) - 8)
+		if err != nil {
+			return Packet{}, fmt.Errorf("pcapng: truncated block: %w", err)
+		}
+		body = body[:len(body)-4]
+
+		switch blockType {
+		case blockInterfaceDesc:
+			if err := pr.parseInterface(body); err != nil {
+				return Packet{}, err
+			}
+
+		case blockEnhancedPacket:
+			if len(body) < 20 {
+				return Packet{}, errors.New("pcapng: short enhanced packet block")
+			}
+			ifaceID := pr.order.Uint32(body[0:4])
+			ts := uint64(pr.order.Uint32(body[4:8]))<<32 | uint64(pr.order.Uint32(body[8:12]))
+			capLen := pr.order.Uint32(body[12:16])
+			origLen := pr.order.Uint32(body[16:20])
+			if int(capLen) > len(body)-20 {
+				return Packet{}, errors.New("pcapng: enhanced packet exceeds block")
+			}
+			ifi, ok := pr.iface(ifaceID)
+			if !ok || ifi.linkType != LinkTypeEthernet {
+				continue
+			}
+			pr.lastTs = ifi.timestamp(ts)
+			return Packet{Timestamp: pr.lastTs, Data: body[20 : 20+capLen], OrigLen: int(origLen)}, nil
+
+		case blockPacketObsolete:
+			if len(body) < 20 {
+				return Packet{}, errors.New("pcapng: short packet block")
+			}
+			ifaceID := uint32(pr.order.Uint16(body[0:2]))
+			ts := uint64(pr.order.Uint32(body[4:8]))<<32 | uint64(pr.order.Uint32(body[8:12]))
+			capLen := pr.order.Uint32(body[12:16])
+			origLen := pr.order.Uint32(body[16:20])
+			if int(capLen) > len(body)-20 {
+				return Packet{}, errors.New("pcapng: packet exceeds block")
+			}
+			ifi, ok := pr.iface(ifaceID)
+			if !ok || ifi.linkType != LinkTypeEthernet {
+				continue
+			}
+			pr.lastTs = ifi.timestamp(ts)
+			return Packet{Timestamp: pr.lastTs, Data: body[20 : 20+capLen], OrigLen: int(origLen)}, nil
+
+		case blockSimplePacket:
+			// SPB no lleva timestamp: heredamos el del último paquete visto
+			if len(body) < 4 {
+				return Packet{}, errors.New("pcapng: short simple packet block")
+			}
+			ifi, ok := pr.iface(0)
+			if !ok || ifi.linkType != LinkTypeEthernet {
+				continue
+			}
+			origLen := pr.order.Uint32(body[0:4])
+			capLen := origLen
+			if ifi.snapLen > 0 && capLen > ifi.snapLen {
+				capLen = ifi.snapLen
+			}
+			if int(capLen) > len(body)-4 {
+				capLen = uint32(len(body) - 4)
+			}
+			return Packet{Timestamp: pr.lastTs, Data: body[4 : 4+capLen], OrigLen: int(origLen)}, nil
+		}
+		// Resto de bloques (SHB, NRB, ISB, custom...): ignorados
+	}
+}
+
+func (pr *Reader) parseInterface(body []byte) error {
+	if len(body) < 8 {
+		return errors.New("pcapng: short interface description block")
+	}
+	ifi := ngInterface{
+		linkType: pr.order.Uint16(body[0:2]),
+		snapLen:  pr.order.Uint32(body[4:8]),
+		tsExp:    6, // Default: microsegundos
+	}
+
+	opts := body[8:]
+	for len(opts) >= 4 {
+		code := pr.order.Uint16(opts[0:2])
+		optLen := int(pr.order.Uint16(opts[2:4]))
+		if code == optEndOfOpt {
+			break
+		}
+		padded := (optLen + 3) &^ 3
+		if 4+padded > len(opts) {
+			break
+		}
+		if code == optTsResol && optLen >= 1 {
+			v := opts[4]
+			ifi.tsPow2 = v&0x80 != 0
+			ifi.tsExp = v & 0x7F
+		}
+		opts = opts[4+padded:]
+	}
+
+	pr.ifaces = append(pr.ifaces, ifi)
+	return nil
+}
+
+func (pr *Reader) iface(id uint32) (ngInterface, bool) {
+	if int(id) >= len(pr.ifaces) {
+		return ngInterface{}, false
+	}
+	return pr.ifaces[id], true
+}
+
+// timestamp convierte las unidades de la interfaz a time.Time
+func (ifi ngInterface) timestamp(units uint64) time.Time {
+	if ifi.tsPow2 {
+		if ifi.tsExp >= 64 {
+			return time.Unix(0, 0)
+		}
+		sec := units >> ifi.tsExp
+		frac := units & (1<<ifi.tsExp - 1)
+		hi, lo := bits.Mul64(frac, 1e9)
+		nsec := hi<<(64-ifi.tsExp) | lo>>ifi.tsExp
+		return time.Unix(int64(sec), int64(nsec))
+	}
+
+	if ifi.tsExp > 19 {
+		return time.Unix(0, 0)
+	}
+	div := uint64(1)
+	for i := uint8(0); i < ifi.tsExp; i++ {
+		div *= 10
+	}
+	sec := units / div
+	frac := units % div
+
+	var nsec uint64
+	if ifi.tsExp <= 9 {
+		mul := uint64(1)
+		for i := ifi.tsExp; i < 9; i++ {
+			mul *= 10
+		}
+		nsec = frac * mul
+	} else {
+		d := uint64(1)
+		for i := uint8(9); i < ifi.tsExp; i++ {
+			d *= 10
+		}
+		nsec = frac / d
+	}
+	return time.Unix(int64(sec), int64(nsec))
+}
+
+// read lee n bytes reutilizando el buffer interno (Zero-alloc en régimen estable)
+func (pr *Reader) read(n int) ([]byte, error) {
+	if cap(pr.buf) < n {
+		pr.buf = make([]byte, n)
+	}
+	pr.buf = pr.buf[:n]
+	if _, err := io.ReadFull(pr.r, pr.buf); err != nil {
+		return nil, err
+	}
+	return pr.buf, nil
+}
@@ -0,0 +1,150 @@
+package pcap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+)
+
+func classicCapture(order binary.ByteOrder, magic uint32, frames [][]byte, ts []time.Time) []byte {
+	var b bytes.Buffer
+	hdr := make([]byte, 24)
+	order.PutUint32(hdr[0:4], magic)
+	order.PutUint16(hdr[4:6], 2)
+	order.PutUint16(hdr[6:8], 4)
+	order.PutUint32(hdr[16:20], 65535)
+	order.PutUint32(hdr[20:24], LinkTypeEthernet)
+	b.Write(hdr)
+
+	for i, f := range frames {
+		rec := make([]byte, 16)
+		order.PutUint32(rec[0:4], uint32(ts[i].Unix()))
+		frac := uint32(ts[i].Nanosecond())
+		if magic == magicMicros {
+			frac /= 1000
+		}
+		order.PutUint32(rec[4:8], frac)
+		order.PutUint32(rec[8:12], uint32(len(f)))
+		order.PutUint32(rec[12:16], uint32(len(f)))
+		b.Write(rec)
+		b.Write(f)
+	}
+	return b.Bytes()
+}
+
+func ngBlock(blockType uint32, body []byte) []byte {
+	padded := (len(body) + 3) &^ 3
+	total := uint32(12 + padded)
+	out := make([]byte, total)
+	binary.LittleEndian.PutUint32(out[0:4], blockType)
+	binary.LittleEndian.PutUint32(out[4:8], total)
+	copy(out[8:], body)
+	binary.LittleEndian.PutUint32(out[total-4:], total)
+	return out
+}
+
+func TestReader_Classic(t *testing.T) {
+	base := time.Unix(1700000000, 123456000)
+	frames := [][]byte{
+		bytes.Repeat([]byte{0xFF}, 60),
+		bytes.Repeat([]byte{0x01}, 64),
+	}
+	ts := []time.Time{base, base.Add(1500 * time.Millisecond)}
+
+	for _, tc := range []struct {
+		name  string
+		order binary.ByteOrder
+		magic uint32
+	}{
+		{"LE-micro", binary.LittleEndian, magicMicros},
+		{"BE-micro", binary.BigEndian, magicMicros},
+		{"LE-nano", binary.LittleEndian, magicNanos},
+	} {
+		r, err := NewReader(bytes.NewReader(classicCapture(tc.order, tc.magic, frames, ts)))
+		if err != nil {
+			t.Fatalf("%s: NewReader: %v", tc.name, err)
+		}
+		for i := range frames {
+			pkt, err := r.Next()
+			if err != nil {
+				t.Fatalf("%s: Next #%d: %v", tc.name, i, err)
+			}
+			if !bytes.Equal(pkt.Data, frames[i]) {
+				t.Errorf("%s: frame #%d mismatch", tc.name, i)
+			}
+			if !pkt.Timestamp.Equal(ts[i]) {
+				t.Errorf("%s: timestamp #%d = %v, esperaba %v", tc.name, i, pkt.Timestamp, ts[i])
+			}
+		}
+		if _, err := r.Next(); err != io.EOF {
+			t.Errorf("%s: esperaba io.EOF, obtuve %v", tc.name, err)
+		}
+	}
+}
+
+func TestReader_PcapNG(t *testing.T) {
+	var b bytes.Buffer
+
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:4], byteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:6], 1)
+	binary.LittleEndian.PutUint64(shb[8:16], ^uint64(0))
+	b.Write(ngBlock(blockSectionHeader, shb))
+
+	// Interfaz 0: Ethernet con resolución de nanosegundos (if_tsresol = 9)
+	idb := make([]byte, 8+8+4)
+	binary.LittleEndian.PutUint16(idb[0:2], LinkTypeEthernet)
+	binary.LittleEndian.PutUint32(idb[4:8], 2048)
+	binary.LittleEndian.PutUint16(idb[8:10], optTsResol)
+	binary.LittleEndian.PutUint16(idb[10:12], 1)
+	idb[12] = 9
+	b.Write(ngBlock(blockInterfaceDesc, idb))
+
+	// Interfaz 1: no Ethernet (se debe ignorar)
+	idb2 := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb2[0:2], 113)
+	b.Write(ngBlock(blockInterfaceDesc, idb2))
+
+	epb := func(iface uint32, ts time.Time, data []byte) []byte {
+		body := make([]byte, 20+len(data))
+		units := uint64(ts.UnixNano())
+		binary.LittleEndian.PutUint32(body[0:4], iface)
+		binary.LittleEndian.PutUint32(body[4:8], uint32(units>>32))
+		binary.LittleEndian.PutUint32(body[8:12], uint32(units))
+		binary.LittleEndian.PutUint32(body[12:16], uint32(len(data)))
+		binary.LittleEndian.PutUint32(body[16:20], uint32(len(data)))
+		copy(body[20:], data)
+		return ngBlock(blockEnhancedPacket, body)
+	}
+
+	ts := time.Unix(1700000000, 987654321)
+	frame := []byte("0123456789abcdefXYZ") // Longitud impar: fuerza padding
+	b.Write(epb(1, ts, []byte("ignored")))
+	b.Write(epb(0, ts, frame))
+
+	r, err := NewReader(&b)
+	if err != nil {
+		t.Fatalf("NewReader: %v", err)
+	}
+	pkt, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next: %v", err)
+	}
+	if !bytes.Equal(pkt.Data, frame) {
+		t.Errorf("payload = %q, esperaba %q", pkt.Data, frame)
+	}
+	if !pkt.Timestamp.Equal(ts) {
+		t.Errorf("timestamp = %v, esperaba %v", pkt.Timestamp, ts)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("esperaba io.EOF, obtuve %v", err)
+	}
+}
+
+func TestReader_UnknownFormat(t *testing.T) {
+	if _, err := NewReader(bytes.NewReader(make([]byte, 32))); err != ErrUnsupportedFormat {
+		t.Errorf("esperaba ErrUnsupportedFormat, obtuve %v", err)
+	}
+}
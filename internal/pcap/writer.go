@@ -0,0 +1,93 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	optIfName = 2
+)
+
+// Writer genera ficheros pcapng (una sección, una interfaz Ethernet, timestamps en ns).
+// Es el formato que abren directamente Wireshark y tshark.
+type Writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter escribe la cabecera de sección y la descripción de la interfaz.
+func NewWriter(w io.Writer, ifaceName string, snapLen int) (*Writer, error) {
+	pw := &Writer{w: w, buf: make([]byte, 0, 2048)}
+
+	// --- SHB ---
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:4], byteOrderMagic)
+	binary.LittleEndian.PutUint16(shb[4:6], 1) // Major
+	binary.LittleEndian.PutUint16(shb[6:8], 0) // Minor
+	binary.LittleEndian.PutUint64(shb[8:16], ^uint64(0))
+	if err := pw.writeBlock(blockSectionHeader, shb); err != nil {
+		return nil, err
+	}
+
+	// --- IDB (if_name + if_tsresol=9) ---
+	idb := make([]byte, 8)
+	binary.LittleEndian.PutUint16(idb[0:2], LinkTypeEthernet)
+	binary.LittleEndian.PutUint32(idb[4:8], uint32(snapLen))
+	idb = appendOption(idb, optIfName, []byte(ifaceName))
+	idb = appendOption(idb, optTsResol, []byte{9})
+	idb = appendOption(idb, optEndOfOpt, nil)
+	if err := pw.writeBlock(blockInterfaceDesc, idb); err != nil {
+		return nil, err
+	}
+
+	return pw, nil
+}
+
+// WritePacket añade un Enhanced Packet Block.
+func (pw *Writer) WritePacket(ts time.Time, data []byte, origLen int) error {
+	body := pw.buf[:0]
+	var hdr [20]byte
+	units := uint64(ts.UnixNano())
+	binary.LittleEndian.PutUint32(hdr[4:8], uint32(units>>32))
+	binary.LittleEndian.PutUint32(hdr[8:12], uint32(units))
+	binary.LittleEndian.PutUint32(hdr[12:16], uint32(len(data)))
+	binary.LittleEndian.PutUint32(hdr[16:20], uint32(origLen))
+	body = append(body, hdr[:]...)
+	body = append(body, data...)
+	pw.buf = body
+	return pw.writeBlock(blockEnhancedPacket, body)
+}
+
+func (pw *Writer) writeBlock(blockType uint32, body []byte) error {
+	padding := (4 - len(body)%4) % 4
+	total := uint32(12 + len(body) + padding)
+
+	var hdr [8]byte
+	binary.LittleEndian.PutUint32(hdr[0:4], blockType)
+	binary.LittleEndian.PutUint32(hdr[4:8], total)
+	if _, err := pw.w.Write(hdr[:]); err != nil {
+		return err
+	}
+	if _, err := pw.w.Write(body); err != nil {
+		return err
+	}
+
+	var trailer [8]byte // Hasta 3 bytes de padding + longitud final
+	binary.LittleEndian.PutUint32(trailer[padding:padding+4], total)
+	_, err := pw.w.Write(trailer[:padding+4])
+	return err
+}
+
+func appendOption(b []byte, code uint16, value []byte) []byte {
+	var hdr [4]byte
+	binary.LittleEndian.PutUint16(hdr[0:2], code)
+	binary.LittleEndian.PutUint16(hdr[2:4], uint16(len(value)))
+	b = append(b, hdr[:]...)
+	b = append(b, value...)
+	for i := len(value); i%4 != 0; i++ {
+		b = append(b, 0)
+	}
+	return b
+}
@@ -0,0 +1,110 @@
+package sniffer
+
+import (
+	"encoding/binary"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"github.com/mdlayher/packet"
+	"golang.org/x/sys/unix"
+)
+
+// vlanTag es la etiqueta 802.1Q que el kernel extrajo de la trama (VLAN offload).
+type vlanTag struct {
+	tci   uint16
+	tpid  uint16 // 0 si el kernel no informa del TPID (kernels antiguos)
+	valid bool
+}
+
+// auxReader lee tramas junto con el mensaje de control PACKET_AUXDATA.
+//
+// En la mayoría de NICs el driver retira la cabecera 802.1Q antes de que la trama
+// llegue al socket raw: el VLAN sólo está disponible en tp_vlan_tci.
+type auxReader struct {
+	rc  syscall.RawConn
+	oob []byte
+}
+
+// enableAuxdata activa PACKET_AUXDATA en el socket.
+func enableAuxdata(conn *packet.Conn) (*auxReader, error) {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+
+	var serr error
+	if err := rc.Control(func(fd uintptr) {
+		serr = unix.SetsockoptInt(int(fd), unix.SOL_PACKET, unix.PACKET_AUXDATA, 1)
+	}); err != nil {
+		return nil, err
+	}
+	if serr != nil {
+		return nil, fmt.Errorf("setsockopt PACKET_AUXDATA: %w", serr)
+	}
+
+	return &auxReader{
+		rc:  rc,
+		oob: make([]byte, unix.CmsgSpace(int(unsafe.Sizeof(unix.TpacketAuxdata{})))),
+	}, nil
+}
+
+// read es el equivalente a conn.ReadFrom, respetando el ReadDeadline del socket.
+func (ar *auxReader) read(buf []byte) (int, vlanTag, error) {
+	var (
+		n, oobn int
+		rerr    error
+	)
+
+	err := ar.rc.Read(func(fd uintptr) bool {
+		n, oobn, _, _, rerr = unix.Recvmsg(int(fd), buf, ar.oob, unix.MSG_DONTWAIT)
+		// EAGAIN: devolvemos el control al poller de Go hasta que haya datos
+		return rerr != unix.EAGAIN
+	})
+	if err != nil {
+		return 0, vlanTag{}, err
+	}
+	if rerr != nil {
+		return 0, vlanTag{}, rerr
+	}
+
+	return n, parseAuxVLAN(ar.oob[:oobn]), nil
+}
+
+// parseAuxVLAN recorre los mensajes de control sin reservar memoria
+// (unix.ParseSocketControlMessage asigna un slice por paquete).
+func parseAuxVLAN(oob []byte) vlanTag {
+	for len(oob) >= unix.SizeofCmsghdr {
+		h := (*unix.Cmsghdr)(unsafe.Pointer(&oob[0]))
+		msgLen := int(h.Len)
+		if msgLen < unix.SizeofCmsghdr || msgLen > len(oob) {
+			break
+		}
+
+		if h.Level == unix.SOL_PACKET && h.Type == unix.PACKET_AUXDATA {
+			data := oob[unix.CmsgLen(0):msgLen]
+			if len(data) < int(unsafe.Sizeof(unix.TpacketAuxdata{})) {
+				break
+			}
+			status := binary.NativeEndian.Uint32(data[0:4])
+			if status&unix.TP_STATUS_VLAN_VALID == 0 {
+				return vlanTag{}
+			}
+			tag := vlanTag{
+				tci:   binary.NativeEndian.Uint16(data[16:18]),
+				valid: true,
+			}
+			if status&unix.TP_STATUS_VLAN_TPID_VALID != 0 {
+				tag.tpid = binary.NativeEndian.Uint16(data[18:20])
+			}
+			return tag
+		}
+
+		space := unix.CmsgSpace(msgLen - unix.CmsgLen(0))
+		if space > len(oob) {
+			break
+		}
+		oob = oob[space:]
+	}
+	return vlanTag{}
+}
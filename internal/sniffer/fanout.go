@@ -0,0 +1,70 @@
+package sniffer
+
+import (
+	"net"
+	"os"
+
+	"github.com/mdlayher/packet"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+)
+
+// fanoutGroup reparte el tráfico de una interfaz entre varios sockets (PACKET_FANOUT).
+//
+// Usamos PACKET_FANOUT_CBPF con un hash de la MAC origen en lugar de PACKET_FANOUT_HASH:
+// el hash del kernel es por flujo L3/L4, y los algoritmos por MAC (MacStorm, FlapGuard,
+// ArpWatchdog, EtherFuse) necesitan ver todas las tramas de una MAC en el mismo worker.
+// skfLLOff es SKF_LL_OFF (-0x200000): el programa de fanout se ejecuta con los datos
+// apuntando a la cabecera de red, y las cargas desde la cabecera Ethernet necesitan este
+// desplazamiento especial (linux/filter.h).
+const skfLLOff = 0xFFE00000
+
+type fanoutGroup struct {
+	id      uint16
+	workers int
+	prog    []bpf.RawInstruction
+}
+
+func newFanoutGroup(ifi *net.Interface, workers int) (*fanoutGroup, error) {
+	// El kernel aplica "resultado % miembros": los bits bajos deben depender de toda la MAC
+	// (hash multiplicativo de Knuth y nos quedamos con la parte alta)
+	prog, err := bpf.Assemble([]bpf.Instruction{
+		bpf.LoadAbsolute{Off: skfLLOff + 8, Size: 4}, // MAC origen, bytes 2..5
+		bpf.TAX{},
+		bpf.LoadAbsolute{Off: skfLLOff + 6, Size: 2}, // MAC origen, bytes 0..1
+		bpf.ALUOpX{Op: bpf.ALUOpXor},
+		bpf.ALUOpConstant{Op: bpf.ALUOpMul, Val: 0x9E3779B1},
+		bpf.ALUOpConstant{Op: bpf.ALUOpShiftRight, Val: 16},
+		bpf.RetA{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// El ID de grupo es global al network namespace: lo derivamos del PID y la interfaz
+	return &fanoutGroup{
+		id:      uint16(os.Getpid()) ^ uint16(ifi.Index),
+		workers: workers,
+		prog:    prog,
+	}, nil
+}
+
+// join une un socket AF_PACKET ya enlazado (bind) al grupo.
+func (g *fanoutGroup) join(fd int) error {
+	if err := unix.SetsockoptInt(fd, unix.SOL_PACKET, unix.PACKET_FANOUT, int(g.id)|unix.PACKET_FANOUT_CBPF<<16); err != nil {
+		return err
+	}
+	return unix.SetsockoptSockFprog(fd, unix.SOL_PACKET, unix.PACKET_FANOUT_DATA, sockFprog(g.prog))
+}
+
+func (g *fanoutGroup) joinConn(conn *packet.Conn) error {
+	rc, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var jerr error
+	if err := rc.Control(func(fd uintptr) { jerr = g.join(int(fd)) }); err != nil {
+		return err
+	}
+	return jerr
+}
@@ -0,0 +1,100 @@
+package sniffer
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"golang.org/x/net/bpf"
+)
+
+// runFanout ejecuta el programa como lo hace el kernel en PACKET_FANOUT_CBPF: los datos
+// empiezan en la cabecera de red y la cabecera Ethernet sólo es accesible con
+// SKF_LL_OFF. bpf.VM no soporta esos desplazamientos negativos, así que se interpreta
+// aquí el subconjunto de instrucciones que usa newFanoutGroup.
+func runFanout(t *testing.T, prog []bpf.RawInstruction, frame []byte) uint32 {
+	t.Helper()
+	l3 := frame[14:]
+	var a, x uint32
+	for _, raw := range prog {
+		switch ins := raw.Disassemble().(type) {
+		case bpf.LoadAbsolute:
+			buf, off := l3, ins.Off
+			if off >= skfLLOff {
+				buf, off = frame, off-skfLLOff
+			}
+			if int(off)+ins.Size > len(buf) {
+				return 0 // El kernel aborta el programa: todo al worker 0
+			}
+			switch ins.Size {
+			case 2:
+				a = uint32(binary.BigEndian.Uint16(buf[off:]))
+			case 4:
+				a = binary.BigEndian.Uint32(buf[off:])
+			}
+		case bpf.TAX:
+			x = a
+		case bpf.ALUOpX:
+			if ins.Op != bpf.ALUOpXor {
+				t.Fatalf("Instrucción no soportada: %v", ins)
+			}
+			a ^= x
+		case bpf.ALUOpConstant:
+			switch ins.Op {
+			case bpf.ALUOpMul:
+				a *= ins.Val
+			case bpf.ALUOpShiftRight:
+				a >>= ins.Val
+			default:
+				t.Fatalf("Instrucción no soportada: %v", ins)
+			}
+		case bpf.RetA:
+			return a
+		default:
+			t.Fatalf("Instrucción no soportada: %v", ins)
+		}
+	}
+	t.Fatal("El programa no termina en RET")
+	return 0
+}
+
+// El reparto debe ser estable por MAC y no concentrarse en un worker aunque las MACs
+// sólo difieran en un byte (típico de un rango de un mismo fabricante).
+func TestFanoutHash_Distribution(t *testing.T) {
+	g, err := newFanoutGroup(&net.Interface{Index: 1}, 4)
+	if err != nil {
+		t.Fatalf("newFanoutGroup: %v", err)
+	}
+
+	shard := func(src []byte, payload byte) int {
+		frame := make([]byte, 60)
+		copy(frame[6:12], src)
+		for i := 14; i < len(frame); i++ {
+			frame[i] = payload
+		}
+		return int(runFanout(t, g.prog, frame)) % g.workers
+	}
+
+	for _, pos := range []int{3, 4, 5} {
+		counts := make([]int, g.workers)
+		for i := 0; i < 256; i++ {
+			mac := []byte{0x52, 0x54, 0x00, 0x12, 0x34, 0x56}
+			mac[pos] = byte(i)
+			counts[shard(mac, 0)]++
+		}
+		for w, c := range counts {
+			if c < 32 {
+				t.Errorf("Byte %d de la MAC: worker %d recibe sólo %d/256 MACs (%v)", pos, w, c, counts)
+			}
+		}
+	}
+
+	// Sólo cuenta la MAC origen: la carga L3 (lo que el kernel ve en offset 0) no influye
+	mac := []byte{0x02, 0, 0, 0, 7, 0x10}
+	want := shard(mac, 0)
+	for payload := 1; payload < 256; payload++ {
+		if got := shard(mac, byte(payload)); got != want {
+			t.Fatalf("La misma MAC cae en workers distintos (%d y %d) según la carga L3", want, got)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package sniffer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"time"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/detector"
+	"github.com/soyunomas/loopwarden/internal/pcap"
+	"github.com/soyunomas/loopwarden/internal/telemetry"
+)
+
+// ReplayOptions controla la reproducción de una captura offline.
+type ReplayOptions struct {
+	// Fast procesa el fichero sin pausas. El reloj virtual sigue los timestamps
+	// de la captura, así que las ventanas por segundo no se ven afectadas.
+	Fast bool
+	// Speed es el multiplicador de velocidad en modo tiempo real (1.0 = original).
+	Speed float64
+}
+
+// ReplayStats resume una reproducción.
+type ReplayStats struct {
+	Packets    uint64 // Tramas leídas del fichero
+	Dispatched uint64 // Tramas entregadas al motor (tras el filtro equivalente al BPF)
+	First      time.Time
+	Last       time.Time
+}
+
+// Replay alimenta el motor con las tramas de una captura en lugar de un socket AF_PACKET.
+// No requiere privilegios ni NIC: es la herramienta para calibrar umbrales contra incidentes pasados.
+// El reloj de replay debe estar instalado (detector.SetClock) antes de crear el engine.
+func Replay(ctx context.Context, r *pcap.Reader, ifaceName string, cfg *config.Config, engine *detector.Engine, clk *detector.ReplayClock, opts ReplayOptions) (ReplayStats, error) {
+	var stats ReplayStats
+
+	if lt := r.LinkType(); lt != pcap.LinkTypeEthernet {
+		return stats, fmt.Errorf("unsupported link type %d (only Ethernet captures can be replayed)", lt)
+	}
+
+	if opts.Speed <= 0 {
+		opts.Speed = 1.0
+	}
+
+	// Sin socket: ActiveProbe queda en modo pasivo
+	engine.StartAll(ctx, nil, &net.Interface{Name: ifaceName})
+	defer engine.StopAll()
+
+	log.Printf("⏯️  Replay started on virtual interface %s (fast=%v, speed=%.2fx)", ifaceName, opts.Fast, opts.Speed)
+
+	var wallStart time.Time
+	snapLen := cfg.Network.SnapLen
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return stats, nil
+		}
+
+		pkt, err := r.Next()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return stats, nil
+			}
+			return stats, err
+		}
+
+		stats.Packets++
+		if stats.First.IsZero() {
+			stats.First = pkt.Timestamp
+		}
+		stats.Last = pkt.Timestamp
+
+		// --- RITMO (sólo en tiempo real) ---
+		if !opts.Fast {
+			if wallStart.IsZero() {
+				wallStart = time.Now()
+			}
+			offset := time.Duration(float64(pkt.Timestamp.Sub(stats.First)) / opts.Speed)
+			if wait := time.Until(wallStart.Add(offset)); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return stats, nil
+				case <-time.After(wait):
+				}
+			}
+		}
+
+		// El reloj avanza aunque la trama se descarte: las ventanas dependen del tiempo, no del tráfico
+		clk.Advance(pkt.Timestamp)
+
+		data := pkt.Data
+		if !acceptFrame(data) {
+			continue
+		}
+		if snapLen > 0 && len(data) > snapLen {
+			data = data[:snapLen]
+		}
+		n := len(data)
+		stats.Dispatched++
+
+		start := time.Now()
+
+		engine.DispatchPacket(data, n, resolveVLAN(ifaceName, data, vlanTag{}))
+
+		duration := time.Since(start).Nanoseconds()
+		telemetry.ProcessingTime.WithLabelValues(ifaceName).Observe(float64(duration))
+	}
+}
+
+// acceptFrame replica en user-space el filtro BPF del modo live:
+// sólo Broadcast/Multicast (bit I/G del destino) llegan a los algoritmos.
+func acceptFrame(frame []byte) bool {
+	return len(frame) > 0 && frame[0]&0x01 == 1
+}
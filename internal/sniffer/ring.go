@@ -0,0 +1,264 @@
+package sniffer
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/mdlayher/packet"
+	"golang.org/x/net/bpf"
+	"golang.org/x/sys/unix"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/detector"
+	"github.com/soyunomas/loopwarden/internal/telemetry"
+)
+
+const (
+	ringFrameSize = 2048 // Sólo lo usa el kernel para validar la geometría en TPACKET_V3
+
+	// Offsets dentro de tpacket_block_desc (version, offset_to_priv, tpacket_hdr_v1)
+	blockStatusOff = 8
+	blockNumPkts   = 12
+	blockFirstPkt  = 16
+)
+
+// ringCapture es un socket AF_PACKET con PACKET_RX_RING (TPACKET_V3).
+//
+// OPTIMIZACIÓN: El kernel escribe las tramas directamente en memoria compartida y
+// nos entrega bloques completos: un poll() por bloque en lugar de un recvfrom() por trama.
+// Las tramas se despachan sin copia (Data apunta al mmap) hasta devolver el bloque.
+type ringCapture struct {
+	fd        int
+	ring      []byte
+	blockSize int
+	blocks    int
+	cur       int
+
+	batch []detector.RawFrame
+}
+
+// openRing crea el socket, aplica el filtro BPF y mapea el ring.
+// Cualquier error es recuperable: Run vuelve al backend de socket.
+func openRing(ifi *net.Interface, cfg *config.NetworkConfig, filter []bpf.RawInstruction, fanout *fanoutGroup) (*ringCapture, error) {
+	// --- Configuración Efectiva ---
+	blockKB := cfg.RingBlockKB
+	blocks := cfg.RingBlocks
+	timeoutMs := cfg.RingTimeoutMs
+
+	// Fallbacks de Seguridad
+	if blockKB <= 0 { blockKB = 1024 }
+	if blocks <= 0 { blocks = 32 }
+	if timeoutMs <= 0 { timeoutMs = 100 }
+
+	// El tamaño de bloque debe ser múltiplo de página
+	pageSize := os.Getpagesize()
+	blockSize := (blockKB*1024 + pageSize - 1) / pageSize * pageSize
+
+	proto := htons(unix.ETH_P_ALL)
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, int(proto))
+	if err != nil {
+		return nil, fmt.Errorf("socket: %w", err)
+	}
+
+	r := &ringCapture{
+		fd:        fd,
+		blockSize: blockSize,
+		blocks:    blocks,
+		batch:     make([]detector.RawFrame, 0, blockSize/64),
+	}
+
+	if err := r.setup(ifi, proto, filter, timeoutMs); err != nil {
+		r.close()
+		return nil, err
+	}
+	if fanout != nil {
+		if err := fanout.join(r.fd); err != nil {
+			r.close()
+			return nil, fmt.Errorf("PACKET_FANOUT: %w", err)
+		}
+	}
+	return r, nil
+}
+
+func (r *ringCapture) setup(ifi *net.Interface, proto uint16, filter []bpf.RawInstruction, timeoutMs int) error {
+	// 1. Filtro antes del bind: no queremos que entre Unicast al ring ni un instante
+	if err := unix.SetsockoptSockFprog(r.fd, unix.SOL_SOCKET, unix.SO_ATTACH_FILTER, sockFprog(filter)); err != nil {
+		return fmt.Errorf("SO_ATTACH_FILTER: %w", err)
+	}
+
+	// 2. TPACKET_V3 + ring
+	if err := unix.SetsockoptInt(r.fd, unix.SOL_PACKET, unix.PACKET_VERSION, unix.TPACKET_V3); err != nil {
+		return fmt.Errorf("PACKET_VERSION: %w", err)
+	}
+	req := unix.TpacketReq3{
+		Block_size:     uint32(r.blockSize),
+		Block_nr:       uint32(r.blocks),
+		Frame_size:     ringFrameSize,
+		Frame_nr:       uint32(r.blockSize / ringFrameSize * r.blocks),
+		Retire_blk_tov: uint32(timeoutMs), // Entrega bloques a medio llenar con poco tráfico
+	}
+	if err := unix.SetsockoptTpacketReq3(r.fd, unix.SOL_PACKET, unix.PACKET_RX_RING, &req); err != nil {
+		return fmt.Errorf("PACKET_RX_RING: %w", err)
+	}
+
+	ring, err := unix.Mmap(r.fd, 0, r.blockSize*r.blocks, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED|unix.MAP_LOCKED)
+	if err != nil {
+		// MAP_LOCKED puede fallar por RLIMIT_MEMLOCK: reintentamos sin él
+		ring, err = unix.Mmap(r.fd, 0, r.blockSize*r.blocks, unix.PROT_READ|unix.PROT_WRITE, unix.MAP_SHARED)
+		if err != nil {
+			return fmt.Errorf("mmap: %w", err)
+		}
+	}
+	r.ring = ring
+
+	// 3. Bind + promiscuo
+	if err := unix.Bind(r.fd, &unix.SockaddrLinklayer{Protocol: proto, Ifindex: ifi.Index}); err != nil {
+		return fmt.Errorf("bind: %w", err)
+	}
+	mreq := unix.PacketMreq{Ifindex: int32(ifi.Index), Type: unix.PACKET_MR_PROMISC}
+	if err := unix.SetsockoptPacketMreq(r.fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, &mreq); err != nil {
+		log.Printf("[%s] Warning: Failed to set promiscuous mode: %v", ifi.Name, err)
+	}
+	return nil
+}
+
+func (r *ringCapture) close() {
+	if r.ring != nil {
+		unix.Munmap(r.ring)
+		r.ring = nil
+	}
+	unix.Close(r.fd)
+}
+
+// run consume bloques hasta que se cancela el contexto.
+func (r *ringCapture) run(ctx context.Context, ifaceName string, engine *detector.Engine) error {
+	pfd := []unix.PollFd{{Fd: int32(r.fd), Events: unix.POLLIN | unix.POLLERR}}
+	lastStats := time.Now()
+
+	for {
+		// Con tráfico sostenido (una tormenta) todos los bloques están listos y nunca se
+		// llega al poll: el contexto se vigila en cada vuelta, no sólo al esperar.
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		// --- MONITOR DE DROPS ---
+		// PACKET_STATISTICS se pone a cero al leerlo: cada lectura es ya el delta
+		if time.Since(lastStats) >= 5*time.Second {
+			lastStats = time.Now()
+			if stats, err := unix.GetsockoptTpacketStatsV3(r.fd, unix.SOL_PACKET, unix.PACKET_STATISTICS); err == nil && stats.Drops > 0 {
+				telemetry.SocketDrops.WithLabelValues(ifaceName).Add(float64(stats.Drops))
+				if stats.Drops > 100 {
+					log.Printf("⚠️ [%s] KERNEL DROPS: %d packets lost", ifaceName, stats.Drops)
+				}
+			}
+		}
+
+		block := r.ring[r.cur*r.blockSize : (r.cur+1)*r.blockSize]
+		status := (*uint32)(unsafe.Pointer(&block[blockStatusOff]))
+
+		if atomic.LoadUint32(status)&unix.TP_STATUS_USER == 0 {
+			// Bloque aún en manos del kernel: esperamos con timeout para vigilar el contexto
+			if _, err := unix.Poll(pfd, 250); err != nil && err != unix.EINTR {
+				return fmt.Errorf("[%s] poll: %w", ifaceName, err)
+			}
+			continue
+		}
+
+		// --- PROCESAMIENTO DEL BLOQUE (HOT PATH) ---
+		start := time.Now()
+
+		numPkts := int(binary.NativeEndian.Uint32(block[blockNumPkts:]))
+		off := int(binary.NativeEndian.Uint32(block[blockFirstPkt:]))
+
+		r.batch = r.batch[:0]
+		for i := 0; i < numPkts && off+int(unsafe.Sizeof(unix.Tpacket3Hdr{})) <= len(block); i++ {
+			hdr := (*unix.Tpacket3Hdr)(unsafe.Pointer(&block[off]))
+			dataStart := off + int(hdr.Mac)
+			dataEnd := dataStart + int(hdr.Snaplen)
+			if dataEnd > len(block) {
+				break
+			}
+			data := block[dataStart:dataEnd]
+
+			var tag vlanTag
+			if hdr.Status&unix.TP_STATUS_VLAN_VALID != 0 {
+				tag = vlanTag{tci: uint16(hdr.Hv1.Vlan_tci), valid: true}
+				if hdr.Status&unix.TP_STATUS_VLAN_TPID_VALID != 0 {
+					tag.tpid = hdr.Hv1.Vlan_tpid
+				}
+			}
+			r.batch = append(r.batch, detector.RawFrame{Data: data, VLAN: resolveVLAN(ifaceName, data, tag)})
+
+			if hdr.Next_offset == 0 {
+				break
+			}
+			off += int(hdr.Next_offset)
+		}
+
+		engine.DispatchBatch(r.batch)
+
+		// Devolvemos el bloque al kernel (las tramas del batch dejan de ser válidas)
+		atomic.StoreUint32(status, unix.TP_STATUS_KERNEL)
+		r.cur = (r.cur + 1) % r.blocks
+
+		// El histograma sigue siendo "por paquete": media del bloque
+		if n := len(r.batch); n > 0 {
+			duration := time.Since(start).Nanoseconds()
+			telemetry.ProcessingTime.WithLabelValues(ifaceName).Observe(float64(duration) / float64(n))
+		}
+	}
+}
+
+// runRing es el equivalente a Run sobre TPACKET_V3. ActiveProbe necesita un socket
+// para inyectar sondas: abrimos uno de mdlayher/packet sólo para TX (filtro que descarta todo).
+func runRing(ctx context.Context, ifi *net.Interface, ring *ringCapture, engine *detector.Engine) error {
+	ifaceName := ifi.Name
+
+	txConn, err := packet.Listen(ifi, packet.Raw, 3, nil)
+	if err != nil {
+		return fmt.Errorf("[%s] failed to open TX socket: %w", ifaceName, err)
+	}
+	defer txConn.Close()
+
+	dropAll, err := bpf.Assemble([]bpf.Instruction{bpf.RetConstant{Val: 0}})
+	if err == nil {
+		err = txConn.SetBPF(dropAll)
+	}
+	if err != nil {
+		log.Printf("⚠️ [%s] Cannot silence TX socket: %v", ifaceName, err)
+	}
+
+	engine.StartAll(ctx, txConn, ifi)
+	defer engine.StopAll() // Antes de cerrar txConn: ActiveProbe inyecta por él
+
+	// El ring siempre informa del tag retirado por el driver (tp_vlan_tci)
+	telemetry.AuxdataEnabled.WithLabelValues(ifaceName).Set(1)
+
+	log.Printf("🛡️  Sniffer active on %s [BPF Active, TPACKET_V3 ring: %d x %d KB]",
+		ifaceName, ring.blocks, ring.blockSize/1024)
+
+	return ring.run(ctx, ifaceName, engine)
+}
+
+func sockFprog(filter []bpf.RawInstruction) *unix.SockFprog {
+	prog := make([]unix.SockFilter, len(filter))
+	for i, ins := range filter {
+		prog[i] = unix.SockFilter{Code: ins.Op, Jt: ins.Jt, Jf: ins.Jf, K: ins.K}
+	}
+	return &unix.SockFprog{Len: uint16(len(prog)), Filter: &prog[0]}
+}
+
+// htons convierte al orden de red que esperan socket() y sockaddr_ll.
+func htons(v uint16) uint16 {
+	var b [2]byte
+	binary.BigEndian.PutUint16(b[:], v)
+	return binary.NativeEndian.Uint16(b[:])
+}
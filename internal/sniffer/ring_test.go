@@ -0,0 +1,71 @@
+package sniffer
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/detector"
+)
+
+// fakeRing es un ring sin socket: bloques vacíos que el test marca como listos.
+func fakeRing(blocks, blockSize int) (*ringCapture, func(i int) *uint32) {
+	r := &ringCapture{fd: -1, ring: make([]byte, blocks*blockSize), blockSize: blockSize, blocks: blocks}
+	status := func(i int) *uint32 {
+		return (*uint32)(unsafe.Pointer(&r.ring[i*blockSize+blockStatusOff]))
+	}
+	return r, status
+}
+
+func TestRingRun_StopsWhileAlwaysReady(t *testing.T) {
+	engine := detector.NewEngine(&config.AlgorithmConfig{}, nil, "test")
+
+	// Contexto ya cancelado: no se consume ni un bloque más
+	r, status := fakeRing(4, 4096)
+	for i := 0; i < r.blocks; i++ {
+		atomic.StoreUint32(status(i), unix.TP_STATUS_USER)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.run(ctx, "test", engine); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadUint32(status(0)) != unix.TP_STATUS_USER {
+		t.Error("Con el contexto cancelado no debe procesarse ningún bloque")
+	}
+
+	// Tormenta: el "kernel" devuelve cada bloque lleno en cuanto se libera
+	r, status = fakeRing(4, 4096)
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			for i := 0; i < r.blocks; i++ {
+				atomic.StoreUint32(status(i), unix.TP_STATUS_USER)
+			}
+		}
+	}()
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.run(ctx, "test", engine) }()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("run no vuelve tras cancelar con el ring siempre lleno")
+	}
+}
@@ -13,19 +13,71 @@ import (
 	"golang.org/x/net/bpf"
 
 	"github.com/soyunomas/loopwarden/internal/config"
+	"github.com/soyunomas/loopwarden/internal/decoder"
 	"github.com/soyunomas/loopwarden/internal/detector"
 	"github.com/soyunomas/loopwarden/internal/telemetry"
 )
 
-// Run inicia la captura de paquetes.
-// OPTIMIZACIÓN: Implementa "Socket Breaker" para shutdown inmediato.
-func Run(ctx context.Context, ifaceName string, cfg *config.Config, engine *detector.Engine) error {
+// Run inicia la captura de paquetes: un worker por Engine (ver detector.NewEngineGroup).
+// Con más de un worker los sockets se unen a un grupo PACKET_FANOUT repartido por MAC origen.
+// Si un worker falla se detienen todos y se devuelve el primer error.
+func Run(ctx context.Context, ifaceName string, cfg *config.Config, engines []*detector.Engine) error {
 	
 	ifi, err := net.InterfaceByName(ifaceName)
 	if err != nil {
 		return fmt.Errorf("interface %s not found: %w", ifaceName, err)
 	}
 
+	filter, err := captureFilter(cfg.Network.SnapLen)
+	if err != nil {
+		return fmt.Errorf("[%s] BPF assembly failed: %w", ifaceName, err)
+	}
+
+	if len(engines) == 1 {
+		return runWorker(ctx, ifi, cfg, filter, engines[0], nil)
+	}
+
+	fanout, err := newFanoutGroup(ifi, len(engines))
+	if err != nil {
+		return fmt.Errorf("[%s] fanout program failed: %w", ifaceName, err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(engines))
+	for _, engine := range engines {
+		go func(e *detector.Engine) {
+			err := runWorker(ctx, ifi, cfg, filter, e, fanout)
+			cancel() // Un worker caído deja la interfaz sin cubrir una parte de las MACs
+			errs <- err
+		}(engine)
+	}
+
+	var firstErr error
+	for range engines {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// runWorker captura con un socket (o ring) y despacha a su Engine.
+// OPTIMIZACIÓN: Implementa "Socket Breaker" para shutdown inmediato.
+func runWorker(ctx context.Context, ifi *net.Interface, cfg *config.Config, filter []bpf.RawInstruction, engine *detector.Engine, fanout *fanoutGroup) error {
+	ifaceName := ifi.Name
+
+	// Backend TPACKET_V3: si el kernel lo rechaza, seguimos con el socket clásico
+	if cfg.Network.CaptureMode == "ring" {
+		ring, err := openRing(ifi, &cfg.Network, filter, fanout)
+		if err == nil {
+			defer ring.close()
+			return runRing(ctx, ifi, ring, engine)
+		}
+		log.Printf("⚠️ [%s] TPACKET_V3 ring unavailable, falling back to socket capture: %v", ifaceName, err)
+	}
+
 	conn, err := packet.Listen(ifi, packet.Raw, 3, nil)
 	if err != nil {
 		return fmt.Errorf("[%s] failed to open raw socket: %w", ifaceName, err)
@@ -35,28 +87,33 @@ func Run(ctx context.Context, ifaceName string, cfg *config.Config, engine *dete
 	// Sin embargo, Go permite Close() múltiples veces sin pánico, así que lo mantenemos por seguridad.
 	defer conn.Close()
 
-	engine.StartAll(conn, ifi)
+	engine.StartAll(ctx, conn, ifi)
+	defer engine.StopAll() // Tickers de los algoritmos: sin esto cada relanzamiento de la pila los filtraba
 
 	if err := conn.SetPromiscuous(true); err != nil {
 		log.Printf("[%s] Warning: Failed to set promiscuous mode: %v", ifaceName, err)
 	}
 
-	filter, err := bpf.Assemble([]bpf.Instruction{
-		bpf.LoadAbsolute{Off: 0, Size: 1},
-		bpf.ALUOpConstant{Op: bpf.ALUOpAnd, Val: 1},
-		bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0, SkipTrue: 1},
-		bpf.RetConstant{Val: uint32(cfg.Network.SnapLen)}, 
-		bpf.RetConstant{Val: 0},                           
-	})
-	if err != nil {
-		return fmt.Errorf("[%s] BPF assembly failed: %w", ifaceName, err)
-	}
-
 	if err := conn.SetBPF(filter); err != nil {
 		return fmt.Errorf("[%s] failed to apply BPF filter: %w", ifaceName, err)
 	}
 
-	log.Printf("🛡️  Sniffer active on %s [BPF Active]", ifaceName)
+	if fanout != nil {
+		if err := fanout.joinConn(conn); err != nil {
+			return fmt.Errorf("[%s] failed to join fanout group: %w", ifaceName, err)
+		}
+	}
+
+	// VLAN offload: recuperamos el tag que el driver retira de la trama
+	aux, err := enableAuxdata(conn)
+	if err != nil {
+		log.Printf("⚠️ [%s] PACKET_AUXDATA unavailable, VLAN tags only from inline headers: %v", ifaceName, err)
+		telemetry.AuxdataEnabled.WithLabelValues(ifaceName).Set(0)
+	} else {
+		telemetry.AuxdataEnabled.WithLabelValues(ifaceName).Set(1)
+	}
+
+	log.Printf("🛡️  Sniffer active on %s [BPF Active, Auxdata: %v]", ifaceName, aux != nil)
 
 	// --- 1. MONITOR DE DROPS ---
 	go func() {
@@ -102,7 +159,13 @@ func Run(ctx context.Context, ifaceName string, cfg *config.Config, engine *dete
 		// Mantenemos el Deadline para evitar zombies si el breaker fallara (defensa en profundidad)
 		conn.SetReadDeadline(time.Now().Add(1 * time.Second))
 		
-		n, _, err := conn.ReadFrom(buf)
+		var n int
+		var tag vlanTag
+		if aux != nil {
+			n, tag, err = aux.read(buf)
+		} else {
+			n, _, err = conn.ReadFrom(buf)
+		}
 		if err != nil {
 			// Comprobamos si el error es porque cerramos el socket (shutdown limpio)
 			// Go suele devolver "use of closed network connection" o "file already closed"
@@ -128,15 +191,7 @@ func Run(ctx context.Context, ifaceName string, cfg *config.Config, engine *dete
 		// --- PROCESAMIENTO (Sin cambios) ---
 		start := time.Now()
 
-		telemetry.TrackPacket(ifaceName, buf[:n], n)
-
-		var vlanID uint16 = 0
-		if n >= 18 {
-			etherType := binary.BigEndian.Uint16(buf[12:14])
-			if etherType == 0x8100 {
-				vlanID = binary.BigEndian.Uint16(buf[14:16]) & 0x0FFF
-			}
-		}
+		vlanID := resolveVLAN(ifaceName, buf[:n], tag)
 
 		engine.DispatchPacket(buf[:n], n, vlanID)
 
@@ -144,3 +199,28 @@ func Run(ctx context.Context, ifaceName string, cfg *config.Config, engine *dete
 		telemetry.ProcessingTime.WithLabelValues(ifaceName).Observe(float64(duration))
 	}
 }
+
+// captureFilter descarta Unicast en el kernel (bit I/G del destino) y trunca a snaplen.
+func captureFilter(snapLen int) ([]bpf.RawInstruction, error) {
+	return bpf.Assemble([]bpf.Instruction{
+		bpf.LoadAbsolute{Off: 0, Size: 1},
+		bpf.ALUOpConstant{Op: bpf.ALUOpAnd, Val: 1},
+		bpf.JumpIf{Cond: bpf.JumpEqual, Val: 0, SkipTrue: 1},
+		bpf.RetConstant{Val: uint32(snapLen)}, 
+		bpf.RetConstant{Val: 0},                           
+	})
+}
+
+// resolveVLAN devuelve el tag que el kernel retiró de la trama (auxdata), o 0.
+// Las etiquetas que siguen en línea (802.1Q, QinQ) las recorre decoder.Decode en cada
+// algoritmo, usando este valor como tag exterior. Contabiliza el origen para la telemetría.
+func resolveVLAN(ifaceName string, frame []byte, tag vlanTag) uint16 {
+	if tag.valid {
+		telemetry.VlanTagSource.WithLabelValues(ifaceName, "auxdata").Inc()
+		return tag.tci & 0x0FFF
+	}
+	if len(frame) >= 14 && decoder.IsVLANTPID(binary.BigEndian.Uint16(frame[12:14])) {
+		telemetry.VlanTagSource.WithLabelValues(ifaceName, "inline").Inc()
+	}
+	return 0
+}
@@ -5,6 +5,8 @@ import (
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/soyunomas/loopwarden/internal/decoder"
 )
 
 // Buckets para histogramas de latencia (en nanosegundos).
@@ -49,6 +51,21 @@ var (
 		Help: "Number of packets dropped by the kernel interface driver due to buffer overflow",
 	}, []string{"interface"})
 
+	LinkEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loopwarden_link_events_total",
+		Help: "Link state transitions seen by the link monitor (down, carrier_lost, up, flap)",
+	}, []string{"interface", "event"})
+
+	LinkUp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loopwarden_link_up",
+		Help: "1 if the interface is up with carrier and its capture stack is running",
+	}, []string{"interface"})
+
+	StackRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loopwarden_stack_restarts_total",
+		Help: "Times the capture stack of an interface was (re)started after the first launch",
+	}, []string{"interface"})
+
 	// 5. PERFIL DE TAMAÑO
 	// Etiquetas: interface
 	PacketSizes = promauto.NewHistogramVec(prometheus.HistogramOpts{
@@ -63,12 +80,71 @@ var (
 		Name: "loopwarden_arp_ops_total",
 		Help: "ARP operations breakdown (request/reply)",
 	}, []string{"interface", "operation"})
+
+	// 7. EVIDENCIA FORENSE
+	// Etiquetas: interface
+	ForensicDumps = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loopwarden_forensic_dumps_total",
+		Help: "Pcapng evidence files written when an alert fired",
+	}, []string{"interface"})
+
+	// 8. ORIGEN DEL TAG VLAN
+	// Etiquetas: interface, source (auxdata = offload del driver, inline = cabecera en la trama)
+	VlanTagSource = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loopwarden_vlan_tag_source_total",
+		Help: "VLAN-tagged frames by where the tag was read from (auxdata or inline)",
+	}, []string{"interface", "source"})
+
+	AuxdataEnabled = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loopwarden_auxdata_enabled",
+		Help: "1 if PACKET_AUXDATA (hardware-stripped VLAN recovery) is active on the socket",
+	}, []string{"interface"})
+
+	// 9. ENTREGA DE ALERTAS (SPOOL)
+	// Etiquetas: channel
+	AlertSpoolDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loopwarden_alert_spool_depth",
+		Help: "Alerts queued for delivery per notification channel",
+	}, []string{"channel"})
+
+	AlertSpoolBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loopwarden_alert_spool_bytes",
+		Help: "Size of the queued alerts per notification channel",
+	}, []string{"channel"})
+
+	// Etiquetas: channel, result (sent, failed)
+	AlertDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loopwarden_alert_deliveries_total",
+		Help: "Delivery attempts per notification channel and result",
+	}, []string{"channel", "result"})
+
+	// Etiquetas: channel
+	AlertDeliverySeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "loopwarden_alert_delivery_seconds",
+		Help:    "Duration of each delivery attempt per notification channel",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"channel"})
+
+	// Etiquetas: channel, reason (expired, overflow, corrupt, rejected)
+	AlertSpoolDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loopwarden_alert_spool_dropped_total",
+		Help: "Queued alerts discarded without delivery (too old, spool full, unreadable or rejected by the channel)",
+	}, []string{"channel", "reason"})
+
+	// 10. ALERTAS SUPRIMIDAS
+	// Etiquetas: algorithm, reason (digest, muted, silenced)
+	AlertsSuppressed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "loopwarden_alerts_suppressed_total",
+		Help: "Alerts not sent individually (grouped into a digest, muted or silenced)",
+	}, []string{"algorithm", "reason"})
 )
 
-// TrackPacket analiza el paquete RAW y actualiza métricas.
+// TrackPacket actualiza las métricas a partir de la trama ya decodificada por el Engine.
 // AHORA requiere ifaceName.
 // OPTIMIZACIÓN: Zero-alloc. Lee bytes directamente sin crear objetos intermedios.
-func TrackPacket(ifaceName string, data []byte, length int) {
+func TrackPacket(ifaceName string, f *decoder.Frame) {
+	data := f.Data
+	length := len(data)
 	if length < 14 {
 		return
 	}
@@ -96,7 +172,7 @@ func TrackPacket(ifaceName string, data []byte, length int) {
 		sType = "ARP"
 	case 0x86DD:
 		sType = "IPv6"
-	case 0x8100, 0x88A8:
+	case 0x8100, 0x88A8, 0x9100:
 		sType = "VLAN_Tagged"
 	case 0x8808:
 		sType = "FlowControl" // PAUSE Frames
@@ -116,9 +192,9 @@ func TrackPacket(ifaceName string, data []byte, length int) {
 
 	// --- D. DETALLE ARP ---
 	// Si es ARP, miramos si es Request (1) o Reply (2).
-	// Header Eth (14) + Offset ARP OpCode (6) = Byte 20.
-	if eTypeVal == 0x0806 && length >= 22 {
-		opCode := binary.BigEndian.Uint16(data[20:22])
+	// Header Eth (14, o más con etiquetas VLAN en línea) + Offset ARP OpCode (6).
+	if f.Valid && f.Eth.EtherType == 0x0806 && length >= f.Eth.L3Offset+8 {
+		opCode := binary.BigEndian.Uint16(data[f.Eth.L3Offset+6 : f.Eth.L3Offset+8])
 		switch opCode {
 		case 1:
 			ArpOps.WithLabelValues(ifaceName, "request").Inc()